@@ -25,6 +25,14 @@ import (
 // BulkDataReference describes the location of a contiguous sequence of bytes in a file
 type BulkDataReference struct {
 	Reference ByteRegion
+
+	// BitOffset and BitLength locate bulk data which does not start or end on a byte boundary
+	// within the Reference, such as the frames of pixel data with 1 bit allocated. BitOffset is the
+	// number of bits of the first byte preceding the bulk data, counting from the least significant
+	// bit, and BitLength is the number of bits of the bulk data. BitLength is 0 for bulk data of
+	// whole bytes.
+	BitOffset int64
+	BitLength int64
 }
 
 // ByteRegion is a contiguous sequence of bytes in a file described by an Offset and a length
@@ -47,14 +55,29 @@ type BulkDataBuffer interface {
 	// 0 indicating an empty buffer.
 	Length() int64
 
+	// ByteOrder returns the byte order of the words of OW, OF, OL, OD and OV values in the buffer.
+	// The words are byte swapped when written in a transfer syntax of the other byte order.
+	ByteOrder() binary.ByteOrder
+
 	write(w io.Writer, syntax transferSyntax) error
 }
 
-// NewBulkDataBuffer returns a DataElementValue representing a raw sequence of bytes.
+// NewBulkDataBuffer returns a DataElementValue representing a raw sequence of bytes. Words of
+// the bytes are little endian, see NewBulkDataBufferWithByteOrder.
 func NewBulkDataBuffer(b ...[]byte) BulkDataBuffer {
 	return bytesValue(b)
 }
 
+// NewBulkDataBufferWithByteOrder returns a DataElementValue representing a raw sequence of bytes
+// whose words are in the given byte order, such as the value of an OW DataElement read from the
+// explicit VR big endian transfer syntax.
+func NewBulkDataBufferWithByteOrder(order binary.ByteOrder, b ...[]byte) BulkDataBuffer {
+	if order == binary.BigEndian {
+		return bigEndianBytesValue(b)
+	}
+	return bytesValue(b)
+}
+
 // NewEncapsulatedFormatBuffer returns a DataElementValue representing the encapsulated image
 // format. The offset table is assumed to be the basic offset table fragment and fragments is
 // assumed to be the remaining image fragments (excluding the basic offset table fragment). To
@@ -69,6 +92,14 @@ func NewEncapsulatedFormatBuffer(offsetTable []byte, fragments ...[]byte) BulkDa
 	return encapsulatedFormatBuffer(buff)
 }
 
+// NewBitPackedFrameBuffer returns a DataElementValue representing native pixel data with 1 bit
+// allocated as frames of frameBits bits. Each frame starts at the least significant bit of its
+// first byte and the frames are packed contiguously when written, so frames may start and end
+// mid-byte in the written value.
+func NewBitPackedFrameBuffer(frameBits int64, frames ...[]byte) BulkDataBuffer {
+	return &bitPackedBuffer{frames: frames, frameBits: frameBits}
+}
+
 type bytesValue [][]byte
 
 func (b bytesValue) write(w io.Writer, syntax transferSyntax) error {
@@ -103,6 +134,69 @@ func (b bytesValue) Length() int64 {
 	return int64(totalLength)
 }
 
+func (b bytesValue) ByteOrder() binary.ByteOrder {
+	return binary.LittleEndian
+}
+
+// bigEndianBytesValue is a bytesValue whose words are big endian
+type bigEndianBytesValue [][]byte
+
+func (b bigEndianBytesValue) write(w io.Writer, syntax transferSyntax) error {
+	return bytesValue(b).write(w, syntax)
+}
+
+func (b bigEndianBytesValue) Data() [][]byte {
+	return b
+}
+
+func (b bigEndianBytesValue) Length() int64 {
+	return bytesValue(b).Length()
+}
+
+func (b bigEndianBytesValue) ByteOrder() binary.ByteOrder {
+	return binary.BigEndian
+}
+
+// bitPackedBuffer is a BulkDataBuffer of frames of pixel data with 1 bit allocated. Data returns
+// the frames each starting at the least significant bit of their first byte rather than as packed
+// in the file.
+type bitPackedBuffer struct {
+	frames    [][]byte
+	frameBits int64
+}
+
+func (b *bitPackedBuffer) write(w io.Writer, syntax transferSyntax) error {
+	packed := make([]byte, b.Length(), b.Length()+1)
+	for i, frame := range b.frames {
+		if int64(len(frame))*8 < b.frameBits {
+			return fmt.Errorf("frame %d of length %d can't hold %d bits", i, len(frame), b.frameBits)
+		}
+		for j := int64(0); j < b.frameBits; j++ {
+			bit := int64(i)*b.frameBits + j
+			packed[bit/8] |= (frame[j/8] >> uint(j%8) & 1) << uint(bit%8)
+		}
+	}
+	if len(packed)%2 != 0 {
+		// To achieve even length, append trailing null byte.
+		packed = append(packed, 0x00)
+	}
+	_, err := w.Write(packed)
+	return err
+}
+
+func (b *bitPackedBuffer) Data() [][]byte {
+	return b.frames
+}
+
+// Length returns the number of bytes of the packed frames
+func (b *bitPackedBuffer) Length() int64 {
+	return (int64(len(b.frames))*b.frameBits + 7) / 8
+}
+
+func (b *bitPackedBuffer) ByteOrder() binary.ByteOrder {
+	return binary.LittleEndian
+}
+
 type encapsulatedFormatBuffer [][]byte
 
 func (b encapsulatedFormatBuffer) write(w io.Writer, syntax transferSyntax) error {
@@ -130,6 +224,12 @@ func (b encapsulatedFormatBuffer) Length() int64 {
 	return UndefinedLength
 }
 
+// ByteOrder returns little endian, the byte order of all transfer syntaxes with encapsulated pixel
+// data
+func (b encapsulatedFormatBuffer) ByteOrder() binary.ByteOrder {
+	return binary.LittleEndian
+}
+
 // BulkDataReader represents a streamable contiguous sequence of bytes within a file
 type BulkDataReader struct {
 	io.Reader
@@ -137,6 +237,11 @@ type BulkDataReader struct {
 	// Offset is the number of bytes in the file preceding the bulk data described
 	// by the BulkDataReader
 	Offset int64
+
+	// BitOffset and BitLength locate bulk data which does not start or end on a byte boundary
+	// within the bytes of the reader, see BulkDataReference
+	BitOffset int64
+	BitLength int64
 }
 
 // Close discards all bytes in the reader
@@ -168,6 +273,10 @@ type BulkDataIterator interface {
 	// explicit length.
 	Length() int64
 
+	// ByteOrder returns the byte order of the words of OW, OF, OL, OD and OV values read from the
+	// iterator. The words are byte swapped when written in a transfer syntax of the other byte order.
+	ByteOrder() binary.ByteOrder
+
 	write(w io.Writer, syntax transferSyntax) error
 }
 
@@ -183,17 +292,22 @@ func NewEncapsulatedFormatIterator(r io.Reader, offset int64) BulkDataIterator {
 // NewBulkDataIterator returns a BulkDataIterator with a single BulkDataReader
 // described by r and offset. Offset can safely be set to 0 with the
 // understanding that the BulkDataReaders won't have the proper offset set.
+// Words read from r are little endian.
 func NewBulkDataIterator(r io.Reader, offset int64) BulkDataIterator {
-	cr := &countReader{r: r, bytesRead: offset}
-	return &oneShotIterator{cr: cr, empty: false, length: -1}
+	return newBulkDataIterator(r, offset, -1, binary.LittleEndian)
 }
 
 // NewBulkDataIteratorWithLength returns a BulkDataIterator with an explicit
 // length. A length is required when Constructing a DataSet and native bulk
 // data must write out an explicit length before the bulk data.
+// Words read from r are little endian.
 func NewBulkDataIteratorWithLength(r io.Reader, offset, length int64) BulkDataIterator {
+	return newBulkDataIterator(r, offset, length, binary.LittleEndian)
+}
+
+func newBulkDataIterator(r io.Reader, offset, length int64, order binary.ByteOrder) BulkDataIterator {
 	cr := &countReader{r: r, bytesRead: offset}
-	return &oneShotIterator{cr: cr, empty: false, length: length}
+	return &oneShotIterator{cr: cr, empty: false, length: length, order: order}
 }
 
 // oneShotIterator is a BulkDataIterator that contains exactly one BulkDataReader
@@ -205,6 +319,9 @@ type oneShotIterator struct {
 	// but needs to be present to be able to Construct a DataSet without buffering
 	// the whole reader into memory.
 	length int64
+
+	// order is the byte order of the words read from cr
+	order binary.ByteOrder
 }
 
 func (it *oneShotIterator) Next() (*BulkDataReader, error) {
@@ -214,7 +331,7 @@ func (it *oneShotIterator) Next() (*BulkDataReader, error) {
 
 	it.empty = true
 
-	return &BulkDataReader{it.cr, it.cr.bytesRead}, nil
+	return &BulkDataReader{Reader: it.cr, Offset: it.cr.bytesRead}, nil
 }
 
 func (it *oneShotIterator) Close() error {
@@ -232,13 +349,17 @@ func (it *oneShotIterator) ToBuffer() (BulkDataBuffer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("collecting fragments into memory: %v", err)
 	}
-	return NewBulkDataBuffer(b), nil
+	return NewBulkDataBufferWithByteOrder(it.order, b), nil
 }
 
 func (it *oneShotIterator) Length() int64 {
 	return it.length
 }
 
+func (it *oneShotIterator) ByteOrder() binary.ByteOrder {
+	return it.order
+}
+
 func (it *oneShotIterator) write(w io.Writer, syntax transferSyntax) error {
 	return writeByteFragments(w, func() (io.Reader, error) {
 		return it.Next()
@@ -285,7 +406,7 @@ func (it *encapsulatedFormatIterator) Next() (*BulkDataReader, error) {
 	}
 
 	currentReaderBytes := limitCountReader(it.dr.cr, int64(length))
-	it.currentReader = &BulkDataReader{currentReaderBytes, currentReaderBytes.bytesRead}
+	it.currentReader = &BulkDataReader{Reader: currentReaderBytes, Offset: currentReaderBytes.bytesRead}
 
 	return it.currentReader, nil
 }
@@ -316,6 +437,12 @@ func (it *encapsulatedFormatIterator) Length() int64 {
 	return UndefinedLength
 }
 
+// ByteOrder returns little endian, the byte order of all transfer syntaxes with encapsulated pixel
+// data
+func (it *encapsulatedFormatIterator) ByteOrder() binary.ByteOrder {
+	return binary.LittleEndian
+}
+
 func (it *encapsulatedFormatIterator) write(w io.Writer, syntax transferSyntax) error {
 	return writeEncapsulatedFormat(w, syntax.byteOrder(), func() (io.Reader, error) {
 		return it.Next()
@@ -380,3 +507,83 @@ func writeEncapsulatedFormat(w io.Writer, order binary.ByteOrder, fragmentProvid
 
 	return nil
 }
+
+// wordSize returns the number of bytes of the words making up values of the given VR whose byte
+// order depends on the transfer syntax. 1 is returned for VRs holding bytes or text.
+func wordSize(vr *VR) int {
+	switch vr {
+	case OWVR:
+		return 2
+	case OFVR, OLVR:
+		return 4
+	case ODVR, OVVR:
+		return 8
+	default:
+		return 1
+	}
+}
+
+// byteSwapReader reverses the order of the bytes of each word of size bytes read from r
+type byteSwapReader struct {
+	r    io.Reader
+	size int
+}
+
+func (s *byteSwapReader) Read(p []byte) (int, error) {
+	if len(p) < s.size {
+		return 0, io.ErrShortBuffer
+	}
+	// Reading whole words keeps the words aligned across calls to Read
+	n, err := io.ReadFull(s.r, p[:len(p)-len(p)%s.size])
+	swapBytes(p[:n-n%s.size], s.size)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// swapBytes reverses the order of the bytes of each word of size bytes in b
+func swapBytes(b []byte, size int) {
+	for start := 0; start+size <= len(b); start += size {
+		for i, j := start, start+size-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+	}
+}
+
+// UnpackBits returns the bitLength bits of b starting bitOffset bits into b as values of 0 or 1,
+// such as the mask of a frame of pixel data with 1 bit allocated located by the BitOffset and
+// BitLength of a BulkDataReader or BulkDataReference. Bits are counted from the least significant
+// bit of each byte.
+func UnpackBits(b []byte, bitOffset, bitLength int64) ([]uint8, error) {
+	if bitOffset < 0 || bitLength < 0 || int64(len(b))*8 < bitOffset+bitLength {
+		return nil, fmt.Errorf("%d bits at bit offset %d are outside of %d bytes", bitLength, bitOffset, len(b))
+	}
+	bits := make([]uint8, bitLength)
+	for i := range bits {
+		bit := bitOffset + int64(i)
+		bits[i] = b[bit/8] >> uint(bit%8) & 1
+	}
+	return bits, nil
+}
+
+// alignBits returns the bitLength bits of b starting bitOffset bits into b shifted to start at the
+// least significant bit of the first byte, with the bits following them in the last byte cleared
+func alignBits(b []byte, bitOffset, bitLength int64) ([]byte, error) {
+	if bitOffset < 0 || bitLength < 0 || int64(len(b))*8 < bitOffset+bitLength {
+		return nil, fmt.Errorf("%d bits at bit offset %d are outside of %d bytes", bitLength, bitOffset, len(b))
+	}
+	aligned := make([]byte, (bitLength+7)/8)
+	start, shift := bitOffset/8, uint(bitOffset%8)
+	for i := range aligned {
+		j := start + int64(i)
+		aligned[i] = b[j] >> shift
+		if shift > 0 && j+1 < int64(len(b)) {
+			aligned[i] |= b[j+1] << (8 - shift)
+		}
+	}
+	if r := bitLength % 8; r != 0 {
+		aligned[len(aligned)-1] &= 1<<uint(r) - 1
+	}
+	return aligned, nil
+}
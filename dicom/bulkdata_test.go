@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"reflect"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNewBulkDataBuffer_offsets(t *testing.T) {
@@ -132,6 +133,62 @@ func TestOneShotIterator_ToBuffer(t *testing.T) {
 	}
 }
 
+func TestOneShotIterator_ToBuffer_byteOrder(t *testing.T) {
+	iter := newBulkDataIterator(bytes.NewReader(sampleBytes), 0, -1, binary.BigEndian)
+	b, err := iter.ToBuffer()
+	if err != nil {
+		t.Fatalf("toBuffer: %v", err)
+	}
+	if b.ByteOrder() != binary.BigEndian {
+		t.Fatalf("got byte order %v, want %v", b.ByteOrder(), binary.BigEndian)
+	}
+	if want := [][]byte{sampleBytes}; !reflect.DeepEqual(b.Data(), want) {
+		t.Fatalf("got %v, want %v", b.Data(), want)
+	}
+}
+
+func TestByteSwapReader(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		size int
+		want []byte
+	}{
+		{
+			"16-bit words",
+			[]byte{0x01, 0x02, 0x03, 0x04},
+			2,
+			[]byte{0x02, 0x01, 0x04, 0x03},
+		},
+		{
+			"64-bit words",
+			[]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+			8,
+			[]byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+		},
+		{
+			"trailing partial word is not swapped",
+			[]byte{0x01, 0x02, 0x03, 0x04, 0x05},
+			4,
+			[]byte{0x04, 0x03, 0x02, 0x01, 0x05},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// One byte reads check that words are kept aligned across reads of the underlying reader
+			r := &byteSwapReader{r: iotest.OneByteReader(bytes.NewReader(tc.in)), size: tc.size}
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll(_) => %v", err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestEncapsulatedFormatIterator_OffsetTablePresent(t *testing.T) {
 	// test behavior of encapsulated pixel data value field as described in
 	// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_A.4
@@ -315,3 +372,101 @@ func encapsulatedFormatBytes(includeOffsetTable bool, fragments ...[]byte) []byt
 func oneShotIteratorFromBytes(data []byte) BulkDataIterator {
 	return NewBulkDataIterator(bytes.NewReader(data), 0)
 }
+
+func TestUnpackBits(t *testing.T) {
+	tests := []struct {
+		name      string
+		b         []byte
+		bitOffset int64
+		bitLength int64
+		want      []uint8
+	}{
+		{"whole byte", []byte{0x35}, 0, 8, []uint8{1, 0, 1, 0, 1, 1, 0, 0}},
+		{"mid-byte", []byte{0x35}, 2, 3, []uint8{1, 0, 1}},
+		{"across bytes", []byte{0xC0, 0x01}, 6, 4, []uint8{1, 1, 1, 0}},
+		{"no bits", []byte{}, 0, 0, []uint8{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := UnpackBits(tc.b, tc.bitOffset, tc.bitLength)
+			if err != nil {
+				t.Fatalf("UnpackBits(%v, %d, %d) => %v", tc.b, tc.bitOffset, tc.bitLength, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("UnpackBits(%v, %d, %d) => %v, want %v", tc.b, tc.bitOffset, tc.bitLength, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnpackBits_outOfRange(t *testing.T) {
+	if _, err := UnpackBits([]byte{0xFF}, 4, 5); err == nil {
+		t.Fatalf("UnpackBits(_, 4, 5) => nil, expected an error")
+	}
+}
+
+func TestAlignBits(t *testing.T) {
+	tests := []struct {
+		name      string
+		b         []byte
+		bitOffset int64
+		bitLength int64
+		want      []byte
+	}{
+		{"aligned", []byte{0xFF, 0x0F}, 0, 12, []byte{0xFF, 0x0F}},
+		{"trailing bits are cleared", []byte{0xFF}, 0, 6, []byte{0x3F}},
+		{"shifted across bytes", []byte{0xC0, 0x01}, 6, 4, []byte{0x07}},
+		{"shifted into 2 bytes", []byte{0xF0, 0xFF, 0x0A}, 4, 12, []byte{0xFF, 0x0F}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := alignBits(tc.b, tc.bitOffset, tc.bitLength)
+			if err != nil {
+				t.Fatalf("alignBits(%v, %d, %d) => %v", tc.b, tc.bitOffset, tc.bitLength, err)
+			}
+			if !bytes.Equal(got, tc.want) {
+				t.Fatalf("alignBits(%v, %d, %d) => %v, want %v", tc.b, tc.bitOffset, tc.bitLength, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBitPackedBuffer_write(t *testing.T) {
+	tests := []struct {
+		name   string
+		buffer BulkDataBuffer
+		want   []byte
+	}{
+		{
+			"frames are packed contiguously",
+			NewBitPackedFrameBuffer(6, []byte{0x34}, []byte{0x08}, []byte{0x01}, []byte{0x1E}),
+			[]byte{0x34, 0x12, 0x78, 0x00},
+		},
+		{
+			"frames of whole bytes",
+			NewBitPackedFrameBuffer(8, []byte{0x01}, []byte{0x02}),
+			[]byte{0x01, 0x02},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			if err := tc.buffer.write(got, explicitVRLittleEndian); err != nil {
+				t.Fatalf("write(_, _) => %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), tc.want) {
+				t.Fatalf("got %v, want %v", got.Bytes(), tc.want)
+			}
+		})
+	}
+}
+
+func TestBitPackedBuffer_write_shortFrame(t *testing.T) {
+	buffer := NewBitPackedFrameBuffer(12, []byte{0xFF})
+	if err := buffer.write(&bytes.Buffer{}, explicitVRLittleEndian); err == nil {
+		t.Fatalf("write(_, _) => nil, expected an error")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// Codec converts frames of pixel data between the native format and the encapsulated format of a
+// transfer syntax. Implementations must be safe for concurrent use.
+type Codec interface {
+	// Decode decodes the compressed bytes of a frame, the concatenation of the fragments holding the
+	// frame, into a native frame described by info.
+	Decode(data []byte, info FrameInfo) ([]byte, error)
+
+	// Encode encodes a native frame described by info into the compressed bytes of a frame.
+	Encode(frame []byte, info FrameInfo) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+
+	// codecs holds the Codecs of the transfer syntaxes keyed by transfer syntax UID
+	codecs = map[string]Codec{
+		RLELosslessUID:        rleCodec{},
+		JPEGBaselineUID:       jpegBaselineCodec{},
+		JPEGLosslessUID:       jpegLosslessCodec{},
+		JPEGLosslessSV1UID:    jpegLosslessCodec{},
+		JPEGLSLosslessUID:     JPEGLSCodec{},
+		JPEGLSNearLosslessUID: JPEGLSCodec{},
+	}
+)
+
+// RegisterCodec registers the Codec of the transfer syntax with the given UID, such as a JPEG 2000
+// codec implemented outside of this package. The transfer syntax must be known to the parser, see
+// LookupTransferSyntax, and must store pixel data in the encapsulated format. An error is returned
+// if a Codec is already registered for the transfer syntax.
+func RegisterCodec(uid string, codec Codec) error {
+	if codec == nil {
+		return fmt.Errorf("codec of transfer syntax %v must not be nil", uid)
+	}
+	ts, err := LookupTransferSyntax(uid)
+	if err != nil {
+		return err
+	}
+	if !ts.Encapsulated {
+		return fmt.Errorf("transfer syntax %v does not store pixel data in the encapsulated format", ts)
+	}
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	if _, ok := codecs[uid]; ok {
+		return fmt.Errorf("codec of transfer syntax %v already registered", ts)
+	}
+	codecs[uid] = codec
+	return nil
+}
+
+// LookupCodec returns the Codec of the transfer syntax with the given UID. An error is returned if
+// no Codec is built into the package or registered with RegisterCodec for the transfer syntax.
+func LookupCodec(uid string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[uid]
+	if !ok {
+		return nil, fmt.Errorf("no codec registered for transfer syntax %v", uid)
+	}
+	return codec, nil
+}
+
+// DecodeFrames decodes pixel data in the encapsulated format of the transfer syntax with the given
+// UID, such as the value of a PixelData element parsed from a DICOM file, into numberOfFrames
+// native frames described by info. The fragments of each frame are located as described in
+// NewFrameIndex, with the Basic Offset Table of pixelData.
+func DecodeFrames(pixelData BulkDataBuffer, uid string, info FrameInfo, numberOfFrames int) ([][]byte, error) {
+	codec, err := LookupCodec(uid)
+	if err != nil {
+		return nil, err
+	}
+	if pixelData.Length() != UndefinedLength {
+		return nil, fmt.Errorf("pixel data is not in the encapsulated format")
+	}
+	idx, err := newFrameIndex(&DataSet{Elements: map[DataElementTag]*DataElement{}}, pixelData, numberOfFrames)
+	if err != nil {
+		return nil, err
+	}
+
+	frames := make([][]byte, 0, idx.NumberOfFrames())
+	for i := 0; i < idx.NumberOfFrames(); i++ {
+		data, err := idx.Frame(i)
+		if err != nil {
+			return nil, err
+		}
+		frame, err := codec.Decode(data, info)
+		if err != nil {
+			return nil, fmt.Errorf("decoding frame %d: %v", i, err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// EncodeFrames encodes native frames described by info into the encapsulated format of the
+// transfer syntax with the given UID, with one fragment per frame. The Basic Offset Table of the
+// returned BulkDataBuffer holds the offset of each fragment.
+func EncodeFrames(frames [][]byte, uid string, info FrameInfo) (BulkDataBuffer, error) {
+	codec, err := LookupCodec(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	offsetTable := make([]byte, 4*len(frames))
+	fragments := make([][]byte, 0, len(frames))
+	offset := 0
+	for i, frame := range frames {
+		fragment, err := codec.Encode(frame, info)
+		if err != nil {
+			return nil, fmt.Errorf("encoding frame %d: %v", i, err)
+		}
+		// fragments are padded to even length
+		if len(fragment)%2 != 0 {
+			fragment = append(fragment, 0x00)
+		}
+		binary.LittleEndian.PutUint32(offsetTable[4*i:], uint32(offset))
+		// each fragment is preceded by its 4 byte item tag and 4 byte length
+		offset += 8 + len(fragment)
+		fragments = append(fragments, fragment)
+	}
+	return NewEncapsulatedFormatBuffer(offsetTable, fragments...), nil
+}
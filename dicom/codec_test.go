@@ -0,0 +1,187 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// reverseCodec is a Codec that reverses the bytes of frames
+type reverseCodec struct{}
+
+func (reverseCodec) Decode(data []byte, info FrameInfo) ([]byte, error) {
+	return reverse(data), nil
+}
+
+func (reverseCodec) Encode(frame []byte, info FrameInfo) ([]byte, error) {
+	return reverse(frame), nil
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, v := range b {
+		r[len(b)-1-i] = v
+	}
+	return r
+}
+
+func TestLookupCodec(t *testing.T) {
+	tests := []struct {
+		uid  string
+		want Codec
+	}{
+		{RLELosslessUID, rleCodec{}},
+		{JPEGBaselineUID, jpegBaselineCodec{}},
+		{JPEGLosslessSV1UID, jpegLosslessCodec{}},
+	}
+
+	for _, tc := range tests {
+		got, err := LookupCodec(tc.uid)
+		if err != nil {
+			t.Fatalf("LookupCodec(%q) => %v", tc.uid, err)
+		}
+		if got != tc.want {
+			t.Errorf("LookupCodec(%q) => %T, want %T", tc.uid, got, tc.want)
+		}
+	}
+
+	if _, err := LookupCodec(JPEG2000UID); err == nil {
+		t.Errorf("LookupCodec(%q) => nil, expected an error", JPEG2000UID)
+	}
+}
+
+func TestRegisterCodec(t *testing.T) {
+	uid := "1.2.826.0.1.3680043.2.1143.2"
+	ts := TransferSyntax{
+		UID:          uid,
+		Name:         "Private Reversed",
+		ByteOrder:    binary.LittleEndian,
+		ExplicitVR:   true,
+		Encapsulated: true,
+	}
+	if err := RegisterTransferSyntax(ts); err != nil {
+		t.Fatalf("RegisterTransferSyntax(%v) => %v", ts, err)
+	}
+	if err := RegisterCodec(uid, reverseCodec{}); err != nil {
+		t.Fatalf("RegisterCodec(%q, _) => %v", uid, err)
+	}
+
+	info := FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8}
+	frames := [][]byte{{0x01, 0x02}, {0x03, 0x04}}
+	pixelData, err := EncodeFrames(frames, uid, info)
+	if err != nil {
+		t.Fatalf("EncodeFrames(_, %q, _) => %v", uid, err)
+	}
+	want := NewEncapsulatedFormatBuffer([]byte{0, 0, 0, 0, 10, 0, 0, 0}, []byte{0x02, 0x01}, []byte{0x04, 0x03})
+	if !reflect.DeepEqual(pixelData, want) {
+		t.Fatalf("EncodeFrames(_, %q, _) => %v, want %v", uid, pixelData, want)
+	}
+
+	got, err := DecodeFrames(pixelData, uid, info, len(frames))
+	if err != nil {
+		t.Fatalf("DecodeFrames(_, %q, _, %d) => %v", uid, len(frames), err)
+	}
+	if !reflect.DeepEqual(got, frames) {
+		t.Fatalf("DecodeFrames(_, %q, _, %d) => %v, want %v", uid, len(frames), got, frames)
+	}
+}
+
+func TestRegisterCodec_invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		uid   string
+		codec Codec
+	}{
+		{"nil codec", JPEG2000UID, nil},
+		{"unknown transfer syntax", "1.2.3.4", reverseCodec{}},
+		{"native transfer syntax", ExplicitVRLittleEndianUID, reverseCodec{}},
+		{"already registered", RLELosslessUID, reverseCodec{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := RegisterCodec(tc.uid, tc.codec); err == nil {
+				t.Fatalf("RegisterCodec(%q, _) => nil, expected an error", tc.uid)
+			}
+		})
+	}
+}
+
+func TestDecodeFrames(t *testing.T) {
+	info := FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 16}
+	frame := bytes.Repeat([]byte{0x01, 0x02, 0x03}, 4)
+	fragment, err := EncodeRLEFrame(frame, info)
+	if err != nil {
+		t.Fatalf("EncodeRLEFrame(_, _) => %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		pixelData      BulkDataBuffer
+		numberOfFrames int
+		want           [][]byte
+	}{
+		{
+			"one fragment per frame",
+			NewEncapsulatedFormatBuffer([]byte{}, fragment, fragment),
+			2,
+			[][]byte{frame, frame},
+		},
+		{
+			"frame spanning fragments",
+			NewEncapsulatedFormatBuffer([]byte{}, fragment[:10], fragment[10:]),
+			1,
+			[][]byte{frame},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DecodeFrames(tc.pixelData, RLELosslessUID, info, tc.numberOfFrames)
+			if err != nil {
+				t.Fatalf("DecodeFrames(_, _, _, %d) => %v", tc.numberOfFrames, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("DecodeFrames(_, _, _, %d) => %x, want %x", tc.numberOfFrames, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecodeFrames_invalid(t *testing.T) {
+	info := FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8}
+	tests := []struct {
+		name           string
+		pixelData      BulkDataBuffer
+		uid            string
+		numberOfFrames int
+	}{
+		{"no codec", NewEncapsulatedFormatBuffer([]byte{}, []byte{0x00}), JPEG2000UID, 1},
+		{"native pixel data", NewBulkDataBuffer([]byte{0x00, 0x01}), RLELosslessUID, 1},
+		{"fragment count mismatch", NewEncapsulatedFormatBuffer([]byte{}, []byte{0x00}, []byte{0x00}, []byte{0x00}), RLELosslessUID, 2},
+		{"invalid fragment", NewEncapsulatedFormatBuffer([]byte{}, []byte{0x00}), RLELosslessUID, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := DecodeFrames(tc.pixelData, tc.uid, info, tc.numberOfFrames); err == nil {
+				t.Fatalf("DecodeFrames(_, %q, _, %d) => nil, expected an error", tc.uid, tc.numberOfFrames)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"fmt"
+	"math"
+)
+
+// luminance and chrominance photometric interpretations, see the DICOM standard part3 linked below.
+// http://dicom.nema.org/medical/dicom/current/output/html/part03.html#sect_C.7.6.3.1.2
+const (
+	// PhotometricYBRFull is full range luminance and chrominance samples for each pixel
+	PhotometricYBRFull PhotometricInterpretation = "YBR_FULL"
+	// PhotometricYBRFull422 is YBR_FULL with the chrominance samples subsampled horizontally by 2.
+	// Native frames hold two luminance samples followed by the blue and red chrominance samples of
+	// each pair of pixels.
+	PhotometricYBRFull422 PhotometricInterpretation = "YBR_FULL_422"
+	// PhotometricYBRPartial422 is partial range luminance and chrominance samples, with the
+	// chrominance samples subsampled horizontally as in YBR_FULL_422
+	PhotometricYBRPartial422 PhotometricInterpretation = "YBR_PARTIAL_422"
+	// PhotometricYBRICT is luminance and chrominance samples of the irreversible color transform of
+	// JPEG 2000
+	PhotometricYBRICT PhotometricInterpretation = "YBR_ICT"
+	// PhotometricYBRRCT is luminance and chrominance samples of the reversible color transform of
+	// JPEG 2000
+	PhotometricYBRRCT PhotometricInterpretation = "YBR_RCT"
+)
+
+// subsampled returns true if the chrominance samples of native frames of the
+// PhotometricInterpretation are subsampled horizontally
+func (pi PhotometricInterpretation) subsampled() bool {
+	return pi == PhotometricYBRFull422 || pi == PhotometricYBRPartial422
+}
+
+// ybr returns true if the PhotometricInterpretation is made up of luminance and chrominance
+// samples
+func (pi PhotometricInterpretation) ybr() bool {
+	switch pi {
+	case PhotometricYBRFull, PhotometricYBRFull422, PhotometricYBRPartial422, PhotometricYBRICT, PhotometricYBRRCT:
+		return true
+	}
+	return false
+}
+
+// ConvertToRGB returns the frame of luminance and chrominance samples of the given
+// PhotometricInterpretation converted to an RGB frame described by info. Samples must be unsigned
+// and chrominance samples are centered on half of the range of BitsStored bits. YBR_FULL_422 and
+// YBR_PARTIAL_422 frames may either be in the subsampled native format, which is upsampled to
+// full resolution and returned color-by-pixel, or at full resolution as decoded from JPEG.
+func ConvertToRGB(frame []byte, info FrameInfo, pi PhotometricInterpretation) ([]byte, error) {
+	if err := validateColorFrameInfo(info, pi); err != nil {
+		return nil, err
+	}
+	if pi.subsampled() && len(frame) < info.Length() {
+		var err error
+		if frame, err = upsample(frame, info); err != nil {
+			return nil, err
+		}
+		info.PlanarConfiguration = 0
+	}
+	if len(frame) < info.Length() {
+		return nil, fmt.Errorf("got frame of length %d, expected %d", len(frame), info.Length())
+	}
+
+	max := 1<<uint(info.bitsStored()) - 1
+	rgb := make([]byte, info.Length())
+	for pixel := 0; pixel < info.Rows*info.Columns; pixel++ {
+		r, g, b := ybrToRGB(pi, info.sample(frame, pixel, 0), info.sample(frame, pixel, 1), info.sample(frame, pixel, 2), max)
+		info.setSample(rgb, pixel, 0, r)
+		info.setSample(rgb, pixel, 1, g)
+		info.setSample(rgb, pixel, 2, b)
+	}
+	return rgb, nil
+}
+
+// ConvertFromRGB returns the RGB frame described by info converted to luminance and chrominance
+// samples of the given PhotometricInterpretation, the reverse of ConvertToRGB. YBR_FULL_422 and
+// YBR_PARTIAL_422 frames are returned in the subsampled native format, with the chrominance
+// samples of each pair of pixels averaged. YBR_RCT chrominance samples are clamped to the range of
+// BitsStored bits, so the transform is only reversible where the red and blue samples differ from
+// the green sample by less than half of that range.
+func ConvertFromRGB(frame []byte, info FrameInfo, pi PhotometricInterpretation) ([]byte, error) {
+	if err := validateColorFrameInfo(info, pi); err != nil {
+		return nil, err
+	}
+	if len(frame) < info.Length() {
+		return nil, fmt.Errorf("got frame of length %d, expected %d", len(frame), info.Length())
+	}
+
+	max := 1<<uint(info.bitsStored()) - 1
+	out := info
+	if pi.subsampled() {
+		out.PlanarConfiguration = 0
+	}
+	ybr := make([]byte, info.Length())
+	for pixel := 0; pixel < info.Rows*info.Columns; pixel++ {
+		y, cb, cr := rgbToYBR(pi, info.sample(frame, pixel, 0), info.sample(frame, pixel, 1), info.sample(frame, pixel, 2), max)
+		out.setSample(ybr, pixel, 0, y)
+		out.setSample(ybr, pixel, 1, cb)
+		out.setSample(ybr, pixel, 2, cr)
+	}
+	if pi.subsampled() {
+		return downsample(ybr, out)
+	}
+	return ybr, nil
+}
+
+func validateColorFrameInfo(info FrameInfo, pi PhotometricInterpretation) error {
+	if !pi.ybr() {
+		return fmt.Errorf("unsupported PhotometricInterpretation %q", pi)
+	}
+	if err := validateNativeFrameInfo(info); err != nil {
+		return err
+	}
+	if info.SamplesPerPixel != 3 {
+		return fmt.Errorf("got %d samples per pixel, %v expects 3", info.SamplesPerPixel, pi)
+	}
+	if info.PixelRepresentation != 0 {
+		return fmt.Errorf("unsupported signed samples for %v", pi)
+	}
+	if info.highBit() != info.bitsStored()-1 {
+		return fmt.Errorf("unsupported HighBit %d for BitsStored %d", info.highBit(), info.bitsStored())
+	}
+	if pi.subsampled() && info.Columns%2 != 0 {
+		return fmt.Errorf("got %d columns, %v expects an even number", info.Columns, pi)
+	}
+	return nil
+}
+
+// subsampledLength returns the number of bytes of a native frame described by the FrameInfo with
+// the chrominance samples subsampled horizontally
+func (f FrameInfo) subsampledLength() int {
+	return f.Rows * f.Columns * 2 * f.BitsAllocated / 8
+}
+
+// pairInfo returns the FrameInfo of the samples of a subsampled frame, as 4 samples per pair of
+// pixels
+func (f FrameInfo) pairInfo() FrameInfo {
+	return FrameInfo{Rows: f.Rows, Columns: f.Columns / 2, SamplesPerPixel: 4, BitsAllocated: f.BitsAllocated}
+}
+
+// upsample returns the subsampled frame described by info at full resolution and color-by-pixel,
+// repeating the chrominance samples of each pair of pixels
+func upsample(frame []byte, info FrameInfo) ([]byte, error) {
+	if info.Columns%2 != 0 {
+		return nil, fmt.Errorf("got %d columns, subsampled frames expect an even number", info.Columns)
+	}
+	if len(frame) < info.subsampledLength() {
+		return nil, fmt.Errorf("got subsampled frame of length %d, expected %d", len(frame), info.subsampledLength())
+	}
+	pairs, full := info.pairInfo(), info
+	full.PlanarConfiguration = 0
+
+	b := make([]byte, full.Length())
+	for pair := 0; pair < info.Rows*info.Columns/2; pair++ {
+		cb, cr := pairs.sample(frame, pair, 2), pairs.sample(frame, pair, 3)
+		for i := 0; i < 2; i++ {
+			full.setSample(b, 2*pair+i, 0, pairs.sample(frame, pair, i))
+			full.setSample(b, 2*pair+i, 1, cb)
+			full.setSample(b, 2*pair+i, 2, cr)
+		}
+	}
+	return b, nil
+}
+
+// downsample returns the color-by-pixel frame described by info in the subsampled native format,
+// averaging the chrominance samples of each pair of pixels
+func downsample(frame []byte, info FrameInfo) ([]byte, error) {
+	pairs := info.pairInfo()
+	b := make([]byte, info.subsampledLength())
+	for pair := 0; pair < info.Rows*info.Columns/2; pair++ {
+		pairs.setSample(b, pair, 0, info.sample(frame, 2*pair, 0))
+		pairs.setSample(b, pair, 1, info.sample(frame, 2*pair+1, 0))
+		pairs.setSample(b, pair, 2, (info.sample(frame, 2*pair, 1)+info.sample(frame, 2*pair+1, 1)+1)/2)
+		pairs.setSample(b, pair, 3, (info.sample(frame, 2*pair, 2)+info.sample(frame, 2*pair+1, 2)+1)/2)
+	}
+	return b, nil
+}
+
+// ybrToRGB converts luminance and chrominance values in [0, max] to red, green and blue values in
+// [0, max]
+func ybrToRGB(pi PhotometricInterpretation, y, cb, cr, max int) (r, g, b int) {
+	half := (max + 1) / 2
+	if pi == PhotometricYBRRCT {
+		g = y - floorDiv(cb+cr-2*half, 4)
+		return clamp(cr-half+g, max), clamp(g, max), clamp(cb-half+g, max)
+	}
+
+	fy, fcb, fcr := float64(y), float64(cb-half), float64(cr-half)
+	if pi == PhotometricYBRPartial422 {
+		scale := float64(max+1) / 256
+		fy = 255.0 / 219 * (fy - 16*scale)
+		fcb, fcr = 255.0/224*fcb, 255.0/224*fcr
+	}
+	return round(fy+1.402*fcr, max),
+		round(fy-0.344136*fcb-0.714136*fcr, max),
+		round(fy+1.772*fcb, max)
+}
+
+// rgbToYBR converts red, green and blue values in [0, max] to luminance and chrominance values in
+// [0, max]
+func rgbToYBR(pi PhotometricInterpretation, r, g, b, max int) (y, cb, cr int) {
+	half := (max + 1) / 2
+	if pi == PhotometricYBRRCT {
+		return clamp(floorDiv(r+2*g+b, 4), max), clamp(b-g+half, max), clamp(r-g+half, max)
+	}
+
+	fr, fg, fb := float64(r), float64(g), float64(b)
+	fy := 0.299*fr + 0.587*fg + 0.114*fb
+	fcb := -0.168736*fr - 0.331264*fg + 0.5*fb
+	fcr := 0.5*fr - 0.418688*fg - 0.081312*fb
+	if pi == PhotometricYBRPartial422 {
+		scale := float64(max+1) / 256
+		fy = 219.0/255*fy + 16*scale
+		fcb, fcr = 224.0/255*fcb, 224.0/255*fcr
+	}
+	return round(fy, max), round(fcb+float64(half), max), round(fcr+float64(half), max)
+}
+
+// round returns v rounded to the nearest integer in [0, max]
+func round(v float64, max int) int {
+	return clamp(int(math.Floor(v+0.5)), max)
+}
+
+// clamp returns v clamped to [0, max]
+func clamp(v, max int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// floorDiv returns a divided by b rounded down
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+// convertFrameToRGB returns the frame of luminance and chrominance samples of the given
+// PhotometricInterpretation with its samples converted to red, green and blue
+func convertFrameToRGB(frame *Frame, pi PhotometricInterpretation) (*Frame, error) {
+	info := frame.Info
+	if info.SamplesPerPixel != 3 {
+		return nil, fmt.Errorf("got %d samples per pixel, %v expects 3", info.SamplesPerPixel, pi)
+	}
+	if info.PixelRepresentation != 0 {
+		return nil, fmt.Errorf("unsupported signed samples for %v", pi)
+	}
+	max := 1<<uint(info.bitsStored()) - 1
+	samples := make([]int, len(frame.Samples))
+	for i := 0; i+2 < len(samples); i += 3 {
+		samples[i], samples[i+1], samples[i+2] = ybrToRGB(pi, frame.Samples[i], frame.Samples[i+1], frame.Samples[i+2], max)
+	}
+	return &Frame{Info: info, Samples: samples}, nil
+}
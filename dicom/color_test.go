@@ -0,0 +1,229 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestConvertToRGB(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		info  FrameInfo
+		pi    PhotometricInterpretation
+		want  []byte
+	}{
+		{
+			"YBR_FULL",
+			[]byte{76, 85, 255},
+			FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRFull,
+			[]byte{254, 0, 0},
+		},
+		{
+			"YBR_FULL color-by-plane",
+			[]byte{10, 20, 128, 128, 128, 128},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8, PlanarConfiguration: 1},
+			PhotometricYBRFull,
+			[]byte{10, 20, 10, 20, 10, 20},
+		},
+		{
+			"YBR_FULL 12 bit samples",
+			[]byte{0x00, 0x08, 0x00, 0x08, 0x00, 0x08},
+			FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 16, BitsStored: 12},
+			PhotometricYBRFull,
+			[]byte{0x00, 0x08, 0x00, 0x08, 0x00, 0x08},
+		},
+		{
+			"YBR_FULL_422 subsampled",
+			[]byte{10, 20, 128, 128},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRFull422,
+			[]byte{10, 10, 10, 20, 20, 20},
+		},
+		{
+			"YBR_FULL_422 at full resolution",
+			[]byte{10, 128, 128, 20, 128, 128},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRFull422,
+			[]byte{10, 10, 10, 20, 20, 20},
+		},
+		{
+			"YBR_PARTIAL_422 subsampled",
+			[]byte{16, 235, 128, 128},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRPartial422,
+			[]byte{0, 0, 0, 255, 255, 255},
+		},
+		{
+			"YBR_ICT",
+			[]byte{76, 85, 255},
+			FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRICT,
+			[]byte{254, 0, 0},
+		},
+		{
+			"YBR_RCT",
+			[]byte{112, 78, 228},
+			FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRRCT,
+			[]byte{200, 100, 50},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ConvertToRGB(tc.frame, tc.info, tc.pi)
+			if err != nil {
+				t.Fatalf("ConvertToRGB(_, _, %v) => %v", tc.pi, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("ConvertToRGB(_, _, %v) => %v, want %v", tc.pi, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvertFromRGB(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		info  FrameInfo
+		pi    PhotometricInterpretation
+		want  []byte
+	}{
+		{
+			"YBR_FULL",
+			[]byte{255, 0, 0},
+			FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRFull,
+			[]byte{76, 85, 255},
+		},
+		{
+			"YBR_FULL_422 averages chrominance",
+			[]byte{255, 255, 255, 0, 0, 255},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRFull422,
+			[]byte{255, 29, 192, 118},
+		},
+		{
+			"YBR_PARTIAL_422 from color-by-plane",
+			[]byte{255, 0, 255, 0, 255, 0},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8, PlanarConfiguration: 1},
+			PhotometricYBRPartial422,
+			[]byte{235, 16, 128, 128},
+		},
+		{
+			"YBR_RCT",
+			[]byte{200, 100, 50},
+			FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricYBRRCT,
+			[]byte{112, 78, 228},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ConvertFromRGB(tc.frame, tc.info, tc.pi)
+			if err != nil {
+				t.Fatalf("ConvertFromRGB(_, _, %v) => %v", tc.pi, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("ConvertFromRGB(_, _, %v) => %v, want %v", tc.pi, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvertFromRGB_reversible(t *testing.T) {
+	info := FrameInfo{Rows: 1, Columns: 4, SamplesPerPixel: 3, BitsAllocated: 8}
+	rgb := []byte{0, 0, 0, 255, 255, 255, 100, 150, 200, 64, 32, 90}
+	ybr, err := ConvertFromRGB(rgb, info, PhotometricYBRRCT)
+	if err != nil {
+		t.Fatalf("ConvertFromRGB(_, _, %v) => %v", PhotometricYBRRCT, err)
+	}
+	got, err := ConvertToRGB(ybr, info, PhotometricYBRRCT)
+	if err != nil {
+		t.Fatalf("ConvertToRGB(_, _, %v) => %v", PhotometricYBRRCT, err)
+	}
+	if !reflect.DeepEqual(got, rgb) {
+		t.Fatalf("got %v, want %v", got, rgb)
+	}
+}
+
+func TestConvertToRGB_invalid(t *testing.T) {
+	info := FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8}
+	tests := []struct {
+		name  string
+		frame []byte
+		info  FrameInfo
+		pi    PhotometricInterpretation
+	}{
+		{"RGB", make([]byte, 6), info, PhotometricRGB},
+		{"single sample", make([]byte, 2), FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8}, PhotometricYBRFull},
+		{"signed samples", make([]byte, 6), FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8, PixelRepresentation: 1}, PhotometricYBRFull},
+		{"odd columns", make([]byte, 6), FrameInfo{Rows: 2, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8}, PhotometricYBRFull422},
+		{"short frame", make([]byte, 5), info, PhotometricYBRFull},
+		{"short subsampled frame", make([]byte, 3), info, PhotometricYBRFull422},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ConvertToRGB(tc.frame, tc.info, tc.pi); err == nil {
+				t.Fatalf("ConvertToRGB(_, _, %v) => nil, expected an error", tc.pi)
+			}
+		})
+	}
+}
+
+func TestPixelData_Image_subsampled(t *testing.T) {
+	ds := NewDataSet(map[DataElementTag]interface{}{
+		RowsTag:                      []uint16{1},
+		ColumnsTag:                   []uint16{2},
+		SamplesPerPixelTag:           []uint16{3},
+		BitsAllocatedTag:             []uint16{8},
+		PhotometricInterpretationTag: []string{"YBR_FULL_422"},
+		NumberOfFramesTag:            []string{"2"},
+		PixelDataTag:                 NewBulkDataBuffer([]byte{10, 20, 128, 128}, []byte{76, 76, 85, 255}),
+	})
+	pixelData, err := NewPixelData(ds)
+	if err != nil {
+		t.Fatalf("NewPixelData(_) => %v", err)
+	}
+
+	frame, err := pixelData.NativeFrame(1)
+	if err != nil {
+		t.Fatalf("NativeFrame(1) => %v", err)
+	}
+	if want := []byte{76, 85, 255, 76, 85, 255}; !reflect.DeepEqual(frame, want) {
+		t.Fatalf("NativeFrame(1) => %v, want %v", frame, want)
+	}
+
+	got, err := pixelData.Image(0)
+	if err != nil {
+		t.Fatalf("Image(0) => %v", err)
+	}
+	want := &image.RGBA{
+		Pix:    []byte{10, 10, 10, 0xFF, 20, 20, 20, 0xFF},
+		Stride: 8,
+		Rect:   image.Rect(0, 0, 2, 1),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Image(0) => %v, want %v", got, want)
+	}
+}
@@ -42,5 +42,8 @@ func Construct(w io.Writer, dataSet *DataSet, opts ...ConstructOption) error {
 		}
 	}
 
+	if err := closeDataElementWriter(writer); err != nil {
+		return fmt.Errorf("closing DataElementWriter: %v", err)
+	}
 	return nil
 }
@@ -119,6 +119,23 @@ func TestConstruct(t *testing.T) {
 	}
 }
 
+func TestConstruct_deflated(t *testing.T) {
+	// The compressed bytes depend on the deflate implementation, so the parsed DataSets are compared
+	// rather than the files.
+	want := parse("DeflatedExplicitVRLittleEndian.dcm", t)
+
+	w := &bytes.Buffer{}
+	if err := Construct(w, want); err != nil {
+		t.Fatalf("Construct: %v", err)
+	}
+	got, err := Parse(w)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	compareDataSets(got, want, explicitVRLittleEndian.byteOrder(), t)
+}
+
 func TestConstruct_NoVR(t *testing.T) {
 	tests := []struct {
 		name string
@@ -239,7 +256,7 @@ func TestConstruct_InvalidDataSet(t *testing.T) {
 		{
 			"non-empty []BulkDataReference cannot be written",
 			NewDataSet(map[DataElementTag]interface{}{
-				PixelDataTag: []BulkDataReference{{ByteRegion{1, 2}}},
+				PixelDataTag: []BulkDataReference{{Reference: ByteRegion{1, 2}}},
 			}),
 		},
 		{
@@ -316,3 +333,47 @@ func compareFiles(t *testing.T, got, want io.Reader) {
 		t.Fatalf("got %v\n, want %v", gotBytes, wantBytes)
 	}
 }
+
+func TestConstruct_undefinedLengthUN(t *testing.T) {
+	unknownSequence := []byte{
+		0x09, 0x00, 0x10, 0x10, // Tag
+		'U', 'N', // VR
+		0x00, 0x00, // Reserved Bytes
+		0xFF, 0xFF, 0xFF, 0xFF, // Undefined Length
+		0xFE, 0xFF, 0x00, 0xE0, // Item Tag
+		0xFF, 0xFF, 0xFF, 0xFF, // Undefined Item Length
+		0x08, 0x00, 0x60, 0x00, // Modality Tag (implicit VR)
+		0x02, 0x00, 0x00, 0x00, // Length
+		'M', 'R', // Value
+		0xFE, 0xFF, 0x0D, 0xE0, // Item Delimitation Tag
+		0x00, 0x00, 0x00, 0x00, // Item Delimitation Length
+		0xFE, 0xFF, 0xDD, 0xE0, // Sequence Delimitation Tag
+		0x00, 0x00, 0x00, 0x00, // Sequence Delimitation Length
+	}
+
+	file := &bytes.Buffer{}
+	writer := mustNewDataElementWriterWithSyntax(t, file, ExplicitVRLittleEndianUID)
+	header := file.Len()
+	element, err := readDataElement(dcmReaderFromBytes(unknownSequence), explicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("readDataElement(_, _) => %v", err)
+	}
+	if element, err = processElement(element, newParseContext(explicitVRLittleEndian)); err != nil {
+		t.Fatalf("processElement(_, _) => %v", err)
+	}
+	if err := writer.WriteElement(element); err != nil {
+		t.Fatalf("WriteElement(_) => %v", err)
+	}
+
+	if got := file.Bytes()[header:]; !bytes.Equal(got, unknownSequence) {
+		t.Fatalf("got %v, want %v", got, unknownSequence)
+	}
+
+	dataSet, err := Parse(file)
+	if err != nil {
+		t.Fatalf("Parse(_) => %v", err)
+	}
+	if got := dataSet.Elements[0x00091010]; got.VR != UNVR {
+		t.Fatalf("got VR %v, want %v", got.VR, UNVR)
+	}
+}
@@ -14,9 +14,42 @@
 
 package dicom
 
+import (
+	"compress/flate"
+)
+
 // ConstructOption configures how the Construct function behaves
 type ConstructOption struct {
 	transform func(element *DataElement) (*DataElement, error)
+
+	// setting configures how the DICOM file is written rather than transforming its DataElements.
+	setting func(*constructSettings)
+}
+
+// constructSettings holds the configuration of Construct that is not expressed as a DataElement
+// transform
+type constructSettings struct {
+	deflateLevel int
+}
+
+func newConstructSettings(opts ...ConstructOption) constructSettings {
+	settings := constructSettings{deflateLevel: flate.DefaultCompression}
+	for _, opt := range opts {
+		if opt.setting != nil {
+			opt.setting(&settings)
+		}
+	}
+	return settings
+}
+
+// DeflateLevel returns an option that sets the compression level of data sets written in the
+// Deflated Explicit VR Little Endian transfer syntax. The level is one of the levels accepted by
+// compress/flate, e.g. flate.BestSpeed or flate.BestCompression. flate.DefaultCompression is used
+// if the option is not given. The option has no effect on other transfer syntaxes.
+func DeflateLevel(level int) ConstructOption {
+	return ConstructOption{setting: func(settings *constructSettings) {
+		settings.deflateLevel = level
+	}}
 }
 
 // ConstructOptionWithTransform returns a construct option that applies the given transformation to
@@ -15,7 +15,11 @@
 package dicom
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -124,6 +128,8 @@ type DataElement struct {
 	// []uint16,
 	// []int32,
 	// []uint32,
+	// []int64,
+	// []uint64,
 	// []float32,
 	// []float64
 	// []BulkDataReference
@@ -131,6 +137,9 @@ type DataElement struct {
 	// BulkDataIterator
 	// *Sequence
 	// SequenceIterator
+	//
+	// *Sequence and SequenceIterator are also used for elements of VR UN with undefined length,
+	// which hold a sequence encoded in implicit VR little endian as specified in CP-246.
 	ValueField DataElementValue
 
 	// ValueLength is equal to the length of the ValueField in bytes.
@@ -160,8 +169,7 @@ func (e *DataElement) string(indentLvl int) string {
 // IntValue returns the first value of ValueField as an int64 if it can safely do so. If it cannot
 // safely do so, an error is returned.
 //
-// Note since DICOM does not support binary integers larger than 32-bits. This is safe as long as
-// there is no integer string that overflows an int64.
+// Note this is safe as long as there is no integer string or UV value that overflows an int64.
 func (e *DataElement) IntValue() (int64, error) {
 	switch v := e.ValueField.(type) {
 	case []string:
@@ -192,11 +200,80 @@ func (e *DataElement) IntValue() (int64, error) {
 		if len(v) > 0 {
 			return int64(v[0]), nil
 		}
+	case []int64:
+		if len(v) > 0 {
+			return v[0], nil
+		}
+	case []uint64:
+		if len(v) > 0 {
+			if v[0] > math.MaxInt64 {
+				return 0, fmt.Errorf("value %d overflows int64", v[0])
+			}
+			return int64(v[0]), nil
+		}
 	}
 
 	return 0, fmt.Errorf("unexpected type %T (expected integer array or integer string)", e.ValueField)
 }
 
+// Uint16Values returns the values of ValueField as 16-bit words. The words of an OW DataElement
+// buffered into a BulkDataBuffer are decoded in the byte order of the buffer, with the fragments of
+// the buffer concatenated. []uint16 values, such as those of US DataElements, are returned as is.
+// An error is returned for any other ValueField, including BulkDataIterators which must be buffered
+// first.
+func (e *DataElement) Uint16Values() ([]uint16, error) {
+	switch v := e.ValueField.(type) {
+	case []uint16:
+		return v, nil
+	case BulkDataBuffer:
+		if e.VR != OWVR {
+			return nil, fmt.Errorf("unexpected VR %v (expected OW)", e.VR)
+		}
+		if v.Length() == UndefinedLength {
+			return nil, fmt.Errorf("encapsulated bulk data can't be converted to words")
+		}
+		if v.Length()%2 != 0 {
+			return nil, fmt.Errorf("odd length %d of OW value", v.Length())
+		}
+		words := make([]uint16, 0, v.Length()/2)
+		data := bytes.Join(v.Data(), nil)
+		for i := 0; i+1 < len(data); i += 2 {
+			words = append(words, v.ByteOrder().Uint16(data[i:]))
+		}
+		return words, nil
+	}
+
+	return nil, fmt.Errorf("unexpected type %T (expected []uint16 or BulkDataBuffer)", e.ValueField)
+}
+
+// FloatValues returns the values of ValueField as float64s. Decimal and integer strings, such as
+// those of DS and IS DataElements, are parsed after trimming padding. An error is returned if
+// ValueField holds neither strings nor floating point values.
+func (e *DataElement) FloatValues() ([]float64, error) {
+	switch v := e.ValueField.(type) {
+	case []string:
+		values := make([]float64, 0, len(v))
+		for _, s := range v {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, f)
+		}
+		return values, nil
+	case []float32:
+		values := make([]float64, 0, len(v))
+		for _, f := range v {
+			values = append(values, float64(f))
+		}
+		return values, nil
+	case []float64:
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unexpected type %T (expected string array or floating point array)", e.ValueField)
+}
+
 // StringValue value returns the first element of ValueField as a string if ValueField is a string
 // slice with at least 1 value. If this is not the case, an error is returned.
 func (e *DataElement) StringValue() (string, error) {
@@ -220,6 +297,26 @@ type DataSet struct {
 	// Length returns the number of bytes used to store the DataSet in the DICOM file. Can be equal
 	// to UndefinedLength (or equivalently 0xFFFFFFFF) to represent undefined length
 	Length uint32
+
+	// Recoveries lists the deviations from the DICOM file format that were tolerated to parse the
+	// file, most of which require the Lenient option. It is only set on the DataSet returned by
+	// Parse.
+	Recoveries []Recovery
+
+	// Truncated is true when parsing ended before the end of the data set because of the StopAtTag
+	// option. It is only set on the DataSet returned by Parse.
+	Truncated bool
+
+	// Diagnostics lists the non-conformances found while parsing the DataSet and its sequence items,
+	// in the order encountered. It is only set on the DataSet returned by Parse.
+	Diagnostics []Diagnostic
+
+	// source is the file the DataSet was parsed from when created by ParseReaderAt or OpenFile.
+	// It is used to resolve BulkDataReferences.
+	source *io.SectionReader
+
+	// closer releases source when the DataSet was created by OpenFile
+	closer io.Closer
 }
 
 // NewDataSet is a helper method for creating a DataSet for standard tags.
@@ -292,18 +389,62 @@ func (d *DataSet) MetaElements() *DataSet {
 	return ret
 }
 
+// OpenBulkData returns an io.SectionReader over the bytes in the file described by ref. ref can be
+// any BulkDataReference in the DataSet including the fragment and frame references within nested
+// sequence items. An error is returned if the DataSet was not created by ParseReaderAt or OpenFile
+// or if the DataSet was parsed from the deflated transfer syntax, in which case the offsets of
+// references do not correspond to positions within the file.
+func (d *DataSet) OpenBulkData(ref BulkDataReference) (*io.SectionReader, error) {
+	if d.source == nil {
+		return nil, errors.New("data set has no random access source to resolve bulk data from")
+	}
+
+	region := ref.Reference
+	if region.Offset < 0 || region.Length < 0 || region.Offset+region.Length > d.source.Size() {
+		return nil, fmt.Errorf("bulk data reference %v is outside of source of size %v", region, d.source.Size())
+	}
+	return io.NewSectionReader(d.source, region.Offset, region.Length), nil
+}
+
+// Close releases the file held open by a DataSet created by OpenFile. For any other DataSet Close
+// does nothing.
+func (d *DataSet) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+// TransferSyntax returns the TransferSyntax identified by the TransferSyntaxUID (0002,0010)
+// element of the DataSet. An error is returned if the element is missing or if the transfer syntax
+// is unknown, see LookupTransferSyntax.
+func (d *DataSet) TransferSyntax() (TransferSyntax, error) {
+	syntaxUID, err := d.transferSyntaxUID()
+	if err != nil {
+		return TransferSyntax{}, err
+	}
+	return LookupTransferSyntax(syntaxUID)
+}
+
 func (d *DataSet) transferSyntax() (transferSyntax, error) {
+	syntaxUID, err := d.transferSyntaxUID()
+	if err != nil {
+		return nil, err
+	}
+	return lookupTransferSyntax(syntaxUID), nil
+}
+
+func (d *DataSet) transferSyntaxUID() (string, error) {
 	syntaxElement, ok := d.Elements[TransferSyntaxUIDTag]
 	if !ok {
-		return nil, fmt.Errorf("transfer syntax element is missing from data set")
+		return "", fmt.Errorf("transfer syntax element is missing from data set")
 	}
 
 	syntaxUID, err := syntaxElement.StringValue()
 	if err != nil {
-		return nil, fmt.Errorf("transfer syntax element cannot be converted to string: %v", err)
+		return "", fmt.Errorf("transfer syntax element cannot be converted to string: %v", err)
 	}
-
-	return lookupTransferSyntax(syntaxUID), nil
+	return syntaxUID, nil
 }
 
 func (d *DataSet) isMetaHeader() bool {
@@ -15,6 +15,9 @@
 package dicom
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
 	"math"
 	"reflect"
 	"strconv"
@@ -262,6 +265,116 @@ func TestDataElement_IntValue_invalidCases(t *testing.T) {
 	}
 }
 
+func TestDataElement_FloatValues(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		want []float64
+	}{
+		{"decimal strings with padding", []string{" -1024.5", "1e2 "}, []float64{-1024.5, 100}},
+		{"integer strings", []string{"12"}, []float64{12}},
+		{"FL values", []float32{0.5, 2}, []float64{0.5, 2}},
+		{"FD values", []float64{0.25}, []float64{0.25}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			elem := &DataElement{ValueField: tc.in}
+			got, err := elem.FloatValues()
+			if err != nil {
+				t.Fatalf("FloatValues() => %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("FloatValues() => %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	for _, in := range []interface{}{[]string{"1.5.1"}, []uint16{1}} {
+		elem := &DataElement{ValueField: in}
+		if _, err := elem.FloatValues(); err == nil {
+			t.Errorf("FloatValues() of %v => nil, expected an error", in)
+		}
+	}
+}
+
+func TestDataElement_Uint16Values(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *DataElement
+		want []uint16
+	}{
+		{
+			"US values",
+			&DataElement{Tag: RowsTag, VR: USVR, ValueField: []uint16{1, 2}},
+			[]uint16{1, 2},
+		},
+		{
+			"little endian OW buffer",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataBuffer([]byte{0x01, 0x02}, []byte{0x03, 0x04})},
+			[]uint16{0x0201, 0x0403},
+		},
+		{
+			"big endian OW buffer",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataBufferWithByteOrder(binary.BigEndian, []byte{0x01, 0x02, 0x03, 0x04})},
+			[]uint16{0x0102, 0x0304},
+		},
+		{
+			"words spanning fragments",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataBuffer([]byte{0x01}, []byte{0x02})},
+			[]uint16{0x0201},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.in.Uint16Values()
+			if err != nil {
+				t.Fatalf("Uint16Values() => %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %x, want %x", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDataElement_Uint16Values_invalidCases(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *DataElement
+	}{
+		{
+			"OB buffer",
+			&DataElement{Tag: PixelDataTag, VR: OBVR, ValueField: NewBulkDataBuffer([]byte{0x01, 0x02})},
+		},
+		{
+			"odd length",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataBuffer([]byte{0x01, 0x02, 0x03})},
+		},
+		{
+			"encapsulated",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewEncapsulatedFormatBuffer(nil, []byte{0x01, 0x02})},
+		},
+		{
+			"streamed bulk data",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataIterator(bytes.NewReader([]byte{0x01, 0x02}), 0)},
+		},
+		{
+			"strings",
+			&DataElement{Tag: PatientNameTag, VR: PNVR, ValueField: []string{"name"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, err := tc.in.Uint16Values(); err == nil {
+				t.Fatalf("Uint16Values() => %v, expected an error", got)
+			}
+		})
+	}
+}
+
 func TestDataElement_StringValue(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -383,13 +496,13 @@ func TestDataSet_SortedTags(t *testing.T) {
 		},
 		{
 			"when Elements contains multiple elements",
-			&DataSet{map[DataElementTag]*DataElement{
+			&DataSet{Elements: map[DataElementTag]*DataElement{
 				PrivateInformationTag:           {},
 				PrivateInformationCreatorUIDTag: {},
 				SourceApplicationEntityTitleTag: {},
 				ImplementationVersionNameTag:    {},
 				ImplementationClassUIDTag:       {},
-			}, UndefinedLength},
+			}, Length: UndefinedLength},
 			[]DataElementTag{
 				ImplementationClassUIDTag,
 				ImplementationVersionNameTag,
@@ -428,13 +541,13 @@ func TestDataSet_SortedElements(t *testing.T) {
 		},
 		{
 			"when Elements contains multiple elements",
-			&DataSet{map[DataElementTag]*DataElement{
+			&DataSet{Elements: map[DataElementTag]*DataElement{
 				PrivateInformationTag:           {Tag: PrivateInformationTag},
 				PrivateInformationCreatorUIDTag: {Tag: PrivateInformationCreatorUIDTag},
 				SourceApplicationEntityTitleTag: {Tag: SourceApplicationEntityTitleTag},
 				ImplementationVersionNameTag:    {Tag: ImplementationVersionNameTag},
 				ImplementationClassUIDTag:       {Tag: ImplementationClassUIDTag},
-			}, UndefinedLength},
+			}, Length: UndefinedLength},
 			[]*DataElement{
 				{Tag: ImplementationClassUIDTag},
 				{Tag: ImplementationVersionNameTag},
@@ -494,3 +607,77 @@ func TestDataSet_MetaElements(t *testing.T) {
 		})
 	}
 }
+
+func TestDataSet_TransferSyntax(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      *DataSet
+		wantUID string
+		wantErr bool
+	}{
+		{
+			"known transfer syntax",
+			NewDataSet(map[DataElementTag]interface{}{TransferSyntaxUIDTag: []string{ExplicitVRBigEndianUID}}),
+			ExplicitVRBigEndianUID,
+			false,
+		},
+		{
+			"unknown transfer syntax",
+			NewDataSet(map[DataElementTag]interface{}{TransferSyntaxUIDTag: []string{"1.2.3.4"}}),
+			"",
+			true,
+		},
+		{
+			"missing transfer syntax element",
+			NewDataSet(map[DataElementTag]interface{}{}),
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.in.TransferSyntax()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("TransferSyntax() => %v, want error: %v", err, tc.wantErr)
+			}
+			if got.UID != tc.wantUID {
+				t.Fatalf("got UID %q, want %q", got.UID, tc.wantUID)
+			}
+		})
+	}
+}
+
+func TestDataSet_OpenBulkData(t *testing.T) {
+	source := io.NewSectionReader(bytes.NewReader(sampleBytes), 0, int64(len(sampleBytes)))
+
+	tests := []struct {
+		name string
+		in   *DataSet
+		ref  BulkDataReference
+	}{
+		{
+			"when the data set has no source",
+			&DataSet{},
+			BulkDataReference{Reference: ByteRegion{0, 1}},
+		},
+		{
+			"when the reference extends past the end of the source",
+			&DataSet{source: source},
+			BulkDataReference{Reference: ByteRegion{2, int64(len(sampleBytes))}},
+		},
+		{
+			"when the reference has a negative offset",
+			&DataSet{source: source},
+			BulkDataReference{Reference: ByteRegion{-1, 1}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.in.OpenBulkData(tc.ref); err == nil {
+				t.Fatalf("expected error to be returned")
+			}
+		})
+	}
+}
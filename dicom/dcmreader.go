@@ -25,10 +25,13 @@ import (
 // parsing tags, numbers, strings
 type dcmReader struct {
 	cr *countReader
+
+	// limiter enforces the limits of the parse the dcmReader reads for. It may be nil.
+	limiter *parseLimiter
 }
 
 func newDcmReader(r io.Reader) *dcmReader {
-	return &dcmReader{&countReader{r, 0}}
+	return &dcmReader{cr: &countReader{r, 0}}
 }
 
 func (dr *dcmReader) Tag(order binary.ByteOrder) (DataElementTag, error) {
@@ -47,7 +50,7 @@ func (dr *dcmReader) Tag(order binary.ByteOrder) (DataElementTag, error) {
 // Limit returns a dcmReader that shares the same underlying io.Reader that returns
 // EOF after reading n bytes.
 func (dr *dcmReader) Limit(n int64) *dcmReader {
-	return &dcmReader{limitCountReader(dr.cr, n)}
+	return &dcmReader{limitCountReader(dr.cr, n), dr.limiter}
 }
 
 // Skip advances the input stream by n bytes
@@ -0,0 +1,164 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiagnosticCategory classifies the non-conformances reported in Diagnostics
+type DiagnosticCategory int
+
+const (
+	// VRMismatch indicates the explicit VR of a DataElement differs from the VR of its tag in the
+	// data dictionary
+	VRMismatch DiagnosticCategory = iota
+
+	// OddLength indicates a DataElement has a value length that is not even as required by PS3.5
+	// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_7.1.1
+	OddLength
+
+	// UnknownTransferSyntax indicates the transfer syntax UID is neither defined by the standard nor
+	// registered with RegisterTransferSyntax. The data set was read as explicit VR little endian.
+	UnknownTransferSyntax
+
+	// DroppedElement indicates a DataElement was excluded from the returned DataSet because it could
+	// not be interpreted, for example by SplitUncompressedPixelDataFrames
+	DroppedElement
+
+	// DuplicateTag indicates a DataElement has the same tag as a previous DataElement of the same
+	// DataSet. The last DataElement with the tag is kept.
+	DuplicateTag
+
+	// OutOfOrderTag indicates a DataElement has a lower tag than the previous DataElement of the
+	// same DataSet. DataElements must be sorted by tag as specified in PS3.5
+	// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_7.1.
+	OutOfOrderTag
+)
+
+func (c DiagnosticCategory) String() string {
+	switch c {
+	case VRMismatch:
+		return "VR mismatch"
+	case OddLength:
+		return "odd length"
+	case UnknownTransferSyntax:
+		return "unknown transfer syntax"
+	case DroppedElement:
+		return "dropped element"
+	case DuplicateTag:
+		return "duplicate tag"
+	case OutOfOrderTag:
+		return "out of order tag"
+	default:
+		return fmt.Sprintf("DiagnosticCategory(%d)", int(c))
+	}
+}
+
+// Diagnostic describes a non-conformance of the input that did not prevent parsing
+type Diagnostic struct {
+	Category DiagnosticCategory
+
+	// Path lists the sequence items enclosing the DataElement, see ParseContext.Path
+	Path []SequenceItem
+
+	// Tag is the tag of the DataElement the Diagnostic is about
+	Tag DataElementTag
+
+	// Offset is the byte offset of the DataElement in the input. For deflated transfer syntaxes,
+	// offsets of the data set are positions within the inflated stream.
+	Offset int64
+
+	// Message describes the non-conformance
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	path := make([]string, 0, len(d.Path)+1)
+	for _, item := range d.Path {
+		path = append(path, fmt.Sprintf("%v[%d]", item.Tag, item.Index))
+	}
+	path = append(path, d.Tag.String())
+	return fmt.Sprintf("%v at %v (offset %d): %v", d.Category, strings.Join(path, "."), d.Offset, d.Message)
+}
+
+// elementChecker reports the Diagnostics of the DataElements of a single DataSet that can be found
+// without interpreting their values
+type elementChecker struct {
+	ctx  *ParseContext
+	seen map[DataElementTag]bool
+	last DataElementTag
+}
+
+func newElementChecker(ctx *ParseContext) *elementChecker {
+	return &elementChecker{ctx: ctx, seen: map[DataElementTag]bool{}}
+}
+
+func (c *elementChecker) check(element *DataElement) {
+	switch {
+	case c.seen[element.Tag]:
+		c.ctx.Warn(DuplicateTag, element.Tag, "tag already present in data set")
+	case element.Tag < c.last:
+		c.ctx.Warn(OutOfOrderTag, element.Tag, fmt.Sprintf("tag follows %v", c.last))
+	}
+	c.seen[element.Tag] = true
+	c.last = element.Tag
+
+	if element.ValueLength != UndefinedLength && element.ValueLength%2 != 0 {
+		c.ctx.Warn(OddLength, element.Tag, fmt.Sprintf("value length %d", element.ValueLength))
+	}
+
+	// Implicit VRs are looked up in the dictionary, while the File Meta Information is always
+	// encoded with explicit VRs.
+	if c.ctx.ExplicitVR() || element.Tag.IsMetaElement() {
+		if want, ok := vrMismatch(element); ok {
+			c.ctx.Warn(VRMismatch, element.Tag, fmt.Sprintf("VR %v, dictionary VR %v", element.VR, want))
+		}
+	}
+
+	if element.Tag == TransferSyntaxUIDTag && len(c.ctx.Path) == 0 {
+		if uid, err := element.StringValue(); err == nil && !isKnownTransferSyntax(uid) {
+			c.ctx.Warn(UnknownTransferSyntax, element.Tag, fmt.Sprintf("transfer syntax %q", uid))
+		}
+	}
+}
+
+// ambiguousVRs are the VRs of tags the dictionary allows multiple VRs for, such as "US or SS" and
+// "OB or OW". Only one of them is kept by the dictionary, so mismatches among these VRs are not
+// reported.
+var ambiguousVRs = map[*VR]bool{OBVR: true, OWVR: true, USVR: true, SSVR: true}
+
+// vrMismatch returns the dictionary VR of the element and true if it differs from the element's VR
+func vrMismatch(element *DataElement) (*VR, bool) {
+	if element.Tag.IsPrivate() && !element.Tag.IsPrivateCreator() {
+		return nil, false
+	}
+	want := element.Tag.DictionaryVR()
+	if want == UNVR || want == element.VR {
+		return nil, false
+	}
+	if ambiguousVRs[want] && ambiguousVRs[element.VR] {
+		return nil, false
+	}
+	return want, true
+}
+
+// isKnownTransferSyntax returns true if uid is a standard transfer syntax or one registered with
+// RegisterTransferSyntax
+func isKnownTransferSyntax(uid string) bool {
+	_, err := LookupTransferSyntax(uid)
+	return err == nil
+}
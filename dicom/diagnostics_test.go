@@ -0,0 +1,164 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseDataSet_diagnostics(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        []byte
+		syntaxUID string
+		opts      []ParseOption
+		want      []Diagnostic
+	}{
+		{
+			"conformant data sets have no diagnostics",
+			[]byte{
+				0x08, 0x00, 0x60, 0x00, 'C', 'S', 0x02, 0x00, 'M', 'R', // Modality
+				0x10, 0x00, 0x10, 0x00, 'P', 'N', 0x04, 0x00, 'J', 'o', 'e', ' ', // PatientName
+			},
+			ExplicitVRLittleEndianUID,
+			nil,
+			nil,
+		},
+		{
+			"odd lengths, out of order and duplicate tags and VR mismatches are reported",
+			[]byte{
+				0x10, 0x00, 0x10, 0x00, 'P', 'N', 0x03, 0x00, 'J', 'o', 'e', // PatientName
+				0x08, 0x00, 0x60, 0x00, 'L', 'O', 0x02, 0x00, 'M', 'R', // Modality
+				0x10, 0x00, 0x10, 0x00, 'P', 'N', 0x02, 0x00, 'J', 'o', // PatientName
+			},
+			ExplicitVRLittleEndianUID,
+			nil,
+			[]Diagnostic{
+				{Category: OddLength, Tag: PatientNameTag, Offset: 0},
+				{Category: OutOfOrderTag, Tag: ModalityTag, Offset: 11},
+				{Category: VRMismatch, Tag: ModalityTag, Offset: 11},
+				{Category: DuplicateTag, Tag: PatientNameTag, Offset: 21},
+			},
+		},
+		{
+			"diagnostics of sequence items have the path of the item",
+			[]byte{
+				0x08, 0x00, 0x40, 0x11, 'S', 'Q', 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, // ReferencedImageSequence
+				0xFE, 0xFF, 0x00, 0xE0, 0xFF, 0xFF, 0xFF, 0xFF, // Item
+				0x08, 0x00, 0x55, 0x11, 'U', 'I', 0x03, 0x00, '1', '.', '2', // ReferencedSOPInstanceUID
+				0xFE, 0xFF, 0x0D, 0xE0, 0x00, 0x00, 0x00, 0x00, // Item Delimitation
+				0xFE, 0xFF, 0xDD, 0xE0, 0x00, 0x00, 0x00, 0x00, // Sequence Delimitation
+			},
+			ExplicitVRLittleEndianUID,
+			nil,
+			[]Diagnostic{{
+				Category: OddLength,
+				Path:     []SequenceItem{{ReferencedImageSequenceTag, 0}},
+				Tag:      ReferencedSOPInstanceUIDTag,
+				Offset:   20,
+			}},
+		},
+		{
+			"implicit VRs are not reported as mismatches",
+			[]byte{0x08, 0x00, 0x60, 0x00, 0x02, 0x00, 0x00, 0x00, 'M', 'R'},
+			ImplicitVRLittleEndianUID,
+			nil,
+			nil,
+		},
+		{
+			"unknown transfer syntaxes are reported",
+			[]byte{0x08, 0x00, 0x60, 0x00, 'C', 'S', 0x02, 0x00, 'M', 'R'},
+			"1.2.3.4",
+			[]ParseOption{Lenient},
+			[]Diagnostic{{Category: UnknownTransferSyntax, Tag: TransferSyntaxUIDTag}},
+		},
+		{
+			"elements dropped by options are reported",
+			[]byte{
+				0x28, 0x00, 0x10, 0x00, 'U', 'S', 0x02, 0x00, 0x01, 0x00, // Rows
+				0x28, 0x00, 0x11, 0x00, 'U', 'S', 0x02, 0x00, 0x01, 0x00, // Columns
+				0x28, 0x00, 0x00, 0x01, 'U', 'S', 0x02, 0x00, 0x04, 0x00, // BitsAllocated
+				0xE0, 0x7F, 0x10, 0x00, 'O', 'B', 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02, // PixelData
+			},
+			ExplicitVRLittleEndianUID,
+			[]ParseOption{SplitUncompressedPixelDataFrames()},
+			[]Diagnostic{{Category: DroppedElement, Tag: PixelDataTag, Offset: 30}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dataSet, err := ParseDataSet(bytes.NewReader(tc.in), tc.syntaxUID, tc.opts...)
+			if err != nil {
+				t.Fatalf("ParseDataSet(_, _) => %v", err)
+			}
+
+			var got []Diagnostic
+			for _, d := range dataSet.Diagnostics {
+				d.Message = ""
+				got = append(got, d)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got diagnostics %v, want %v", dataSet.Diagnostics, tc.want)
+			}
+		})
+	}
+}
+
+func TestParse_diagnosticOffsets(t *testing.T) {
+	dataSet := NewDataSet(map[DataElementTag]interface{}{
+		TransferSyntaxUIDTag: []string{"1.2.3"},
+		ModalityTag:          []string{"MR"},
+	})
+	file := &bytes.Buffer{}
+	if err := Construct(file, dataSet); err != nil {
+		t.Fatalf("Construct(_, _) => %v", err)
+	}
+
+	got, err := Parse(file)
+	if err != nil {
+		t.Fatalf("Parse(_) => %v", err)
+	}
+
+	// The syntax element follows the preamble, prefix and 12 byte meta group length element.
+	want := []Diagnostic{{
+		Category: UnknownTransferSyntax,
+		Tag:      TransferSyntaxUIDTag,
+		Offset:   132 + 12,
+		Message:  `transfer syntax "1.2.3"`,
+	}}
+	if !reflect.DeepEqual(got.Diagnostics, want) {
+		t.Fatalf("got diagnostics %v, want %v", got.Diagnostics, want)
+	}
+}
+
+func TestParse_conformantFilesHaveNoDiagnostics(t *testing.T) {
+	files := []string{
+		"ExplicitVRLittleEndian.dcm",
+		"ImplicitVRLittleEndian.dcm",
+		"ExplicitVRBigEndian.dcm",
+		"DeflatedExplicitVRLittleEndian.dcm",
+	}
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			if got := parse(file, t).Diagnostics; got != nil {
+				t.Fatalf("got diagnostics %v, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Frame is a frame of pixel data with its samples decoded into integers
+type Frame struct {
+	// Info describes the layout of the frame
+	Info FrameInfo
+
+	// Samples holds the values of the samples of the frame, color-by-pixel and in row-major order
+	// regardless of the PlanarConfiguration of the pixel data. Values hold only the BitsStored bits
+	// of each sample ending at HighBit and are sign extended when PixelRepresentation is 1.
+	Samples []int
+}
+
+// NewFrame decodes a frame in the native format described by info. Samples must be 8, 16 or 32 bit
+// little endian values, or single bits starting at the least significant bit of the first byte of
+// the frame when BitsAllocated is 1.
+func NewFrame(frame []byte, info FrameInfo) (*Frame, error) {
+	if err := validateNativeFrameInfo(info); err != nil {
+		return nil, err
+	}
+	if len(frame) < info.Length() {
+		return nil, fmt.Errorf("got frame of length %d, expected %d", len(frame), info.Length())
+	}
+
+	bitsStored := uint(info.bitsStored())
+	shift := uint(info.highBit()+1) - bitsStored
+	mask := 1<<bitsStored - 1
+	signBit := 1 << (bitsStored - 1)
+
+	pixels := info.Rows * info.Columns
+	samples := make([]int, 0, pixels*info.SamplesPerPixel)
+	for pixel := 0; pixel < pixels; pixel++ {
+		for s := 0; s < info.SamplesPerPixel; s++ {
+			v := info.sample(frame, pixel, s) >> shift & mask
+			if info.PixelRepresentation == 1 && v&signBit != 0 {
+				v -= 1 << bitsStored
+			}
+			samples = append(samples, v)
+		}
+	}
+	return &Frame{Info: info, Samples: samples}, nil
+}
+
+// At returns the value of the given sample of the pixel at column x and row y
+func (f *Frame) At(x, y, sample int) int {
+	return f.Samples[(y*f.Info.Columns+x)*f.Info.SamplesPerPixel+sample]
+}
+
+// PixelData gives access to the frames of the PixelData element of a DataSet. Frames are read and
+// decoded one at a time.
+type PixelData struct {
+	// Info describes the layout of the frames in the native format
+	Info FrameInfo
+
+	// PhotometricInterpretation is the interpretation of the samples of the frames in the native
+	// format
+	PhotometricInterpretation PhotometricInterpretation
+
+	// Palette is the PaletteColorLUT of PALETTE COLOR pixel data
+	Palette *PaletteColorLUT
+
+	numberOfFrames int
+
+	// nativeFrame returns frame i in the native format with little endian samples
+	nativeFrame func(i int) ([]byte, error)
+}
+
+// NewPixelData returns the PixelData of the DataSet, described by the Image Pixel Module elements
+// of the DataSet, see NewFrameInfo.
+//
+// Pixel data in the native format must be buffered into a BulkDataBuffer, with or without
+// SplitUncompressedPixelDataFrames, or be referenced with ReferenceBulkData in a DataSet created
+// by ParseReaderAt or OpenFile. The byte order of referenced pixel data is that of the transfer
+// syntax of the DataSet.
+//
+// Pixel data in the encapsulated format is decoded with the Codec of the transfer syntax of the
+// DataSet, see LookupCodec, and its frames are located as described in NewFrameIndex.
+//
+// The Palette of PALETTE COLOR pixel data is read with NewPaletteColorLUT.
+func NewPixelData(ds *DataSet) (*PixelData, error) {
+	p, err := newPixelData(ds)
+	if err != nil {
+		return nil, err
+	}
+	if p.PhotometricInterpretation == PhotometricPaletteColor {
+		if p.Palette, err = NewPaletteColorLUT(ds); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func newPixelData(ds *DataSet) (*PixelData, error) {
+	info, err := NewFrameInfo(ds)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateNativeFrameInfo(info); err != nil {
+		return nil, err
+	}
+	pi, err := photometricInterpretation(ds, info)
+	if err != nil {
+		return nil, err
+	}
+	element, ok := ds.Elements[PixelDataTag]
+	if !ok {
+		return nil, fmt.Errorf("missing PixelData element")
+	}
+
+	if element.ValueLength == UndefinedLength {
+		return newEncapsulatedPixelData(ds, info, pi)
+	}
+
+	numberOfFrames := int64(1)
+	if element, ok := ds.Elements[NumberOfFramesTag]; ok {
+		if numberOfFrames, err = element.IntValue(); err != nil {
+			return nil, fmt.Errorf("reading NumberOfFrames: %v", err)
+		}
+		if numberOfFrames <= 0 {
+			numberOfFrames = 1
+		}
+	}
+
+	if info.BitsAllocated == 1 {
+		return newBitPackedPixelData(ds, element, info, pi, numberOfFrames)
+	}
+
+	r, err := newNativePixelData(ds, element)
+	if err != nil {
+		return nil, err
+	}
+	frameLength := int64(info.Length())
+	subsampled := pi.subsampled() && info.PlanarConfiguration == 0
+	if subsampled {
+		frameLength = int64(info.subsampledLength())
+	}
+	// dividing rather than multiplying avoids overflow with a hostile NumberOfFrames
+	if frameLength <= 0 || numberOfFrames > r.length/frameLength {
+		return nil, fmt.Errorf("pixel data of length %d can't hold %d frames of length %d", r.length, numberOfFrames, frameLength)
+	}
+	return &PixelData{
+		Info:                      info,
+		PhotometricInterpretation: pi,
+		numberOfFrames:            int(numberOfFrames),
+		nativeFrame: func(i int) ([]byte, error) {
+			frame, err := r.read(int64(i)*frameLength, int64(i+1)*frameLength)
+			if err != nil || !subsampled {
+				return frame, err
+			}
+			return upsample(frame, info)
+		},
+	}, nil
+}
+
+// newBitPackedPixelData returns the PixelData of pixel data with 1 bit allocated, whose frames are
+// packed contiguously and so may start and end mid-byte. Frames are returned starting at the least
+// significant bit of their first byte.
+func newBitPackedPixelData(ds *DataSet, element *DataElement, info FrameInfo, pi PhotometricInterpretation, numberOfFrames int64) (*PixelData, error) {
+	frameBits := int64(info.Rows * info.Columns * info.SamplesPerPixel)
+	p := &PixelData{Info: info, PhotometricInterpretation: pi, numberOfFrames: int(numberOfFrames)}
+
+	// frames split by SplitUncompressedPixelDataFrames are located individually
+	switch v := element.ValueField.(type) {
+	case *bitPackedBuffer:
+		if int64(len(v.frames)) < numberOfFrames {
+			return nil, fmt.Errorf("got %d frames of pixel data, expected %d", len(v.frames), numberOfFrames)
+		}
+		p.nativeFrame = func(i int) ([]byte, error) {
+			return alignBits(v.frames[i], 0, frameBits)
+		}
+		return p, nil
+	case []BulkDataReference:
+		if len(v) == 0 || v[0].BitLength == 0 {
+			break
+		}
+		if int64(len(v)) < numberOfFrames {
+			return nil, fmt.Errorf("got %d frame references, expected %d", len(v), numberOfFrames)
+		}
+		// frames of big endian OW words are located in the words with their bytes swapped, see
+		// SplitUncompressedPixelDataFrames
+		swapWords := false
+		if syntax, err := ds.TransferSyntax(); err == nil && element.VR == OWVR {
+			swapWords = syntax.ByteOrder == binary.BigEndian
+		}
+		p.nativeFrame = func(i int) ([]byte, error) {
+			ref := v[i]
+			pad := int64(0)
+			if swapWords {
+				// words are swapped whole
+				pad = ref.Reference.Offset % 2
+				ref.Reference.Offset -= pad
+				ref.Reference.Length = (ref.Reference.Length + pad + 1) &^ 1
+			}
+			r, err := ds.OpenBulkData(ref)
+			if err != nil {
+				return nil, err
+			}
+			b := make([]byte, ref.Reference.Length)
+			if n, err := r.ReadAt(b, 0); n < len(b) {
+				return nil, err
+			}
+			if swapWords {
+				swapBytes(b, 2)
+				b = b[pad : pad+v[i].Reference.Length]
+			}
+			return alignBits(b, v[i].BitOffset, v[i].BitLength)
+		}
+		return p, nil
+	}
+
+	r, err := newNativePixelData(ds, element)
+	if err != nil {
+		return nil, err
+	}
+	if frameBits <= 0 || numberOfFrames > r.length*8/frameBits {
+		return nil, fmt.Errorf("pixel data of length %d can't hold %d frames of %d bits", r.length, numberOfFrames, frameBits)
+	}
+	p.nativeFrame = func(i int) ([]byte, error) {
+		start, end := int64(i)*frameBits, int64(i+1)*frameBits
+		b, err := r.read(start/8, (end+7)/8)
+		if err != nil {
+			return nil, err
+		}
+		return alignBits(b, start%8, frameBits)
+	}
+	return p, nil
+}
+
+func newEncapsulatedPixelData(ds *DataSet, info FrameInfo, pi PhotometricInterpretation) (*PixelData, error) {
+	syntax, err := ds.TransferSyntax()
+	if err != nil {
+		return nil, err
+	}
+	codec, err := LookupCodec(syntax.UID)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := NewFrameIndex(ds)
+	if err != nil {
+		return nil, err
+	}
+	return &PixelData{
+		Info:                      info,
+		PhotometricInterpretation: pi,
+		numberOfFrames:            idx.NumberOfFrames(),
+		nativeFrame: func(i int) ([]byte, error) {
+			data, err := idx.Frame(i)
+			if err != nil {
+				return nil, err
+			}
+			return codec.Decode(data, info)
+		},
+	}, nil
+}
+
+// NumberOfFrames returns the number of frames of the pixel data
+func (p *PixelData) NumberOfFrames() int {
+	return p.numberOfFrames
+}
+
+// NativeFrame returns frame i in the native format described by Info with little endian samples,
+// decoding it first if the pixel data is in the encapsulated format. YBR_FULL_422 and
+// YBR_PARTIAL_422 frames are upsampled to full resolution.
+func (p *PixelData) NativeFrame(i int) ([]byte, error) {
+	if i < 0 || i >= p.numberOfFrames {
+		return nil, fmt.Errorf("frame %d out of range [0, %d)", i, p.numberOfFrames)
+	}
+	frame, err := p.nativeFrame(i)
+	if err != nil {
+		return nil, fmt.Errorf("reading frame %d: %v", i, err)
+	}
+	return frame, nil
+}
+
+// Frame returns frame i with its samples decoded
+func (p *PixelData) Frame(i int) (*Frame, error) {
+	frame, err := p.NativeFrame(i)
+	if err != nil {
+		return nil, err
+	}
+	return NewFrame(frame, p.Info)
+}
+
+// Frames returns all frames with their samples decoded
+func (p *PixelData) Frames() ([]*Frame, error) {
+	frames := make([]*Frame, 0, p.numberOfFrames)
+	for i := 0; i < p.numberOfFrames; i++ {
+		frame, err := p.Frame(i)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+func validateNativeFrameInfo(info FrameInfo) error {
+	if info.Rows <= 0 || info.Columns <= 0 {
+		return fmt.Errorf("invalid frame dimensions %dx%d", info.Rows, info.Columns)
+	}
+	if info.SamplesPerPixel <= 0 {
+		return fmt.Errorf("invalid SamplesPerPixel %d", info.SamplesPerPixel)
+	}
+	switch info.BitsAllocated {
+	case 1, 8, 16, 32:
+	default:
+		return fmt.Errorf("unsupported BitsAllocated %d", info.BitsAllocated)
+	}
+	if info.bitsStored() > info.BitsAllocated || info.highBit() >= info.BitsAllocated || info.highBit() < info.bitsStored()-1 {
+		return fmt.Errorf("invalid BitsStored %d and HighBit %d for BitsAllocated %d", info.bitsStored(), info.highBit(), info.BitsAllocated)
+	}
+	return nil
+}
+
+// nativePixelData is the value of a PixelData element in the native format, made up of buffered or
+// referenced segments such as the frames split by SplitUncompressedPixelDataFrames
+type nativePixelData struct {
+	ds       *DataSet
+	segments []nativeSegment
+	length   int64
+
+	// order is the byte order of words of size bytes
+	order binary.ByteOrder
+	size  int64
+}
+
+// nativeSegment is a segment of pixel data in the native format which is either buffered into data
+// or referenced by ref
+type nativeSegment struct {
+	data   []byte
+	ref    *BulkDataReference
+	offset int64
+	length int64
+}
+
+// newNativePixelData returns the value of the PixelData element in the native format, buffered
+// into a BulkDataBuffer or referenced by []BulkDataReference
+func newNativePixelData(ds *DataSet, element *DataElement) (*nativePixelData, error) {
+	r := &nativePixelData{ds: ds, size: int64(wordSize(element.VR))}
+	switch v := element.ValueField.(type) {
+	case BulkDataBuffer:
+		r.order = v.ByteOrder()
+		for _, fragment := range v.Data() {
+			r.append(nativeSegment{data: fragment, length: int64(len(fragment))})
+		}
+	case []BulkDataReference:
+		r.order = binary.LittleEndian
+		if syntax, err := ds.TransferSyntax(); err == nil {
+			r.order = syntax.ByteOrder
+		}
+		for i := range v {
+			r.append(nativeSegment{ref: &v[i], length: v[i].Reference.Length})
+		}
+	default:
+		return nil, fmt.Errorf("unexpected type %T of pixel data (expected BulkDataBuffer or []BulkDataReference)", element.ValueField)
+	}
+	return r, nil
+}
+
+func (p *nativePixelData) append(segment nativeSegment) {
+	segment.offset = p.length
+	p.segments = append(p.segments, segment)
+	p.length += segment.length
+}
+
+// read returns the bytes of the pixel data in [start, end) with little endian words. Only the
+// segments overlapping the range are read.
+func (p *nativePixelData) read(start, end int64) ([]byte, error) {
+	if start < 0 || end < start || end > p.length {
+		return nil, fmt.Errorf("range [%d, %d) is outside of pixel data of length %d", start, end, p.length)
+	}
+	// words are swapped whole
+	alignedStart, alignedEnd := start-start%p.size, end+(p.size-end%p.size)%p.size
+	if alignedEnd > p.length {
+		alignedEnd = p.length
+	}
+
+	b := make([]byte, 0, alignedEnd-alignedStart)
+	for _, segment := range p.segments {
+		from, to := max64(alignedStart, segment.offset), min64(alignedEnd, segment.offset+segment.length)
+		if from >= to {
+			continue
+		}
+		if segment.ref == nil {
+			b = append(b, segment.data[from-segment.offset:to-segment.offset]...)
+			continue
+		}
+		r, err := p.ds.OpenBulkData(*segment.ref)
+		if err != nil {
+			return nil, err
+		}
+		part := make([]byte, to-from)
+		if n, err := r.ReadAt(part, from-segment.offset); n < len(part) {
+			return nil, err
+		}
+		b = append(b, part...)
+	}
+
+	if p.order == binary.BigEndian {
+		swapBytes(b, int(p.size))
+	}
+	return b[start-alignedStart : end-alignedStart], nil
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
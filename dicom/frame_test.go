@@ -0,0 +1,428 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestNewFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		info  FrameInfo
+		want  []int
+	}{
+		{
+			"8 bit unsigned",
+			[]byte{0x00, 0x7F, 0x80, 0xFF},
+			FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8},
+			[]int{0, 127, 128, 255},
+		},
+		{
+			"8 bit signed",
+			[]byte{0x00, 0x7F, 0x80, 0xFF},
+			FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8, PixelRepresentation: 1},
+			[]int{0, 127, -128, -1},
+		},
+		{
+			"bits above bits stored are masked",
+			[]byte{0xFF, 0xF0, 0x01, 0x08},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12},
+			[]int{0x0FF, 0x801},
+		},
+		{
+			"12 bit signed samples are sign extended",
+			[]byte{0xFF, 0xF0, 0x01, 0x08},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12, PixelRepresentation: 1},
+			[]int{0x0FF, -2047},
+		},
+		{
+			"samples are shifted down to the high bit",
+			[]byte{0x0C, 0xC0, 0x00, 0x3C},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12, HighBit: 13},
+			[]int{0x003, 0xF00},
+		},
+		{
+			"32 bit signed",
+			[]byte{0xFE, 0xFF, 0xFF, 0xFF, 0x01, 0x00, 0x00, 0x00},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 32, PixelRepresentation: 1},
+			[]int{-2, 1},
+		},
+		{
+			"color-by-pixel",
+			[]byte{1, 2, 3, 4, 5, 6},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8},
+			[]int{1, 2, 3, 4, 5, 6},
+		},
+		{
+			"color-by-plane samples are interleaved",
+			[]byte{1, 4, 2, 5, 3, 6},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8, PlanarConfiguration: 1},
+			[]int{1, 2, 3, 4, 5, 6},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewFrame(tc.frame, tc.info)
+			if err != nil {
+				t.Fatalf("NewFrame(%v, %+v) => %v", tc.frame, tc.info, err)
+			}
+			if !reflect.DeepEqual(got.Samples, tc.want) {
+				t.Fatalf("NewFrame(%v, %+v) => %v, want %v", tc.frame, tc.info, got.Samples, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewFrame_invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		info  FrameInfo
+	}{
+		{
+			"short frame",
+			[]byte{1, 2, 3},
+			FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8},
+		},
+		{
+			"unsupported bits allocated",
+			[]byte{1, 2, 3, 4, 5, 6},
+			FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 12},
+		},
+		{
+			"high bit exceeds bits allocated",
+			[]byte{1, 2, 3, 4, 5, 6, 7, 8},
+			FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12, HighBit: 16},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewFrame(tc.frame, tc.info); err == nil {
+				t.Fatalf("NewFrame(%v, %+v) => nil, expected an error", tc.frame, tc.info)
+			}
+		})
+	}
+}
+
+func TestFrame_At(t *testing.T) {
+	info := FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 2, BitsAllocated: 8}
+	frame, err := NewFrame([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, info)
+	if err != nil {
+		t.Fatalf("NewFrame(_, %+v) => %v", info, err)
+	}
+	if got := frame.At(2, 1, 1); got != 11 {
+		t.Errorf("At(2, 1, 1) => %d, want 11", got)
+	}
+	if got := frame.At(1, 0, 0); got != 2 {
+		t.Errorf("At(1, 0, 0) => %d, want 2", got)
+	}
+}
+
+func TestNewPixelData(t *testing.T) {
+	frames := [][]int{
+		{52, 18, 120, 86, 170, 153},
+		{238, 221, 0, 255, 17, 0},
+		{85, 68, 119, 102, 153, 136},
+		{221, 204, 255, 238, 17, 0},
+	}
+	info := FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16}
+	rleFrames, err := EncodeFrames([][]byte{{0x01, 0x02, 0x03, 0x04}, {0x05, 0x06, 0x07, 0x08}}, RLELosslessUID, info)
+	if err != nil {
+		t.Fatalf("EncodeFrames(_, %v, %+v) => %v", RLELosslessUID, info, err)
+	}
+	imagePixel := map[DataElementTag]interface{}{
+		RowsTag:           []uint16{1},
+		ColumnsTag:        []uint16{2},
+		BitsAllocatedTag:  []uint16{16},
+		NumberOfFramesTag: []string{"2"},
+	}
+	withElements := func(elements map[DataElementTag]interface{}) *DataSet {
+		ds := NewDataSet(imagePixel)
+		for tag, v := range elements {
+			ds.Elements[tag] = &DataElement{tag, tag.DictionaryVR(), v, 0}
+		}
+		return ds
+	}
+	encapsulated := withElements(map[DataElementTag]interface{}{
+		TransferSyntaxUIDTag: []string{RLELosslessUID},
+	})
+	encapsulated.Elements[PixelDataTag] = &DataElement{PixelDataTag, OBVR, rleFrames, UndefinedLength}
+
+	tests := []struct {
+		name string
+		ds   *DataSet
+		want [][]int
+	}{
+		{
+			"native frames",
+			parse("MultiFrameUncompressed.dcm", t),
+			frames,
+		},
+		{
+			"native frames split by SplitUncompressedPixelDataFrames",
+			parse("MultiFrameUncompressed.dcm", t, SplitUncompressedPixelDataFrames()),
+			frames,
+		},
+		{
+			"little endian words",
+			withElements(map[DataElementTag]interface{}{
+				PixelDataTag: NewBulkDataBuffer([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}),
+			}),
+			[][]int{{0x0201, 0x0403}, {0x0605, 0x0807}},
+		},
+		{
+			"big endian words",
+			withElements(map[DataElementTag]interface{}{
+				PixelDataTag: NewBulkDataBufferWithByteOrder(binary.BigEndian, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}),
+			}),
+			[][]int{{0x0102, 0x0304}, {0x0506, 0x0708}},
+		},
+		{
+			"encapsulated frames are decoded",
+			encapsulated,
+			[][]int{{0x0201, 0x0403}, {0x0605, 0x0807}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pixelData, err := NewPixelData(tc.ds)
+			if err != nil {
+				t.Fatalf("NewPixelData(_) => %v", err)
+			}
+			got, err := pixelData.Frames()
+			if err != nil {
+				t.Fatalf("Frames() => %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("Frames() => %d frames, want %d", len(got), len(tc.want))
+			}
+			for i, frame := range got {
+				if !reflect.DeepEqual(frame.Samples, tc.want[i]) {
+					t.Errorf("frame %d: got %v, want %v", i, frame.Samples, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewPixelData_references(t *testing.T) {
+	want := [][]byte{
+		[]byte("4\022xV\252\231"),
+		[]byte("\356\335\000\377\021\000"),
+		[]byte("UDwf\231\210"),
+		[]byte("\335\314\377\356\021\000"),
+	}
+	referenceOpt := ReferenceBulkData(DefaultBulkDataDefinition)
+
+	tests := []struct {
+		name string
+		opts []ParseOption
+	}{
+		{"unsplit pixel data", []ParseOption{referenceOpt}},
+		{"split pixel data", []ParseOption{SplitUncompressedPixelDataFrames(), referenceOpt}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := mustOpenOSFile("MultiFrameUncompressed.dcm", t)
+			defer f.Close()
+
+			info, err := f.Stat()
+			if err != nil {
+				t.Fatalf("f.Stat() => %v", err)
+			}
+			ds, err := ParseReaderAt(f, info.Size(), tc.opts...)
+			if err != nil {
+				t.Fatalf("ParseReaderAt(_, %v) => %v", info.Size(), err)
+			}
+			pixelData, err := NewPixelData(ds)
+			if err != nil {
+				t.Fatalf("NewPixelData(_) => %v", err)
+			}
+			if pixelData.NumberOfFrames() != len(want) {
+				t.Fatalf("NumberOfFrames() => %d, want %d", pixelData.NumberOfFrames(), len(want))
+			}
+			// read frames out of order
+			for _, i := range []int{3, 1, 0, 2} {
+				got, err := pixelData.NativeFrame(i)
+				if err != nil {
+					t.Fatalf("NativeFrame(%d) => %v", i, err)
+				}
+				if !reflect.DeepEqual(got, want[i]) {
+					t.Errorf("NativeFrame(%d) => %v, want %v", i, got, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewPixelData_bitsAllocatedOne(t *testing.T) {
+	// frames of 6 bits packed contiguously, so frames start mid-byte
+	want := [][]int{
+		{0, 0, 1, 0, 1, 1},
+		{0, 0, 0, 1, 0, 0},
+		{1, 0, 0, 0, 0, 0},
+		{0, 1, 1, 1, 1, 0},
+	}
+	referenceOpt := ReferenceBulkData(DefaultBulkDataDefinition)
+
+	tests := []struct {
+		name string
+		opts []ParseOption
+	}{
+		{"unsplit pixel data", nil},
+		{"split pixel data", []ParseOption{SplitUncompressedPixelDataFrames()}},
+		{"unsplit pixel data references", []ParseOption{referenceOpt}},
+		{"split pixel data references", []ParseOption{SplitUncompressedPixelDataFrames(), referenceOpt}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := mustOpenOSFile("MultiFrameUncompressed_BitsAllocatedOne.dcm", t)
+			defer f.Close()
+
+			info, err := f.Stat()
+			if err != nil {
+				t.Fatalf("f.Stat() => %v", err)
+			}
+			ds, err := ParseReaderAt(f, info.Size(), tc.opts...)
+			if err != nil {
+				t.Fatalf("ParseReaderAt(_, %v) => %v", info.Size(), err)
+			}
+			pixelData, err := NewPixelData(ds)
+			if err != nil {
+				t.Fatalf("NewPixelData(_) => %v", err)
+			}
+			got, err := pixelData.Frames()
+			if err != nil {
+				t.Fatalf("Frames() => %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("Frames() => %d frames, want %d", len(got), len(want))
+			}
+			for i, frame := range got {
+				if !reflect.DeepEqual(frame.Samples, want[i]) {
+					t.Errorf("frame %d: got %v, want %v", i, frame.Samples, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewPixelData_invalid(t *testing.T) {
+	imagePixel := map[DataElementTag]interface{}{
+		RowsTag:          []uint16{2},
+		ColumnsTag:       []uint16{2},
+		BitsAllocatedTag: []uint16{8},
+	}
+	withPixelData := func(v interface{}, elements map[DataElementTag]interface{}) *DataSet {
+		ds := NewDataSet(imagePixel)
+		for tag, v := range elements {
+			ds.Elements[tag] = &DataElement{tag, tag.DictionaryVR(), v, 0}
+		}
+		if v != nil {
+			ds.Elements[PixelDataTag] = &DataElement{PixelDataTag, OBVR, v, 0}
+		}
+		return ds
+	}
+
+	tests := []struct {
+		name string
+		ds   *DataSet
+	}{
+		{
+			"missing pixel data",
+			withPixelData(nil, nil),
+		},
+		{
+			"missing image pixel module",
+			NewDataSet(map[DataElementTag]interface{}{PixelDataTag: NewBulkDataBuffer([]byte{1, 2, 3, 4})}),
+		},
+		{
+			"pixel data too short for the number of frames",
+			withPixelData(NewBulkDataBuffer([]byte{1, 2, 3, 4, 5, 6, 7}), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"2"},
+			}),
+		},
+		{
+			"number of frames overflowing the length of the frames",
+			withPixelData(NewBulkDataBuffer([]byte{1, 2, 3, 4}), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"4611686018427387904"},
+			}),
+		},
+		{
+			"number of frames overflowing the bits of the frames",
+			withPixelData(NewBulkDataBuffer([]byte{1, 2, 3, 4}), map[DataElementTag]interface{}{
+				BitsAllocatedTag:  []uint16{1},
+				NumberOfFramesTag: []string{"4611686018427387904"},
+			}),
+		},
+		{
+			"pixel data not buffered",
+			withPixelData(oneShotIteratorFromBytes([]byte{1, 2, 3, 4}), nil),
+		},
+		{
+			"unsupported bits allocated",
+			withPixelData(NewBulkDataBuffer([]byte{1, 2, 3, 4}), map[DataElementTag]interface{}{
+				BitsAllocatedTag: []uint16{12},
+			}),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewPixelData(tc.ds); err == nil {
+				t.Fatalf("NewPixelData(_) => nil, expected an error")
+			}
+		})
+	}
+}
+
+func TestNativePixelData_read_outOfRange(t *testing.T) {
+	r, err := newNativePixelData(nil, &DataElement{PixelDataTag, OWVR, NewBulkDataBuffer([]byte{1, 2, 3, 4}), 4})
+	if err != nil {
+		t.Fatalf("newNativePixelData(_, _) => %v", err)
+	}
+	for _, bounds := range [][2]int64{{-2, 2}, {2, 6}, {3, 1}} {
+		if _, err := r.read(bounds[0], bounds[1]); err == nil {
+			t.Errorf("read(%d, %d) => nil, expected an error", bounds[0], bounds[1])
+		}
+	}
+}
+
+func TestPixelData_Frame_outOfRange(t *testing.T) {
+	ds := NewDataSet(map[DataElementTag]interface{}{
+		RowsTag:          []uint16{1},
+		ColumnsTag:       []uint16{2},
+		BitsAllocatedTag: []uint16{8},
+		PixelDataTag:     NewBulkDataBuffer([]byte{1, 2}),
+	})
+	pixelData, err := NewPixelData(ds)
+	if err != nil {
+		t.Fatalf("NewPixelData(_) => %v", err)
+	}
+	for _, i := range []int{-1, 1} {
+		if _, err := pixelData.Frame(i); err == nil {
+			t.Errorf("Frame(%d) => nil, expected an error", i)
+		}
+	}
+}
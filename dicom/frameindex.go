@@ -0,0 +1,358 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The Extended Offset Table elements were added to the standard after the data dictionary that
+// tags.go is generated from.
+const (
+	// ExtendedOffsetTableTag is the data element tag of ExtendedOffsetTable
+	ExtendedOffsetTableTag = DataElementTag(0x7FE00001)
+
+	// ExtendedOffsetTableLengthsTag is the data element tag of ExtendedOffsetTableLengths
+	ExtendedOffsetTableLengthsTag = DataElementTag(0x7FE00002)
+)
+
+// FrameIndex locates the frames of pixel data in the encapsulated format within its fragments, so
+// that each frame can be read on its own. See the DICOM standard part5 linked below.
+// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_A.4
+type FrameIndex struct {
+	ds        *DataSet
+	fragments []indexedFragment
+
+	// starts holds the index of the first fragment of each frame
+	starts []int
+
+	// lengths holds the byte length of each frame from the Extended Offset Table Lengths, or is nil
+	// if the frames span their fragments
+	lengths []int64
+}
+
+// indexedFragment is a fragment of the encapsulated format which is either buffered into data or
+// referenced by ref
+type indexedFragment struct {
+	data []byte
+	ref  *BulkDataReference
+
+	// offset is the offset of the item holding the fragment relative to the item holding the first
+	// fragment following the Basic Offset Table
+	offset int64
+	length int64
+}
+
+// NewFrameIndex returns the FrameIndex of the PixelData of the DataSet. The first fragment of each
+// frame is located with the Extended Offset Table (7FE0,0001) if present, and with the Basic
+// Offset Table otherwise. When present with the Extended Offset Table, the Extended Offset Table
+// Lengths (7FE0,0002) bound each frame within its fragments. When both offset tables are empty,
+// frames are assumed to be held in one fragment each unless the DataSet holds a single frame.
+// Failing that, fragments starting with a JPEG or JPEG 2000 marker are taken to start a frame.
+//
+// The PixelData must either be buffered into a BulkDataBuffer or be referenced with
+// ReferenceBulkData in a DataSet created by ParseReaderAt or OpenFile, in which case each frame is
+// read from the file on its own by Frame. The Basic Offset Table must not be dropped with
+// DropBasicOffsetTable.
+func NewFrameIndex(ds *DataSet) (*FrameIndex, error) {
+	pixelData, ok := ds.Elements[PixelDataTag]
+	if !ok {
+		return nil, fmt.Errorf("missing PixelData element")
+	}
+	if pixelData.ValueLength != UndefinedLength {
+		return nil, fmt.Errorf("pixel data is not in the encapsulated format")
+	}
+
+	numberOfFrames := -1
+	if element, ok := ds.Elements[NumberOfFramesTag]; ok {
+		n, err := element.IntValue()
+		if err != nil {
+			return nil, fmt.Errorf("reading NumberOfFrames: %v", err)
+		}
+		numberOfFrames = int(n)
+	}
+
+	return newFrameIndex(ds, pixelData.ValueField, numberOfFrames)
+}
+
+// newFrameIndex returns the FrameIndex of the given value of pixel data in the encapsulated format.
+// ds is the DataSet holding the pixel data, which resolves references and may hold an Extended
+// Offset Table. numberOfFrames is -1 if unknown.
+func newFrameIndex(ds *DataSet, value DataElementValue, numberOfFrames int) (*FrameIndex, error) {
+	idx := &FrameIndex{ds: ds}
+	var offsetTable []byte
+	switch v := value.(type) {
+	case BulkDataBuffer:
+		data := v.Data()
+		if len(data) == 0 {
+			return nil, fmt.Errorf("missing basic offset table")
+		}
+		offsetTable = data[0]
+		for _, fragment := range data[1:] {
+			// fragments are written with even length
+			length := int64(len(fragment) + len(fragment)%2)
+			idx.append(indexedFragment{data: fragment, length: length})
+		}
+	case []BulkDataReference:
+		if len(v) == 0 {
+			return nil, fmt.Errorf("missing basic offset table")
+		}
+		var err error
+		if offsetTable, err = readBulkDataReference(ds, v[0]); err != nil {
+			return nil, fmt.Errorf("reading basic offset table: %v", err)
+		}
+		for i := range v[1:] {
+			idx.append(indexedFragment{ref: &v[1+i], length: v[1+i].Reference.Length})
+		}
+	default:
+		return nil, fmt.Errorf("unexpected type %T of pixel data "+
+			"(expected BulkDataBuffer or []BulkDataReference)", value)
+	}
+
+	offsets, err := extendedOffsetTable(ds, ExtendedOffsetTableTag)
+	if err != nil {
+		return nil, err
+	}
+	if offsets != nil {
+		if idx.lengths, err = extendedOffsetTable(ds, ExtendedOffsetTableLengthsTag); err != nil {
+			return nil, err
+		}
+		if idx.lengths != nil && len(idx.lengths) != len(offsets) {
+			return nil, fmt.Errorf("found %d extended offset table lengths, expected %d",
+				len(idx.lengths), len(offsets))
+		}
+	} else {
+		if len(offsetTable)%4 != 0 {
+			return nil, fmt.Errorf("basic offset table of length %d is not a multiple of 4",
+				len(offsetTable))
+		}
+		for i := 0; i < len(offsetTable); i += 4 {
+			offsets = append(offsets, int64(binary.LittleEndian.Uint32(offsetTable[i:])))
+		}
+	}
+
+	if len(offsets) > 0 {
+		err = idx.startAtOffsets(offsets)
+	} else {
+		err = idx.scanFragments(numberOfFrames)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if numberOfFrames >= 0 && numberOfFrames != len(idx.starts) {
+		return nil, fmt.Errorf("found %d frames, expected NumberOfFrames %d",
+			len(idx.starts), numberOfFrames)
+	}
+	for i, length := range idx.lengths {
+		first, end, _ := idx.Fragments(i)
+		var available int64
+		for _, fragment := range idx.fragments[first:end] {
+			available += fragment.length
+		}
+		if length > available {
+			return nil, fmt.Errorf("length %d of frame %d exceeds its fragments of length %d",
+				length, i, available)
+		}
+	}
+	return idx, nil
+}
+
+// NumberOfFrames returns the number of frames in the index
+func (idx *FrameIndex) NumberOfFrames() int {
+	return len(idx.starts)
+}
+
+// Fragments returns the indices of the fragments holding frame i, counting from the first
+// fragment following the Basic Offset Table
+func (idx *FrameIndex) Fragments(i int) (first, end int, err error) {
+	if i < 0 || i >= len(idx.starts) {
+		return 0, 0, fmt.Errorf("frame %d out of range [0, %d)", i, len(idx.starts))
+	}
+	end = len(idx.fragments)
+	if i+1 < len(idx.starts) {
+		end = idx.starts[i+1]
+	}
+	return idx.starts[i], end, nil
+}
+
+// Frame returns the compressed bytes of frame i, the concatenation of the fragments holding it
+// truncated to the length of the frame in the Extended Offset Table Lengths if present. Referenced
+// fragments of other frames are not read.
+func (idx *FrameIndex) Frame(i int) ([]byte, error) {
+	first, end, err := idx.Fragments(i)
+	if err != nil {
+		return nil, err
+	}
+
+	buff := &bytes.Buffer{}
+	for _, fragment := range idx.fragments[first:end] {
+		data := fragment.data
+		if fragment.ref != nil {
+			if data, err = readBulkDataReference(idx.ds, *fragment.ref); err != nil {
+				return nil, fmt.Errorf("reading fragment of frame %d: %v", i, err)
+			}
+		}
+		buff.Write(data)
+	}
+	if idx.lengths != nil && idx.lengths[i] < int64(buff.Len()) {
+		return buff.Bytes()[:idx.lengths[i]], nil
+	}
+	return buff.Bytes(), nil
+}
+
+func (idx *FrameIndex) append(fragment indexedFragment) {
+	if n := len(idx.fragments); n > 0 {
+		last := idx.fragments[n-1]
+		// each fragment is preceded by its 4 byte item tag and 4 byte length
+		fragment.offset = last.offset + 8 + last.length
+	}
+	idx.fragments = append(idx.fragments, fragment)
+}
+
+// startAtOffsets locates the first fragment of each frame from the item offsets of an offset table
+func (idx *FrameIndex) startAtOffsets(offsets []int64) error {
+	next := 0
+	for i, offset := range offsets {
+		for next < len(idx.fragments) && idx.fragments[next].offset < offset {
+			next++
+		}
+		if next == len(idx.fragments) || idx.fragments[next].offset != offset {
+			return fmt.Errorf("offset %d of frame %d does not locate a fragment", offset, i)
+		}
+		idx.starts = append(idx.starts, next)
+		next++
+	}
+	return nil
+}
+
+// scanFragments locates the first fragment of each frame in the absence of an offset table
+func (idx *FrameIndex) scanFragments(numberOfFrames int) error {
+	switch {
+	case len(idx.fragments) == 0:
+		return nil
+	case numberOfFrames <= 1:
+		idx.starts = []int{0}
+		return nil
+	case numberOfFrames == len(idx.fragments):
+		for i := range idx.fragments {
+			idx.starts = append(idx.starts, i)
+		}
+		return nil
+	}
+
+	for i, fragment := range idx.fragments {
+		data := fragment.data
+		if fragment.ref != nil {
+			r, err := idx.ds.OpenBulkData(*fragment.ref)
+			if err != nil {
+				return fmt.Errorf("opening fragment %d: %v", i, err)
+			}
+			data = make([]byte, 2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				data = nil
+			}
+		}
+		if isFrameStart(data) {
+			idx.starts = append(idx.starts, i)
+		}
+	}
+	if len(idx.starts) == 0 || idx.starts[0] != 0 {
+		return fmt.Errorf("first fragment does not start a frame")
+	}
+	return nil
+}
+
+// isFrameStart returns true if the fragment starts with the start of image marker of JPEG, JPEG-LS
+// and JPEG XL or the start of codestream marker of JPEG 2000
+func isFrameStart(fragment []byte) bool {
+	return len(fragment) >= 2 && fragment[0] == 0xFF &&
+		(fragment[1] == jpegSOI || fragment[1] == 0x4F)
+}
+
+// extendedOffsetTable returns the 64 bit values of the Extended Offset Table or Extended Offset
+// Table Lengths element with the given tag, or nil if the DataSet has none
+func extendedOffsetTable(ds *DataSet, tag DataElementTag) ([]int64, error) {
+	element, ok := ds.Elements[tag]
+	if !ok {
+		return nil, nil
+	}
+	name := "extended offset table"
+	if tag == ExtendedOffsetTableLengthsTag {
+		name = "extended offset table lengths"
+	}
+
+	var values []uint64
+	switch v := element.ValueField.(type) {
+	case []uint64:
+		// OV values are buffered as []uint64 by Parse
+		values = v
+	case BulkDataBuffer:
+		var err error
+		if values, err = uint64Values(bytes.Join(v.Data(), nil), v.ByteOrder(), name); err != nil {
+			return nil, err
+		}
+	case []BulkDataReference:
+		var data []byte
+		for _, ref := range v {
+			b, err := readBulkDataReference(ds, ref)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %v", name, err)
+			}
+			data = append(data, b...)
+		}
+		var err error
+		if values, err = uint64Values(data, binary.LittleEndian, name); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected type %T of %s", element.ValueField, name)
+	}
+
+	var offsets []int64
+	for _, value := range values {
+		if value > 1<<62 {
+			return nil, fmt.Errorf("invalid value %d in %s", value, name)
+		}
+		offsets = append(offsets, int64(value))
+	}
+	return offsets, nil
+}
+
+// uint64Values returns the 64 bit values of the given bytes of an element named name
+func uint64Values(data []byte, order binary.ByteOrder, name string) ([]uint64, error) {
+	if len(data)%8 != 0 {
+		return nil, fmt.Errorf("%s of length %d is not a multiple of 8", name, len(data))
+	}
+	values := make([]uint64, len(data)/8)
+	for i := range values {
+		values[i] = order.Uint64(data[8*i:])
+	}
+	return values, nil
+}
+
+func readBulkDataReference(ds *DataSet, ref BulkDataReference) ([]byte, error) {
+	r, err := ds.OpenBulkData(ref)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, ref.Reference.Length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
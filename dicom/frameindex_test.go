@@ -0,0 +1,380 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func offsetTable32(offsets ...uint32) []byte {
+	b := make([]byte, 4*len(offsets))
+	for i, offset := range offsets {
+		binary.LittleEndian.PutUint32(b[4*i:], offset)
+	}
+	return b
+}
+
+func offsetTable64(order binary.ByteOrder, offsets ...uint64) []byte {
+	b := make([]byte, 8*len(offsets))
+	for i, offset := range offsets {
+		order.PutUint64(b[8*i:], offset)
+	}
+	return b
+}
+
+func encapsulatedDataSet(pixelData DataElementValue, elems map[DataElementTag]interface{}) *DataSet {
+	ds := NewDataSet(elems)
+	ds.Elements[PixelDataTag] = &DataElement{PixelDataTag, OBVR, pixelData, UndefinedLength}
+	return ds
+}
+
+func TestNewFrameIndex(t *testing.T) {
+	fragments := [][]byte{
+		{0xFF, 0xD8, 0x01, 0x02},
+		{0x03},
+		{0xFF, 0xD8, 0x04, 0x05},
+		{0xFF, 0x4F, 0x06, 0x07, 0x08, 0x09},
+		{0x0A, 0x0B},
+	}
+	// item offsets of the fragments, with the odd length fragment padded
+	// 0, 12, 22, 34, 48
+
+	tests := []struct {
+		name string
+		ds   *DataSet
+		want [][]byte
+	}{
+		{
+			"basic offset table locates frames spanning several fragments",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer(offsetTable32(0, 22, 34), fragments...), nil),
+			[][]byte{
+				{0xFF, 0xD8, 0x01, 0x02, 0x03},
+				{0xFF, 0xD8, 0x04, 0x05},
+				{0xFF, 0x4F, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B},
+			},
+		},
+		{
+			"extended offset table takes precedence over the basic offset table",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer(offsetTable32(0, 22, 34), fragments...), map[DataElementTag]interface{}{
+				ExtendedOffsetTableTag: NewBulkDataBuffer(offsetTable64(binary.LittleEndian, 0, 34)),
+			}),
+			[][]byte{
+				{0xFF, 0xD8, 0x01, 0x02, 0x03, 0xFF, 0xD8, 0x04, 0x05},
+				{0xFF, 0x4F, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B},
+			},
+		},
+		{
+			"extended offset table is read in the byte order of the buffer",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				ExtendedOffsetTableTag: NewBulkDataBufferWithByteOrder(binary.BigEndian, offsetTable64(binary.BigEndian, 0, 12, 22, 34, 48)),
+			}),
+			[][]byte{
+				{0xFF, 0xD8, 0x01, 0x02},
+				{0x03},
+				{0xFF, 0xD8, 0x04, 0x05},
+				{0xFF, 0x4F, 0x06, 0x07, 0x08, 0x09},
+				{0x0A, 0x0B},
+			},
+		},
+		{
+			"extended offset table lengths bound frames within their fragments",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				ExtendedOffsetTableTag:        []uint64{0, 22},
+				ExtendedOffsetTableLengthsTag: []uint64{5, 3},
+			}),
+			[][]byte{
+				{0xFF, 0xD8, 0x01, 0x02, 0x03},
+				{0xFF, 0xD8, 0x04},
+			},
+		},
+		{
+			"without offset table, a single frame spans all fragments",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"1"},
+			}),
+			[][]byte{bytes.Join(fragments, nil)},
+		},
+		{
+			"without offset table, a single frame is assumed if NumberOfFrames is missing",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), nil),
+			[][]byte{bytes.Join(fragments, nil)},
+		},
+		{
+			"without offset table, each fragment holds a frame if their numbers match",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"5"},
+			}),
+			fragments,
+		},
+		{
+			"without offset table, frames are located by scanning fragments for start markers",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"3"},
+			}),
+			[][]byte{
+				{0xFF, 0xD8, 0x01, 0x02, 0x03},
+				{0xFF, 0xD8, 0x04, 0x05},
+				{0xFF, 0x4F, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B},
+			},
+		},
+		{
+			"pixel data without fragments holds no frames",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}), nil),
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			idx, err := NewFrameIndex(tc.ds)
+			if err != nil {
+				t.Fatalf("NewFrameIndex(_) => %v", err)
+			}
+			if idx.NumberOfFrames() != len(tc.want) {
+				t.Fatalf("NumberOfFrames() => %d, want %d", idx.NumberOfFrames(), len(tc.want))
+			}
+			for i, want := range tc.want {
+				got, err := idx.Frame(i)
+				if err != nil {
+					t.Fatalf("Frame(%d) => %v", i, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("Frame(%d) => %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewFrameIndex_constructedExtendedOffsetTable(t *testing.T) {
+	frames := [][]byte{{0xFF, 0xD8, 0x01}, {0xFF, 0xD8, 0x02, 0x03}}
+	ds := NewDataSet(map[DataElementTag]interface{}{
+		NumberOfFramesTag: []string{"2"},
+	}).Merge(minimalDataSet)
+	ds.Elements[PixelDataTag] = &DataElement{PixelDataTag, OBVR, NewEncapsulatedFormatBuffer(nil, frames...), UndefinedLength}
+	// the frames are padded to even length in items following the empty Basic Offset Table
+	ds.Elements[ExtendedOffsetTableTag] = &DataElement{ExtendedOffsetTableTag, OVVR, []uint64{0, 12}, 16}
+	ds.Elements[ExtendedOffsetTableLengthsTag] = &DataElement{ExtendedOffsetTableLengthsTag, OVVR, []uint64{3, 4}, 16}
+
+	buf := &bytes.Buffer{}
+	if err := Construct(buf, ds); err != nil {
+		t.Fatalf("Construct(_, _) => %v", err)
+	}
+	parsed, err := Parse(buf)
+	if err != nil {
+		t.Fatalf("Parse(_) => %v", err)
+	}
+
+	idx, err := NewFrameIndex(parsed)
+	if err != nil {
+		t.Fatalf("NewFrameIndex(_) => %v", err)
+	}
+	if idx.NumberOfFrames() != len(frames) {
+		t.Fatalf("NumberOfFrames() => %d, want %d", idx.NumberOfFrames(), len(frames))
+	}
+	for i, want := range frames {
+		got, err := idx.Frame(i)
+		if err != nil {
+			t.Fatalf("Frame(%d) => %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Frame(%d) => %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestFrameIndex_Fragments(t *testing.T) {
+	ds := encapsulatedDataSet(NewEncapsulatedFormatBuffer(offsetTable32(0, 20), []byte{1, 2}, []byte{3, 4}, []byte{5, 6}), nil)
+	idx, err := NewFrameIndex(ds)
+	if err != nil {
+		t.Fatalf("NewFrameIndex(_) => %v", err)
+	}
+
+	want := [][2]int{{0, 2}, {2, 3}}
+	for i, w := range want {
+		first, end, err := idx.Fragments(i)
+		if err != nil {
+			t.Fatalf("Fragments(%d) => %v", i, err)
+		}
+		if got := [2]int{first, end}; got != w {
+			t.Errorf("Fragments(%d) => %v, want %v", i, got, w)
+		}
+	}
+	for _, i := range []int{-1, 2} {
+		if _, _, err := idx.Fragments(i); err == nil {
+			t.Errorf("Fragments(%d) => nil, expected an error", i)
+		}
+		if _, err := idx.Frame(i); err == nil {
+			t.Errorf("Frame(%d) => nil, expected an error", i)
+		}
+	}
+}
+
+func TestNewFrameIndex_invalid(t *testing.T) {
+	fragments := [][]byte{{0xFF, 0xD8}, {0x01, 0x02}, {0xFF, 0xD8}}
+
+	tests := []struct {
+		name string
+		ds   *DataSet
+	}{
+		{
+			"missing pixel data",
+			NewDataSet(nil),
+		},
+		{
+			"native pixel data",
+			NewDataSet(map[DataElementTag]interface{}{PixelDataTag: NewBulkDataBuffer([]byte{1, 2})}),
+		},
+		{
+			"missing basic offset table",
+			encapsulatedDataSet(NewBulkDataBuffer(), nil),
+		},
+		{
+			"offset between fragments",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer(offsetTable32(0, 4), fragments...), nil),
+		},
+		{
+			"offset past the last fragment",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer(offsetTable32(0, 30), fragments...), nil),
+		},
+		{
+			"decreasing offsets",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer(offsetTable32(10, 0), fragments...), nil),
+		},
+		{
+			"basic offset table of odd length",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{0, 0, 0, 0, 0, 0}, fragments...), nil),
+		},
+		{
+			"extended offset table of odd length",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				ExtendedOffsetTableTag: NewBulkDataBuffer([]byte{0, 0, 0, 0}),
+			}),
+		},
+		{
+			"extended offset table lengths without a length for each frame",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				ExtendedOffsetTableTag:        []uint64{0, 20},
+				ExtendedOffsetTableLengthsTag: []uint64{2},
+			}),
+		},
+		{
+			"extended offset table length exceeding the fragments of the frame",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				ExtendedOffsetTableTag:        []uint64{0, 20},
+				ExtendedOffsetTableLengthsTag: []uint64{2, 3},
+			}),
+		},
+		{
+			"NumberOfFrames does not match the basic offset table",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer(offsetTable32(0, 20), fragments...), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"3"},
+			}),
+		},
+		{
+			"NumberOfFrames does not match the start markers",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments...), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"4"},
+			}),
+		},
+		{
+			"first fragment does not start a frame",
+			encapsulatedDataSet(NewEncapsulatedFormatBuffer([]byte{}, fragments[1:]...), map[DataElementTag]interface{}{
+				NumberOfFramesTag: []string{"3"},
+			}),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewFrameIndex(tc.ds); err == nil {
+				t.Fatalf("NewFrameIndex(_) => nil, expected an error")
+			}
+		})
+	}
+}
+
+// countingReaderAt records the byte ranges read from r
+type countingReaderAt struct {
+	r     io.ReaderAt
+	reads [][2]int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.reads = append(c.reads, [2]int64{off, off + int64(n)})
+	return n, err
+}
+
+func TestFrameIndex_Frame_references(t *testing.T) {
+	b, err := ioutil.ReadFile("../testdata/MultiFrameCompressed.dcm")
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+	r := &countingReaderAt{r: bytes.NewReader(b)}
+	ds, err := ParseReaderAt(r, int64(len(b)), ReferenceBulkData(DefaultBulkDataDefinition))
+	if err != nil {
+		t.Fatalf("ParseReaderAt(_, %v) => %v", len(b), err)
+	}
+
+	idx, err := NewFrameIndex(ds)
+	if err != nil {
+		t.Fatalf("NewFrameIndex(_) => %v", err)
+	}
+	if idx.NumberOfFrames() != 4 {
+		t.Fatalf("NumberOfFrames() => %d, want 4", idx.NumberOfFrames())
+	}
+
+	r.reads = nil
+	got, err := idx.Frame(2)
+	if err != nil {
+		t.Fatalf("Frame(2) => %v", err)
+	}
+	if want := []byte("UDwf\231\210"); !bytes.Equal(got, want) {
+		t.Errorf("Frame(2) => %v, want %v", got, want)
+	}
+	// only the fragment of frame 2 is read
+	if want := [][2]int64{{458, 464}}; !reflect.DeepEqual(r.reads, want) {
+		t.Errorf("read byte ranges %v, want %v", r.reads, want)
+	}
+}
+
+func TestDecodeFrames_basicOffsetTable(t *testing.T) {
+	info := FrameInfo{Rows: 1, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 8}
+	frames := [][]byte{{1, 1, 1, 1}, {2, 3, 4, 5}}
+	var fragments [][]byte
+	for _, frame := range frames {
+		fragment, err := EncodeRLEFrame(frame, info)
+		if err != nil {
+			t.Fatalf("EncodeRLEFrame(%v, _) => %v", frame, err)
+		}
+		// split each frame into two fragments
+		fragments = append(fragments, fragment[:32], fragment[32:])
+	}
+	offsetTable := offsetTable32(0, uint32(16+len(fragments[0])+len(fragments[1])))
+
+	got, err := DecodeFrames(NewEncapsulatedFormatBuffer(offsetTable, fragments...), RLELosslessUID, info, len(frames))
+	if err != nil {
+		t.Fatalf("DecodeFrames(_, %v, %v, %v) => %v", RLELosslessUID, info, len(frames), err)
+	}
+	if !reflect.DeepEqual(got, frames) {
+		t.Errorf("DecodeFrames(_, %v, %v, %v) => %v, want %v", RLELosslessUID, info, len(frames), got, frames)
+	}
+}
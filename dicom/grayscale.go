@@ -0,0 +1,369 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+)
+
+// VOILUTFunction is the function of the windows of a DataSet, see the DICOM standard part3 linked
+// below.
+// http://dicom.nema.org/medical/dicom/current/output/html/part03.html#sect_C.11.2.1.2
+type VOILUTFunction string
+
+const (
+	// VOILUTFunctionLinear is the default window function
+	VOILUTFunctionLinear VOILUTFunction = "LINEAR"
+	// VOILUTFunctionLinearExact is a linear window function mapping exactly Center - Width/2 to the
+	// minimum and Center + Width/2 to the maximum output value
+	VOILUTFunctionLinearExact VOILUTFunction = "LINEAR_EXACT"
+	// VOILUTFunctionSigmoid is a sigmoid window function
+	VOILUTFunctionSigmoid VOILUTFunction = "SIGMOID"
+)
+
+// Window is a window of modality values given by the WindowCenter and WindowWidth elements
+type Window struct {
+	Center   float64
+	Width    float64
+	Function VOILUTFunction
+}
+
+// apply maps the modality value x to the range [0, 1]
+func (w Window) apply(x float64) float64 {
+	switch w.Function {
+	case VOILUTFunctionLinearExact:
+		switch {
+		case x <= w.Center-w.Width/2:
+			return 0
+		case x > w.Center+w.Width/2:
+			return 1
+		}
+		return (x-w.Center)/w.Width + 0.5
+	case VOILUTFunctionSigmoid:
+		return 1 / (1 + math.Exp(-4*(x-w.Center)/w.Width))
+	default:
+		switch {
+		case x <= w.Center-0.5-(w.Width-1)/2:
+			return 0
+		case x > w.Center-0.5+(w.Width-1)/2:
+			return 1
+		}
+		return (x-(w.Center-0.5))/(w.Width-1) + 0.5
+	}
+}
+
+// VOI is a values of interest transform, either a Window or a VOI LUT
+type VOI struct {
+	// Window is used unless LUT is set
+	Window Window
+
+	// LUT is a LUT of the VOI LUT Sequence
+	LUT *LUT
+
+	// Explanation is the WindowCenterWidthExplanation of the window or the LUTExplanation of the
+	// LUT
+	Explanation string
+}
+
+// GrayscaleTransform is the grayscale pipeline of a DataSet transforming stored values of
+// MONOCHROME1 and MONOCHROME2 frames into display values, with the Modality LUT, VOI LUT and
+// Presentation LUT transforms. See the DICOM standard part4 linked below.
+// http://dicom.nema.org/medical/dicom/current/output/html/part04.html#sect_N.2.1.1
+type GrayscaleTransform struct {
+	// RescaleSlope and RescaleIntercept map stored values to modality values
+	RescaleSlope     float64
+	RescaleIntercept float64
+
+	// ModalityLUT is the LUT of the Modality LUT Sequence, used instead of the rescale if set
+	ModalityLUT *LUT
+
+	// VOIs holds the windows of the DataSet followed by the LUTs of its VOI LUT Sequence
+	VOIs []VOI
+
+	// Inverse is true if the display values are inverted, either by a Presentation LUT Shape of
+	// INVERSE or for MONOCHROME1 frames without Presentation LUT Shape
+	Inverse bool
+}
+
+// NewGrayscaleTransform returns the GrayscaleTransform of the DataSet, with RescaleSlope and
+// RescaleIntercept defaulting to 1 and 0.
+func NewGrayscaleTransform(ds *DataSet) (*GrayscaleTransform, error) {
+	info, err := NewFrameInfo(ds)
+	if err != nil {
+		return nil, err
+	}
+	pi, err := photometricInterpretation(ds, info)
+	if err != nil {
+		return nil, err
+	}
+	if pi != PhotometricMonochrome1 && pi != PhotometricMonochrome2 {
+		return nil, fmt.Errorf("unexpected PhotometricInterpretation %q of grayscale pixel data", pi)
+	}
+
+	t := &GrayscaleTransform{RescaleSlope: 1, Inverse: pi == PhotometricMonochrome1}
+	if err := t.readModality(ds, info); err != nil {
+		return nil, err
+	}
+	if err := t.readWindows(ds); err != nil {
+		return nil, err
+	}
+	// VOI LUTs apply to modality values, which may be negative
+	signed := info.PixelRepresentation == 1 || t.RescaleIntercept < 0
+	luts, explanations, err := sequenceLUTs(ds, VOILUTSequenceTag, signed)
+	if err != nil {
+		return nil, err
+	}
+	for i, lut := range luts {
+		t.VOIs = append(t.VOIs, VOI{LUT: lut, Explanation: explanations[i]})
+	}
+
+	if element, ok := ds.Elements[PresentationLUTShapeTag]; ok {
+		shape, err := element.StringValue()
+		if err != nil {
+			return nil, fmt.Errorf("reading PresentationLUTShape: %v", err)
+		}
+		switch strings.TrimSpace(shape) {
+		case "IDENTITY":
+			t.Inverse = false
+		case "INVERSE":
+			t.Inverse = true
+		default:
+			return nil, fmt.Errorf("unsupported PresentationLUTShape %q", shape)
+		}
+	}
+	return t, nil
+}
+
+func (t *GrayscaleTransform) readModality(ds *DataSet, info FrameInfo) error {
+	luts, _, err := sequenceLUTs(ds, ModalityLUTSequenceTag, info.PixelRepresentation == 1)
+	if err != nil {
+		return err
+	}
+	if len(luts) > 0 {
+		t.ModalityLUT = luts[0]
+		return nil
+	}
+
+	for tag, v := range map[DataElementTag]*float64{
+		RescaleSlopeTag:     &t.RescaleSlope,
+		RescaleInterceptTag: &t.RescaleIntercept,
+	} {
+		element, ok := ds.Elements[tag]
+		if !ok {
+			continue
+		}
+		values, err := element.FloatValues()
+		if err != nil {
+			return fmt.Errorf("reading %v: %v", tag, err)
+		}
+		if len(values) > 0 {
+			*v = values[0]
+		}
+	}
+	return nil
+}
+
+func (t *GrayscaleTransform) readWindows(ds *DataSet) error {
+	centerElement, hasCenter := ds.Elements[WindowCenterTag]
+	widthElement, hasWidth := ds.Elements[WindowWidthTag]
+	if !hasCenter || !hasWidth {
+		return nil
+	}
+	centers, err := centerElement.FloatValues()
+	if err != nil {
+		return fmt.Errorf("reading WindowCenter: %v", err)
+	}
+	widths, err := widthElement.FloatValues()
+	if err != nil {
+		return fmt.Errorf("reading WindowWidth: %v", err)
+	}
+	if len(centers) != len(widths) {
+		return fmt.Errorf("got %d window centers and %d window widths", len(centers), len(widths))
+	}
+	var explanations []string
+	if element, ok := ds.Elements[WindowCenterWidthExplanationTag]; ok {
+		explanations, _ = element.ValueField.([]string)
+	}
+
+	function := VOILUTFunctionLinear
+	if element, ok := ds.Elements[VOILUTFunctionTag]; ok {
+		v, err := element.StringValue()
+		if err != nil {
+			return fmt.Errorf("reading VOILUTFunction: %v", err)
+		}
+		function = VOILUTFunction(strings.TrimSpace(v))
+	}
+
+	for i := range centers {
+		w := Window{Center: centers[i], Width: widths[i], Function: function}
+		switch {
+		case function != VOILUTFunctionLinear && function != VOILUTFunctionLinearExact && function != VOILUTFunctionSigmoid:
+			return fmt.Errorf("unsupported VOILUTFunction %q", function)
+		case function == VOILUTFunctionLinear && w.Width < 1, w.Width <= 0:
+			return fmt.Errorf("invalid WindowWidth %v for VOILUTFunction %v", w.Width, function)
+		}
+		voi := VOI{Window: w}
+		if i < len(explanations) {
+			voi.Explanation = strings.TrimSpace(explanations[i])
+		}
+		t.VOIs = append(t.VOIs, voi)
+	}
+	return nil
+}
+
+// sequenceLUTs returns the LUTs of the items of the sequence of the DataSet, with their
+// explanations
+func sequenceLUTs(ds *DataSet, tag DataElementTag, signed bool) ([]*LUT, []string, error) {
+	element, ok := ds.Elements[tag]
+	if !ok {
+		return nil, nil, nil
+	}
+	seq, ok := element.ValueField.(*Sequence)
+	if !ok {
+		return nil, nil, fmt.Errorf("unexpected type %T of %v (expected *Sequence)", element.ValueField, tag)
+	}
+
+	var luts []*LUT
+	var explanations []string
+	for i, item := range seq.Items {
+		lut, err := newLUT(item, LUTDescriptorTag, LUTDataTag, signed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading item %d of %v: %v", i, tag, err)
+		}
+		explanation := ""
+		if element, ok := item.Elements[LUTExplanationTag]; ok {
+			explanation, _ = element.StringValue()
+		}
+		luts = append(luts, lut)
+		explanations = append(explanations, strings.TrimSpace(explanation))
+	}
+	return luts, explanations, nil
+}
+
+// modality returns the modality value of the stored value v
+func (t *GrayscaleTransform) modality(v int) float64 {
+	if t.ModalityLUT != nil {
+		return float64(t.ModalityLUT.Lookup(v))
+	}
+	return float64(v)*t.RescaleSlope + t.RescaleIntercept
+}
+
+// Apply returns the display values in the range [0, 2^bits - 1] of the samples of the grayscale
+// frame with the VOI transform VOIs[voi]. If voi is -1, the range of modality values of the frame
+// is displayed. bits must be between 1 and 16.
+func (t *GrayscaleTransform) Apply(frame *Frame, voi, bits int) ([]int, error) {
+	if frame.Info.SamplesPerPixel != 1 {
+		return nil, fmt.Errorf("got %d samples per pixel, grayscale frames have 1", frame.Info.SamplesPerPixel)
+	}
+	if voi < -1 || voi >= len(t.VOIs) {
+		return nil, fmt.Errorf("VOI %d out of range [-1, %d)", voi, len(t.VOIs))
+	}
+	if bits < 1 || bits > 16 {
+		return nil, fmt.Errorf("invalid number of bits %d of display values", bits)
+	}
+
+	modality := make([]float64, len(frame.Samples))
+	for i, v := range frame.Samples {
+		modality[i] = t.modality(v)
+	}
+
+	var transform func(x float64) float64
+	switch {
+	case voi == -1:
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, x := range modality {
+			min, max = math.Min(min, x), math.Max(max, x)
+		}
+		transform = func(x float64) float64 {
+			if max == min {
+				return 0
+			}
+			return (x - min) / (max - min)
+		}
+	case t.VOIs[voi].LUT != nil:
+		lut := t.VOIs[voi].LUT
+		transform = func(x float64) float64 {
+			return float64(lut.Lookup(int(math.Floor(x)))) / float64(int(1)<<uint(lut.Bits)-1)
+		}
+	default:
+		transform = t.VOIs[voi].Window.apply
+	}
+
+	maxDisplay := float64(int(1)<<uint(bits) - 1)
+	display := make([]int, len(modality))
+	for i, x := range modality {
+		y := transform(x)
+		if t.Inverse {
+			y = 1 - y
+		}
+		display[i] = int(math.Round(y * maxDisplay))
+	}
+	return display, nil
+}
+
+// Gray returns the grayscale frame as an *image.Gray with the VOI transform VOIs[voi], see Apply
+func (t *GrayscaleTransform) Gray(frame *Frame, voi int) (*image.Gray, error) {
+	display, err := t.Apply(frame, voi, 8)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewGray(image.Rect(0, 0, frame.Info.Columns, frame.Info.Rows))
+	for i, v := range display {
+		img.Pix[i] = byte(v)
+	}
+	return img, nil
+}
+
+// Gray16 returns the grayscale frame as an *image.Gray16 with the VOI transform VOIs[voi], see
+// Apply
+func (t *GrayscaleTransform) Gray16(frame *Frame, voi int) (*image.Gray16, error) {
+	display, err := t.Apply(frame, voi, 16)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewGray16(image.Rect(0, 0, frame.Info.Columns, frame.Info.Rows))
+	for i, v := range display {
+		img.Pix[2*i] = byte(v >> 8)
+		img.Pix[2*i+1] = byte(v)
+	}
+	return img, nil
+}
+
+// WriteGrayscalePNG writes frame i of the grayscale pixel data of the DataSet to w as a 16 bit
+// PNG image, with the VOI transform voi of its GrayscaleTransform, see Apply.
+func WriteGrayscalePNG(w io.Writer, ds *DataSet, i, voi int) error {
+	pixelData, err := NewPixelData(ds)
+	if err != nil {
+		return err
+	}
+	t, err := NewGrayscaleTransform(ds)
+	if err != nil {
+		return err
+	}
+	frame, err := pixelData.Frame(i)
+	if err != nil {
+		return err
+	}
+	img, err := t.Gray16(frame, voi)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
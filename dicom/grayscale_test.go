@@ -0,0 +1,340 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWindow_apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		window Window
+		in     []float64
+		want   []float64
+	}{
+		{
+			"linear",
+			Window{Center: 40, Width: 400, Function: VOILUTFunctionLinear},
+			[]float64{-1000, -160, 39.5, 239, 239.5, 1000},
+			[]float64{0, 0, 0.5, 1, 1, 1},
+		},
+		{
+			"linear exact",
+			Window{Center: 40, Width: 400, Function: VOILUTFunctionLinearExact},
+			[]float64{-1000, -160, -60, 40, 240, 241},
+			[]float64{0, 0, 0.25, 0.5, 1, 1},
+		},
+		{
+			"sigmoid",
+			Window{Center: 40, Width: 400, Function: VOILUTFunctionSigmoid},
+			[]float64{40, 140},
+			[]float64{0.5, 1 / (1 + math.Exp(-1))},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for i, x := range tc.in {
+				if got := tc.window.apply(x); math.Abs(got-tc.want[i]) > 1e-9 {
+					t.Errorf("apply(%v) => %v, want %v", x, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// ctDataSet returns a DataSet of a 1x4 signed 16 bit grayscale frame with the given elements
+func ctDataSet(elements map[DataElementTag]interface{}) *DataSet {
+	ds := NewDataSet(map[DataElementTag]interface{}{
+		RowsTag:                      []uint16{1},
+		ColumnsTag:                   []uint16{4},
+		BitsAllocatedTag:             []uint16{16},
+		PixelRepresentationTag:       []uint16{1},
+		PhotometricInterpretationTag: []string{"MONOCHROME2"},
+		// -2000, 0, 1000, 3000
+		PixelDataTag: NewBulkDataBuffer([]byte{0x30, 0xF8, 0x00, 0x00, 0xE8, 0x03, 0xB8, 0x0B}),
+	})
+	for tag, v := range elements {
+		ds.Elements[tag] = &DataElement{tag, tag.DictionaryVR(), v, 0}
+	}
+	return ds
+}
+
+func TestNewGrayscaleTransform(t *testing.T) {
+	tests := []struct {
+		name string
+		ds   *DataSet
+		want *GrayscaleTransform
+	}{
+		{
+			"defaults",
+			ctDataSet(nil),
+			&GrayscaleTransform{RescaleSlope: 1},
+		},
+		{
+			"rescale and windows",
+			ctDataSet(map[DataElementTag]interface{}{
+				RescaleSlopeTag:                 []string{"2"},
+				RescaleInterceptTag:             []string{"-1024"},
+				WindowCenterTag:                 []string{"40", "300 "},
+				WindowWidthTag:                  []string{"400", "1500"},
+				WindowCenterWidthExplanationTag: []string{"SOFT TISSUE", "BONE"},
+				VOILUTFunctionTag:               []string{"SIGMOID"},
+			}),
+			&GrayscaleTransform{
+				RescaleSlope:     2,
+				RescaleIntercept: -1024,
+				VOIs: []VOI{
+					{Window: Window{40, 400, VOILUTFunctionSigmoid}, Explanation: "SOFT TISSUE"},
+					{Window: Window{300, 1500, VOILUTFunctionSigmoid}, Explanation: "BONE"},
+				},
+			},
+		},
+		{
+			"modality LUT and VOI LUT sequences",
+			ctDataSet(map[DataElementTag]interface{}{
+				ModalityLUTSequenceTag: &Sequence{Items: []*DataSet{
+					lutItem([]uint16{2, 0xFFFF, 12}, []uint16{0, 4095}),
+				}},
+				VOILUTSequenceTag: &Sequence{Items: []*DataSet{
+					NewDataSet(map[DataElementTag]interface{}{
+						LUTDescriptorTag:  []uint16{2, 0, 8},
+						LUTDataTag:        []uint16{0, 255},
+						LUTExplanationTag: []string{"FULL "},
+					}),
+				}},
+				PresentationLUTShapeTag: []string{"INVERSE"},
+			}),
+			&GrayscaleTransform{
+				RescaleSlope: 1,
+				ModalityLUT:  &LUT{FirstMapped: -1, Bits: 12, Data: []int{0, 4095}},
+				VOIs:         []VOI{{LUT: &LUT{FirstMapped: 0, Bits: 8, Data: []int{0, 255}}, Explanation: "FULL"}},
+				Inverse:      true,
+			},
+		},
+		{
+			"MONOCHROME1 is inverted",
+			ctDataSet(map[DataElementTag]interface{}{PhotometricInterpretationTag: []string{"MONOCHROME1"}}),
+			&GrayscaleTransform{RescaleSlope: 1, Inverse: true},
+		},
+		{
+			"presentation LUT shape overrides MONOCHROME1",
+			ctDataSet(map[DataElementTag]interface{}{
+				PhotometricInterpretationTag: []string{"MONOCHROME1"},
+				PresentationLUTShapeTag:      []string{"IDENTITY"},
+			}),
+			&GrayscaleTransform{RescaleSlope: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewGrayscaleTransform(tc.ds)
+			if err != nil {
+				t.Fatalf("NewGrayscaleTransform(_) => %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("NewGrayscaleTransform(_) => %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewGrayscaleTransform_invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements map[DataElementTag]interface{}
+	}{
+		{"color pixel data", map[DataElementTag]interface{}{PhotometricInterpretationTag: []string{"RGB"}}},
+		{"invalid rescale slope", map[DataElementTag]interface{}{RescaleSlopeTag: []string{"a"}}},
+		{"unpaired window center", map[DataElementTag]interface{}{
+			WindowCenterTag: []string{"40", "300"},
+			WindowWidthTag:  []string{"400"},
+		}},
+		{"linear window narrower than 1", map[DataElementTag]interface{}{
+			WindowCenterTag: []string{"40"},
+			WindowWidthTag:  []string{"0.5"},
+		}},
+		{"unsupported VOI LUT function", map[DataElementTag]interface{}{
+			WindowCenterTag:   []string{"40"},
+			WindowWidthTag:    []string{"400"},
+			VOILUTFunctionTag: []string{"CUBIC"},
+		}},
+		{"unsupported presentation LUT shape", map[DataElementTag]interface{}{PresentationLUTShapeTag: []string{"LOG"}}},
+		{"invalid VOI LUT", map[DataElementTag]interface{}{
+			VOILUTSequenceTag: &Sequence{Items: []*DataSet{lutItem([]uint16{4, 0, 8}, []uint16{1})}},
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewGrayscaleTransform(ctDataSet(tc.elements)); err == nil {
+				t.Fatalf("NewGrayscaleTransform(_) => nil, expected an error")
+			}
+		})
+	}
+}
+
+func TestGrayscaleTransform_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements map[DataElementTag]interface{}
+		voi      int
+		bits     int
+		want     []int
+	}{
+		{
+			"full range of modality values",
+			nil,
+			-1,
+			8,
+			[]int{0, 102, 153, 255},
+		},
+		{
+			"rescaled window",
+			map[DataElementTag]interface{}{
+				RescaleInterceptTag: []string{"-1000"},
+				WindowCenterTag:     []string{"0", "0"},
+				WindowWidthTag:      []string{"1000", "1"},
+				VOILUTFunctionTag:   []string{"LINEAR_EXACT"},
+			},
+			0,
+			16,
+			[]int{0, 0, 32768, 65535},
+		},
+		{
+			"second window",
+			map[DataElementTag]interface{}{
+				RescaleInterceptTag: []string{"-1000"},
+				WindowCenterTag:     []string{"500", "0"},
+				WindowWidthTag:      []string{"1000", "1"},
+			},
+			1,
+			8,
+			[]int{0, 0, 255, 255},
+		},
+		{
+			"modality LUT, VOI LUT and inverse presentation LUT shape",
+			map[DataElementTag]interface{}{
+				ModalityLUTSequenceTag: &Sequence{Items: []*DataSet{
+					lutItem([]uint16{2, 0, 16}, []uint16{0, 2}),
+				}},
+				VOILUTSequenceTag: &Sequence{Items: []*DataSet{
+					lutItem([]uint16{3, 0, 4}, []uint16{0, 5, 15}),
+				}},
+				PresentationLUTShapeTag: []string{"INVERSE"},
+			},
+			0,
+			8,
+			[]int{255, 255, 0, 0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ds := ctDataSet(tc.elements)
+			transform, err := NewGrayscaleTransform(ds)
+			if err != nil {
+				t.Fatalf("NewGrayscaleTransform(_) => %v", err)
+			}
+			pixelData, err := NewPixelData(ds)
+			if err != nil {
+				t.Fatalf("NewPixelData(_) => %v", err)
+			}
+			frame, err := pixelData.Frame(0)
+			if err != nil {
+				t.Fatalf("Frame(0) => %v", err)
+			}
+			got, err := transform.Apply(frame, tc.voi, tc.bits)
+			if err != nil {
+				t.Fatalf("Apply(_, %d, %d) => %v", tc.voi, tc.bits, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Apply(_, %d, %d) => %v, want %v", tc.voi, tc.bits, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGrayscaleTransform_Apply_invalid(t *testing.T) {
+	transform := &GrayscaleTransform{RescaleSlope: 1, VOIs: []VOI{{Window: Window{0, 10, VOILUTFunctionLinear}}}}
+	gray := mustNewFrame([]byte{1, 2}, FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8}, t)
+	rgb := mustNewFrame([]byte{1, 2, 3}, FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8}, t)
+
+	tests := []struct {
+		name  string
+		frame *Frame
+		voi   int
+		bits  int
+	}{
+		{"color frame", rgb, 0, 8},
+		{"VOI out of range", gray, 1, 8},
+		{"VOI below -1", gray, -2, 8},
+		{"too many bits", gray, 0, 17},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := transform.Apply(tc.frame, tc.voi, tc.bits); err == nil {
+				t.Fatalf("Apply(_, %d, %d) => nil, expected an error", tc.voi, tc.bits)
+			}
+		})
+	}
+}
+
+func TestGrayscaleTransform_Gray(t *testing.T) {
+	transform := &GrayscaleTransform{RescaleSlope: 1}
+	frame := mustNewFrame([]byte{0, 1, 3, 2}, FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8}, t)
+
+	got, err := transform.Gray(frame, -1)
+	if err != nil {
+		t.Fatalf("Gray(_, -1) => %v", err)
+	}
+	want := &image.Gray{Pix: []byte{0, 85, 255, 170}, Stride: 2, Rect: image.Rect(0, 0, 2, 2)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Gray(_, -1) => %v, want %v", got, want)
+	}
+}
+
+func TestWriteGrayscalePNG(t *testing.T) {
+	ds := ctDataSet(map[DataElementTag]interface{}{
+		WindowCenterTag: []string{"500"},
+		WindowWidthTag:  []string{"1001"},
+	})
+	buff := &bytes.Buffer{}
+	if err := WriteGrayscalePNG(buff, ds, 0, 0); err != nil {
+		t.Fatalf("WriteGrayscalePNG(_, _, 0, 0) => %v", err)
+	}
+
+	img, err := png.Decode(buff)
+	if err != nil {
+		t.Fatalf("png.Decode(_) => %v", err)
+	}
+	got, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("decoded %T, want *image.Gray16", img)
+	}
+	// 0 is just above the lower edge of the window
+	want := &image.Gray16{Pix: []byte{0x00, 0x00, 0x00, 0x21, 0xFF, 0xFF, 0xFF, 0xFF}, Stride: 8, Rect: image.Rect(0, 0, 4, 1)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("decoded %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// PhotometricInterpretation is the intended interpretation of the samples of pixel data, see the
+// DICOM standard part3 linked below.
+// http://dicom.nema.org/medical/dicom/current/output/html/part03.html#sect_C.7.6.3.1.2
+type PhotometricInterpretation string
+
+const (
+	// PhotometricMonochrome1 is a single grayscale sample per pixel, where the minimum sample value
+	// is displayed as white
+	PhotometricMonochrome1 PhotometricInterpretation = "MONOCHROME1"
+	// PhotometricMonochrome2 is a single grayscale sample per pixel, where the minimum sample value
+	// is displayed as black
+	PhotometricMonochrome2 PhotometricInterpretation = "MONOCHROME2"
+	// PhotometricRGB is red, green and blue samples for each pixel
+	PhotometricRGB PhotometricInterpretation = "RGB"
+)
+
+// photometricInterpretation returns the PhotometricInterpretation of the pixel data of the
+// DataSet. MONOCHROME2 and RGB are assumed for pixel data with 1 and 3 samples per pixel when the
+// element is missing.
+func photometricInterpretation(ds *DataSet, info FrameInfo) (PhotometricInterpretation, error) {
+	element, ok := ds.Elements[PhotometricInterpretationTag]
+	if !ok {
+		if info.SamplesPerPixel == 3 {
+			return PhotometricRGB, nil
+		}
+		return PhotometricMonochrome2, nil
+	}
+	v, err := element.StringValue()
+	if err != nil {
+		return "", fmt.Errorf("reading PhotometricInterpretation: %v", err)
+	}
+	return PhotometricInterpretation(strings.TrimSpace(v)), nil
+}
+
+// Image returns frame i of the pixel data as an image.Image, see NewImage. PALETTE COLOR frames
+// are expanded with the Palette, see PaletteColorLUT.Image.
+func (p *PixelData) Image(i int) (image.Image, error) {
+	frame, err := p.Frame(i)
+	if err != nil {
+		return nil, err
+	}
+	if p.PhotometricInterpretation == PhotometricPaletteColor && p.Palette != nil {
+		return p.Palette.Image(frame)
+	}
+	return NewImage(frame, p.PhotometricInterpretation)
+}
+
+// NewImage returns the frame as an image.Image. MONOCHROME2 frames are returned as *image.Gray16
+// and MONOCHROME1 frames as *image.Gray16 with inverted values. RGB frames are returned as
+// *image.RGBA, as are YBR_FULL, YBR_FULL_422, YBR_PARTIAL_422, YBR_ICT and YBR_RCT frames after
+// their samples are converted to RGB. Sample values are scaled from the range of BitsStored bits to
+// the range of the image, with signed samples offset to be non-negative first.
+func NewImage(frame *Frame, pi PhotometricInterpretation) (image.Image, error) {
+	if pi.ybr() {
+		rgb, err := convertFrameToRGB(frame, pi)
+		if err != nil {
+			return nil, err
+		}
+		frame, pi = rgb, PhotometricRGB
+	}
+	info := frame.Info
+	rect := image.Rect(0, 0, info.Columns, info.Rows)
+	switch pi {
+	case PhotometricMonochrome1, PhotometricMonochrome2:
+		if info.SamplesPerPixel != 1 {
+			return nil, fmt.Errorf("got %d samples per pixel, %v expects 1", info.SamplesPerPixel, pi)
+		}
+		img := image.NewGray16(rect)
+		for pixel, v := range frame.Samples {
+			g := scaleSample(v, info, 0xFFFF)
+			if pi == PhotometricMonochrome1 {
+				g = 0xFFFF - g
+			}
+			img.Pix[2*pixel] = byte(g >> 8)
+			img.Pix[2*pixel+1] = byte(g)
+		}
+		return img, nil
+	case PhotometricRGB:
+		if info.SamplesPerPixel != 3 {
+			return nil, fmt.Errorf("got %d samples per pixel, %v expects 3", info.SamplesPerPixel, pi)
+		}
+		img := image.NewRGBA(rect)
+		for pixel := 0; pixel < info.Rows*info.Columns; pixel++ {
+			for s := 0; s < 3; s++ {
+				img.Pix[4*pixel+s] = byte(scaleSample(frame.Samples[3*pixel+s], info, 0xFF))
+			}
+			img.Pix[4*pixel+3] = 0xFF
+		}
+		return img, nil
+	default:
+		return nil, fmt.Errorf("unsupported PhotometricInterpretation %q", pi)
+	}
+}
+
+// scaleSample scales a sample value of the frame to the range [0, max]
+func scaleSample(v int, info FrameInfo, max int) int {
+	bits := uint(info.bitsStored())
+	if info.PixelRepresentation == 1 {
+		v += 1 << (bits - 1)
+	}
+	return v * max / (1<<bits - 1)
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func mustNewFrame(frame []byte, info FrameInfo, t *testing.T) *Frame {
+	f, err := NewFrame(frame, info)
+	if err != nil {
+		t.Fatalf("NewFrame(%v, %+v) => %v", frame, info, err)
+	}
+	return f
+}
+
+func TestNewImage(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		info  FrameInfo
+		pi    PhotometricInterpretation
+		want  image.Image
+	}{
+		{
+			"MONOCHROME2",
+			[]byte{0x00, 0x80, 0xFF},
+			FrameInfo{Rows: 1, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 8},
+			PhotometricMonochrome2,
+			&image.Gray16{Pix: []byte{0x00, 0x00, 0x80, 0x80, 0xFF, 0xFF}, Stride: 6, Rect: image.Rect(0, 0, 3, 1)},
+		},
+		{
+			"MONOCHROME1 is inverted",
+			[]byte{0x00, 0x80, 0xFF},
+			FrameInfo{Rows: 1, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 8},
+			PhotometricMonochrome1,
+			&image.Gray16{Pix: []byte{0xFF, 0xFF, 0x7F, 0x7F, 0x00, 0x00}, Stride: 6, Rect: image.Rect(0, 0, 3, 1)},
+		},
+		{
+			"12 bit samples are scaled to 16 bits",
+			[]byte{0x00, 0x00, 0xFF, 0x0F},
+			FrameInfo{Rows: 2, Columns: 1, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12},
+			PhotometricMonochrome2,
+			&image.Gray16{Pix: []byte{0x00, 0x00, 0xFF, 0xFF}, Stride: 2, Rect: image.Rect(0, 0, 1, 2)},
+		},
+		{
+			"signed samples are offset",
+			[]byte{0x00, 0xF8, 0xFF, 0x07},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12, PixelRepresentation: 1},
+			PhotometricMonochrome2,
+			&image.Gray16{Pix: []byte{0x00, 0x00, 0xFF, 0xFF}, Stride: 4, Rect: image.Rect(0, 0, 2, 1)},
+		},
+		{
+			"RGB color-by-plane",
+			[]byte{0x10, 0x40, 0x20, 0x50, 0x30, 0x60},
+			FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 8, PlanarConfiguration: 1},
+			PhotometricRGB,
+			&image.RGBA{Pix: []byte{0x10, 0x20, 0x30, 0xFF, 0x40, 0x50, 0x60, 0xFF}, Stride: 8, Rect: image.Rect(0, 0, 2, 1)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewImage(mustNewFrame(tc.frame, tc.info, t), tc.pi)
+			if err != nil {
+				t.Fatalf("NewImage(_, %v) => %v", tc.pi, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("NewImage(_, %v) => %v, want %v", tc.pi, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewImage_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		info FrameInfo
+		pi   PhotometricInterpretation
+	}{
+		{
+			"monochrome with 3 samples per pixel",
+			FrameInfo{Rows: 1, Columns: 1, SamplesPerPixel: 3, BitsAllocated: 8},
+			PhotometricMonochrome2,
+		},
+		{
+			"RGB with 1 sample per pixel",
+			FrameInfo{Rows: 1, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 8},
+			PhotometricRGB,
+		},
+		{
+			"unsupported photometric interpretation",
+			FrameInfo{Rows: 1, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 8},
+			PhotometricInterpretation("HSV"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewImage(mustNewFrame([]byte{1, 2, 3}, tc.info, t), tc.pi); err == nil {
+				t.Fatalf("NewImage(_, %v) => nil, expected an error", tc.pi)
+			}
+		})
+	}
+}
+
+func TestPixelData_Image(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements map[DataElementTag]interface{}
+		want     color.Color
+	}{
+		{
+			"MONOCHROME2 is assumed for a single sample",
+			map[DataElementTag]interface{}{},
+			color.Gray16{0xC0C0},
+		},
+		{
+			"MONOCHROME1 with trailing padding",
+			map[DataElementTag]interface{}{PhotometricInterpretationTag: []string{"MONOCHROME1 "}},
+			color.Gray16{0x3F3F},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			elements := map[DataElementTag]interface{}{
+				RowsTag:          []uint16{1},
+				ColumnsTag:       []uint16{2},
+				BitsAllocatedTag: []uint16{8},
+				PixelDataTag:     NewBulkDataBuffer([]byte{0x00, 0xC0}),
+			}
+			for tag, v := range tc.elements {
+				elements[tag] = v
+			}
+			pixelData, err := NewPixelData(NewDataSet(elements))
+			if err != nil {
+				t.Fatalf("NewPixelData(_) => %v", err)
+			}
+			img, err := pixelData.Image(0)
+			if err != nil {
+				t.Fatalf("Image(0) => %v", err)
+			}
+			if got := img.At(1, 0); got != tc.want {
+				t.Fatalf("Image(0).At(1, 0) => %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package dicom
 
 import (
+	"bufio"
 	"bytes"
 	"compress/flate"
 	"fmt"
@@ -36,32 +37,141 @@ type DataElementIterator interface {
 	Length() uint32
 
 	syntax() transferSyntax
+
+	// offset returns the byte offset of the DataElement last returned by Next
+	offset() int64
 }
 
 // NewDataElementIterator creates a DataElementIterator from a DICOM file. The implementation
 // returned will consume input from the io.Reader given as needed. It is the callers responsibility
 // to ensure that Close is called when done consuming DataElements.
 func NewDataElementIterator(r io.Reader) (DataElementIterator, error) {
-	dr := newDcmReader(r)
+	iter, _, err := newFileDataElementIterator(r, parseSettings{}, nil)
+	return iter, err
+}
+
+// NewDataSetIterator creates a DataElementIterator over a data set encoded in the transfer syntax
+// identified by syntaxUID. Unlike NewDataElementIterator, r must not contain the preamble, DICOM
+// signature or File Meta Information of a DICOM file. This is useful for data sets that are not
+// stored as DICOM files such as those exchanged in network messages or extracted from sequence
+// items. The offsets of bulk data are relative to the start of r. An error is returned if syntaxUID
+// is not known to LookupTransferSyntax. It is the callers responsibility to ensure that Close is
+// called when done consuming DataElements.
+func NewDataSetIterator(r io.Reader, syntaxUID string) (DataElementIterator, error) {
+	iter, _, err := newDataSetIterator(r, syntaxUID, parseSettings{}, nil)
+	return iter, err
+}
+
+// newDataSetIterator creates a DataElementIterator over a data set like NewDataSetIterator according
+// to the given settings, reading within the bounds of limiter. When settings permit leniency, a
+// data set in an unknown transfer syntax is read as explicit VR little endian. The recoveries
+// applied to read the data set are returned as well.
+func newDataSetIterator(r io.Reader, syntaxUID string, settings parseSettings, limiter *parseLimiter) (DataElementIterator, []Recovery, error) {
+	if _, err := LookupTransferSyntax(syntaxUID); err != nil && !settings.lenient {
+		return nil, nil, err
+	}
+	dr := &dcmReader{cr: &countReader{limiter.reader(r), 0}, limiter: limiter}
+	dr, syntax, recoveries, err := detectVREncoding(dr, lookupTransferSyntax(syntaxUID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return newFileIterator(dr, nil, syntax), recoveries, nil
+}
+
+// newFileDataElementIterator creates a DataElementIterator from a DICOM file according to the
+// given settings, reading within the bounds of limiter. When settings permit leniency, the
+// recoveries applied to read the file header are returned as well.
+func newFileDataElementIterator(r io.Reader, settings parseSettings, limiter *parseLimiter) (DataElementIterator, []Recovery, error) {
+	r = limiter.reader(r)
+	if settings.lenient {
+		br := bufio.NewReader(r)
+		header, err := readLenientHeader(br, limiter)
+		if err != nil {
+			return nil, nil, err
+		}
+		dr := &dcmReader{cr: &countReader{br, header.length}, limiter: limiter}
+		dr, syntax, recoveries, err := detectVREncoding(dr, header.syntax)
+		if err != nil {
+			return nil, nil, err
+		}
+		recoveries = append(header.recoveries, recoveries...)
+		return newFileIterator(dr, header.metaHeaderBytes, syntax), recoveries, nil
+	}
+
+	dr := &dcmReader{cr: &countReader{r, 0}, limiter: limiter}
 	if err := readDicomSignature(dr); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	metaHeaderBytes, err := bufferMetadataHeader(dr)
 	if err != nil {
-		return nil, fmt.Errorf("reading meta header: %v", err)
+		return nil, nil, fmt.Errorf("reading meta header: %v", err)
 	}
 	syntax, err := findSyntax(metaHeaderBytes)
 	if err != nil {
-		return nil, fmt.Errorf("finding transfer syntax: %v", err)
+		return nil, nil, fmt.Errorf("finding transfer syntax: %v", err)
+	}
+	dr, syntax, recoveries, err := detectVREncoding(dr, syntax)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newFileIterator(dr, metaHeaderBytes, syntax), recoveries, nil
+}
+
+// detectVREncoding detects data sets that are not encoded with the explicit or implicit VR encoding
+// of the declared syntax by looking at the first data element read from dr. The returned dcmReader
+// reads the data set from its start in the returned syntax. Deflated data sets are not examined.
+func detectVREncoding(dr *dcmReader, declared transferSyntax) (*dcmReader, transferSyntax, []Recovery, error) {
+	if declared.isDeflated() {
+		return dr, declared, nil, nil
 	}
 
+	head := make([]byte, tagSize+vrSize)
+	n, err := io.ReadFull(dr.cr, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, nil, fmt.Errorf("reading first data element: %v", err)
+	}
+	start := dr.cr.bytesRead - int64(n)
+	dr = &dcmReader{cr: &countReader{io.MultiReader(bytes.NewReader(head[:n]), dr.cr), start}, limiter: dr.limiter}
+	if n < len(head) {
+		return dr, declared, nil, nil
+	}
+
+	vrBytes := head[tagSize:]
+	_, explicit := declared.(explicitSyntax)
+	switch {
+	case explicit && !isVRCode(vrBytes):
+		// Implicit VR big endian does not exist, so the data set must be implicit VR little endian.
+		return dr, implicitVRLittleEndian, []Recovery{CorrectedVREncoding}, nil
+	case !explicit && isKnownVR(vrBytes):
+		return dr, explicitVRLittleEndian, []Recovery{CorrectedVREncoding}, nil
+	}
+	return dr, declared, nil, nil
+}
+
+// isVRCode returns true if b is formatted like a VR code. Codes of VRs unknown to the reader are
+// accepted so that data sets with VRs added in later editions of the standard are not mistaken for
+// implicit VR data sets.
+func isVRCode(b []byte) bool {
+	return len(b) == vrSize && b[0] >= 'A' && b[0] <= 'Z' && b[1] >= 'A' && b[1] <= 'Z'
+}
+
+func isKnownVR(b []byte) bool {
+	_, err := lookupVRByName(string(b))
+	return err == nil
+}
+
+// newFileIterator creates a DataElementIterator over the meta header elements encoded in
+// metaHeaderBytes followed by the data set elements read from dr in the given syntax.
+func newFileIterator(dr *dcmReader, metaHeaderBytes []byte, syntax transferSyntax) DataElementIterator {
 	metaReader := newDcmReader(bytes.NewBuffer(metaHeaderBytes))
 	metaHeader := newDataElementIterator(metaReader, explicitVRLittleEndian, UndefinedLength)
+	metaOffset := dr.cr.bytesRead - int64(len(metaHeaderBytes))
 
-	if syntax == deflatedExplicitVRLittleEndian {
-		decompressor := flate.NewReader(r)
-		dr := newDcmReader(decompressor)
+	if syntax.isDeflated() {
+		decompressor := flate.NewReader(dr.cr)
+		dr := &dcmReader{cr: &countReader{dr.limiter.reader(decompressor), 0}, limiter: dr.limiter}
 
 		iter := &dataElementIterator{
 			dr:             dr,
@@ -69,10 +179,11 @@ func NewDataElementIterator(r io.Reader) (DataElementIterator, error) {
 			currentElement: nil,
 			empty:          false,
 			metaHeader:     metaHeader,
+			metaOffset:     metaOffset,
 			length:         UndefinedLength,
 		}
 
-		return &deflatedDataElementIterator{DataElementIterator: iter, closer: decompressor}, nil
+		return &deflatedDataElementIterator{DataElementIterator: iter, closer: decompressor}
 	}
 
 	return &dataElementIterator{
@@ -81,20 +192,21 @@ func NewDataElementIterator(r io.Reader) (DataElementIterator, error) {
 		currentElement: nil,
 		empty:          false,
 		metaHeader:     metaHeader,
+		metaOffset:     metaOffset,
 		length:         UndefinedLength,
-	}, nil
+	}
 }
 
 // newDataElementIterator creates a DataElementIterator from a byte stream that excludes header info
 // (preamble and metadata elements)
 func newDataElementIterator(r *dcmReader, syntax transferSyntax, length uint32) DataElementIterator {
 	return &dataElementIterator{
-		r,
-		syntax,
-		nil,
-		false,
-		emptyElementIterator{syntax},
-		length,
+		dr:             r,
+		transferSyntax: syntax,
+		currentElement: nil,
+		empty:          false,
+		metaHeader:     emptyElementIterator{syntax},
+		length:         length,
 	}
 }
 
@@ -105,6 +217,12 @@ type dataElementIterator struct {
 	empty          bool
 	metaHeader     DataElementIterator
 	length         uint32
+
+	// metaOffset is the byte offset of the first element of metaHeader in the input
+	metaOffset int64
+
+	// elementOffset is the byte offset of the element last returned by Next
+	elementOffset int64
 }
 
 func (it *dataElementIterator) Next() (*DataElement, error) {
@@ -115,6 +233,7 @@ func (it *dataElementIterator) Next() (*DataElement, error) {
 	if err != nil {
 		return nil, err
 	}
+	it.elementOffset = it.metaOffset + it.metaHeader.offset()
 	return metaElem, nil
 }
 
@@ -122,13 +241,21 @@ func (it *dataElementIterator) syntax() transferSyntax {
 	return it.transferSyntax
 }
 
+func (it *dataElementIterator) offset() int64 {
+	return it.elementOffset
+}
+
 func (it *dataElementIterator) nextDataSetElement() (*DataElement, error) {
 	if it.empty {
 		return nil, io.EOF
 	}
+	if err := it.dr.limiter.checkContext(); err != nil {
+		return nil, err
+	}
 	if err := it.closeCurrent(); err != nil {
 		return nil, fmt.Errorf("closing: %v", err)
 	}
+	it.elementOffset = it.dr.cr.bytesRead
 
 	element, err := readDataElement(it.dr, it.transferSyntax)
 	if err == io.EOF {
@@ -144,6 +271,13 @@ func (it *dataElementIterator) nextDataSetElement() (*DataElement, error) {
 	return it.currentElement, nil
 }
 
+// stop ends the iteration without reading the value field of the current element or the elements
+// following it
+func (it *dataElementIterator) stop() {
+	it.empty = true
+	it.currentElement = nil
+}
+
 func (it *dataElementIterator) Close() error {
 	// empty the iterator
 	for _, err := it.Next(); err != io.EOF; _, err = it.Next() {
@@ -206,6 +340,9 @@ func bufferMetadataHeader(dr *dcmReader) ([]byte, error) {
 		return nil, fmt.Errorf("FileMetaInformationGroupLength could not be converted to int: %v", err)
 	}
 
+	if err := dr.limiter.checkValueLength(FileMetaInformationGroupLengthTag, metaGroupLength); err != nil {
+		return nil, err
+	}
 	remainderBytes, err := dr.Bytes(metaGroupLength)
 	if err != nil {
 		return nil, fmt.Errorf("buffering file meta elements: %v", err)
@@ -242,6 +379,12 @@ type deflatedDataElementIterator struct {
 	closer io.Closer
 }
 
+func (it *deflatedDataElementIterator) stop() {
+	if s, ok := it.DataElementIterator.(stopper); ok {
+		s.stop()
+	}
+}
+
 func (it *deflatedDataElementIterator) Close() error {
 	if err := it.DataElementIterator.Close(); err != nil {
 		return err
@@ -249,6 +392,44 @@ func (it *deflatedDataElementIterator) Close() error {
 	return it.closer.Close()
 }
 
+// stopper is implemented by DataElementIterators that can end early, after which Close releases
+// the iterator without consuming its remaining input
+type stopper interface {
+	stop()
+}
+
+// stopAtTagIterator is a DataElementIterator that ends when it encounters an element with a tag
+// greater than or equal to tag. The elements remaining in the underlying iterator are not consumed.
+type stopAtTagIterator struct {
+	DataElementIterator
+	tag     DataElementTag
+	stopped bool
+}
+
+func (it *stopAtTagIterator) Next() (*DataElement, error) {
+	if it.stopped {
+		return nil, io.EOF
+	}
+	element, err := it.DataElementIterator.Next()
+	if err != nil {
+		return nil, err
+	}
+	if element.Tag >= it.tag {
+		it.stopped = true
+		if s, ok := it.DataElementIterator.(stopper); ok {
+			s.stop()
+		}
+		return nil, io.EOF
+	}
+	return element, nil
+}
+
+// Close closes the underlying iterator. Once iteration has stopped, the input following the
+// element iteration stopped at is left unread by underlying iterators implementing stopper.
+func (it *stopAtTagIterator) Close() error {
+	return it.DataElementIterator.Close()
+}
+
 type emptyElementIterator struct {
 	transferSyntax transferSyntax
 }
@@ -261,6 +442,10 @@ func (it emptyElementIterator) syntax() transferSyntax {
 	return it.transferSyntax
 }
 
+func (it emptyElementIterator) offset() int64 {
+	return 0
+}
+
 func (it emptyElementIterator) Close() error {
 	return nil
 }
@@ -15,11 +15,13 @@
 package dicom
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
 	"testing/iotest"
 )
@@ -75,6 +77,44 @@ func TestIterator_NextElement(t *testing.T) {
 	}
 }
 
+func TestNewDataSetIterator(t *testing.T) {
+	dataSetBytes, err := ioutil.ReadAll(mustOpenOSFile("ImplicitVRLittleEndianUndefLen.dcm", t))
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+	iter, err := NewDataSetIterator(iotest.OneByteReader(bytes.NewReader(dataSetBytes[132+12+196:])), ImplicitVRLittleEndianUID)
+	if err != nil {
+		t.Fatalf("NewDataSetIterator(_, _) => %v", err)
+	}
+	defer iter.Close()
+
+	want := withoutMetaElements(createExpectedDataSet(bufferedPixelData, 196, ImplicitVRLittleEndianUID))
+	count := 0
+	for elem, err := iter.Next(); err != io.EOF; elem, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("Next() => %v", err)
+		}
+		compareDataElements(elem, want.Elements[elem.Tag], binary.LittleEndian, t)
+		count++
+	}
+	if count != len(want.Elements) {
+		t.Fatalf("got %v elements, want %v", count, len(want.Elements))
+	}
+}
+
+func TestNewDataSetIterator_unknownTransferSyntax(t *testing.T) {
+	in := []byte{0x08, 0x00, 0x60, 0x00, 'C', 'S', 0x02, 0x00, 'M', 'R'}
+	if _, err := NewDataSetIterator(bytes.NewReader(in), "1.2.840.10008.1.2.1.bogus"); err == nil {
+		t.Fatalf("NewDataSetIterator(_, _) => nil, expected an error")
+	}
+	if _, err := ParseDataSet(bytes.NewReader(in), "1.2.840.10008.1.2.1.bogus"); err == nil {
+		t.Fatalf("ParseDataSet(_, _) => nil, expected an error")
+	}
+	if _, err := ParseDataSet(bytes.NewReader(in), "1.2.840.10008.1.2.1.bogus", Lenient); err != nil {
+		t.Fatalf("ParseDataSet(_, _, Lenient) => %v", err)
+	}
+}
+
 func TestIterator_oneByteReader(t *testing.T) {
 	r, err := openFile("ExplicitVRLittleEndian.dcm")
 	if err != nil {
@@ -169,3 +209,123 @@ func (closer *stubCloser) Close() error {
 	closer.closed = true
 	return nil
 }
+
+func TestDetectVREncoding(t *testing.T) {
+	implicitDataSet := []byte{
+		0x08, 0x00, 0x60, 0x00, 0x02, 0x00, 0x00, 0x00, 'M', 'R', // Modality
+		0x10, 0x00, 0x10, 0x00, 0x04, 0x00, 0x00, 0x00, 'J', 'o', 'e', ' ', // PatientName
+	}
+	explicitDataSet := []byte{
+		0x08, 0x00, 0x60, 0x00, 'C', 'S', 0x02, 0x00, 'M', 'R', // Modality
+		0x10, 0x00, 0x10, 0x00, 'P', 'N', 0x04, 0x00, 'J', 'o', 'e', ' ', // PatientName
+	}
+	want := &DataSet{Elements: map[DataElementTag]*DataElement{
+		ModalityTag:    {ModalityTag, CSVR, []string{"MR"}, 2},
+		PatientNameTag: {PatientNameTag, PNVR, []string{"Joe"}, 4},
+	}}
+
+	tests := []struct {
+		name           string
+		in             []byte
+		syntaxUID      string
+		wantRecoveries []Recovery
+	}{
+		{
+			"implicit VR data sets labelled explicit VR are read as implicit VR",
+			implicitDataSet,
+			ExplicitVRLittleEndianUID,
+			[]Recovery{CorrectedVREncoding},
+		},
+		{
+			"explicit VR data sets labelled implicit VR are read as explicit VR",
+			explicitDataSet,
+			ImplicitVRLittleEndianUID,
+			[]Recovery{CorrectedVREncoding},
+		},
+		{
+			"correctly labelled data sets are not corrected",
+			explicitDataSet,
+			ExplicitVRLittleEndianUID,
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseDataSet(bytes.NewReader(tc.in), tc.syntaxUID)
+			if err != nil {
+				t.Fatalf("ParseDataSet(_, _) => %v", err)
+			}
+			compareDataSets(got, want, binary.LittleEndian, t)
+			if !reflect.DeepEqual(got.Recoveries, tc.wantRecoveries) {
+				t.Fatalf("got recoveries %v, want %v", got.Recoveries, tc.wantRecoveries)
+			}
+		})
+	}
+}
+
+func TestDetectVREncoding_file(t *testing.T) {
+	header := NewDataSet(map[DataElementTag]interface{}{
+		TransferSyntaxUIDTag: []string{ExplicitVRLittleEndianUID},
+	})
+	file := &bytes.Buffer{}
+	if err := Construct(file, header); err != nil {
+		t.Fatalf("Construct(_, _) => %v", err)
+	}
+	file.Write([]byte{0x08, 0x00, 0x60, 0x00, 0x02, 0x00, 0x00, 0x00, 'M', 'R'}) // implicit VR Modality
+
+	got, err := Parse(file)
+	if err != nil {
+		t.Fatalf("Parse(_) => %v", err)
+	}
+	if want := []Recovery{CorrectedVREncoding}; !reflect.DeepEqual(got.Recoveries, want) {
+		t.Fatalf("got recoveries %v, want %v", got.Recoveries, want)
+	}
+	compareDataElements(got.Elements[ModalityTag], &DataElement{ModalityTag, CSVR, []string{"MR"}, 2}, binary.LittleEndian, t)
+}
+
+// closeRecordingIterator is a DataElementIterator over elements which records whether it was
+// closed and stopped
+type closeRecordingIterator struct {
+	emptyElementIterator
+	elements []*DataElement
+	closed   bool
+	stopped  bool
+}
+
+func (it *closeRecordingIterator) Next() (*DataElement, error) {
+	if it.stopped || len(it.elements) == 0 {
+		return nil, io.EOF
+	}
+	element := it.elements[0]
+	it.elements = it.elements[1:]
+	return element, nil
+}
+
+func (it *closeRecordingIterator) stop() {
+	it.stopped = true
+}
+
+func (it *closeRecordingIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+func TestStopAtTagIterator_Close(t *testing.T) {
+	underlying := &closeRecordingIterator{elements: []*DataElement{
+		{ModalityTag, CSVR, []string{"MR"}, 2},
+		{PixelDataTag, OBVR, NewBulkDataBuffer([]byte{1, 2}), 2},
+	}}
+	iter := &stopAtTagIterator{DataElementIterator: underlying, tag: PixelDataTag}
+	for _, err := iter.Next(); err != io.EOF; _, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("Next() => %v", err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		t.Fatalf("Close() => %v", err)
+	}
+	if !underlying.stopped || !underlying.closed {
+		t.Fatalf("got stopped %v and closed %v, want the underlying iterator stopped and closed", underlying.stopped, underlying.closed)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+)
+
+// jpegBaselineCodec is the Codec of the JPEG Baseline (Process 1) transfer syntax. Frames hold 8 bit
+// samples with 1 or 3 samples per pixel. The samples of color frames are the Y, Cb and Cr samples
+// of the JPEG stream at full resolution (YBR_FULL), unless the JPEG stream is marked as RGB by an
+// Adobe APP14 segment.
+type jpegBaselineCodec struct{}
+
+func (jpegBaselineCodec) Decode(data []byte, info FrameInfo) ([]byte, error) {
+	if err := validateJPEGBaselineFrameInfo(info); err != nil {
+		return nil, err
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding JPEG: %v", err)
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != info.Columns || bounds.Dy() != info.Rows {
+		return nil, fmt.Errorf("got %dx%d JPEG image, expected %dx%d", bounds.Dx(), bounds.Dy(), info.Columns, info.Rows)
+	}
+
+	frame := make([]byte, info.Length())
+	setPixel := func(pixel int, samples ...byte) {
+		for sample, v := range samples {
+			frame[info.sampleOffset(pixel, sample)] = v
+		}
+	}
+	switch m := img.(type) {
+	case *image.Gray:
+		if info.SamplesPerPixel != 1 {
+			return nil, fmt.Errorf("got grayscale JPEG image, expected %d samples per pixel", info.SamplesPerPixel)
+		}
+		for y := 0; y < info.Rows; y++ {
+			for x := 0; x < info.Columns; x++ {
+				setPixel(y*info.Columns+x, m.Pix[m.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+			}
+		}
+	case *image.YCbCr:
+		if info.SamplesPerPixel != 3 {
+			return nil, fmt.Errorf("got color JPEG image, expected %d samples per pixel", info.SamplesPerPixel)
+		}
+		for y := 0; y < info.Rows; y++ {
+			for x := 0; x < info.Columns; x++ {
+				yi, ci := m.YOffset(bounds.Min.X+x, bounds.Min.Y+y), m.COffset(bounds.Min.X+x, bounds.Min.Y+y)
+				setPixel(y*info.Columns+x, m.Y[yi], m.Cb[ci], m.Cr[ci])
+			}
+		}
+	default:
+		if info.SamplesPerPixel != 3 {
+			return nil, fmt.Errorf("got color JPEG image, expected %d samples per pixel", info.SamplesPerPixel)
+		}
+		for y := 0; y < info.Rows; y++ {
+			for x := 0; x < info.Columns; x++ {
+				r, g, b, _ := m.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				setPixel(y*info.Columns+x, byte(r>>8), byte(g>>8), byte(b>>8))
+			}
+		}
+	}
+	return frame, nil
+}
+
+// Encode encodes the frame with the default quality of image/jpeg. The samples of color frames
+// are Y, Cb and Cr samples, which image/jpeg subsamples to 4:2:0.
+func (jpegBaselineCodec) Encode(frame []byte, info FrameInfo) ([]byte, error) {
+	if err := validateJPEGBaselineFrameInfo(info); err != nil {
+		return nil, err
+	}
+	if len(frame) != info.Length() {
+		return nil, fmt.Errorf("got frame of length %d, expected %d", len(frame), info.Length())
+	}
+
+	rect := image.Rect(0, 0, info.Columns, info.Rows)
+	var img image.Image
+	if info.SamplesPerPixel == 1 {
+		img = &image.Gray{Pix: frame, Stride: info.Columns, Rect: rect}
+	} else {
+		m := image.NewYCbCr(rect, image.YCbCrSubsampleRatio444)
+		for pixel := 0; pixel < info.Rows*info.Columns; pixel++ {
+			m.Y[pixel] = frame[info.sampleOffset(pixel, 0)]
+			m.Cb[pixel] = frame[info.sampleOffset(pixel, 1)]
+			m.Cr[pixel] = frame[info.sampleOffset(pixel, 2)]
+		}
+		img = m
+	}
+
+	buff := &bytes.Buffer{}
+	if err := jpeg.Encode(buff, img, nil); err != nil {
+		return nil, fmt.Errorf("encoding JPEG: %v", err)
+	}
+	return buff.Bytes(), nil
+}
+
+func validateJPEGBaselineFrameInfo(info FrameInfo) error {
+	if info.Rows <= 0 || info.Columns <= 0 {
+		return fmt.Errorf("invalid frame dimensions %dx%d", info.Rows, info.Columns)
+	}
+	if info.BitsAllocated != 8 {
+		return fmt.Errorf("unsupported BitsAllocated %d, JPEG Baseline frames hold 8 bit samples", info.BitsAllocated)
+	}
+	if info.SamplesPerPixel != 1 && info.SamplesPerPixel != 3 {
+		return fmt.Errorf("unsupported SamplesPerPixel %d", info.SamplesPerPixel)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestJPEGBaselineCodec_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		info FrameInfo
+	}{
+		{"grayscale", FrameInfo{Rows: 16, Columns: 24, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"color-by-pixel", FrameInfo{Rows: 16, Columns: 16, SamplesPerPixel: 3, BitsAllocated: 8}},
+		{"color-by-plane", FrameInfo{Rows: 16, Columns: 16, SamplesPerPixel: 3, BitsAllocated: 8, PlanarConfiguration: 1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// a flat frame survives lossy compression
+			frame := make([]byte, tc.info.Length())
+			for pixel := 0; pixel < tc.info.Rows*tc.info.Columns; pixel++ {
+				for sample := 0; sample < tc.info.SamplesPerPixel; sample++ {
+					frame[tc.info.sampleOffset(pixel, sample)] = byte(100 + 20*sample)
+				}
+			}
+
+			codec := jpegBaselineCodec{}
+			data, err := codec.Encode(frame, tc.info)
+			if err != nil {
+				t.Fatalf("Encode(_, %v) => %v", tc.info, err)
+			}
+			got, err := codec.Decode(data, tc.info)
+			if err != nil {
+				t.Fatalf("Decode(_, %v) => %v", tc.info, err)
+			}
+			if len(got) != len(frame) {
+				t.Fatalf("got frame of length %d, want %d", len(got), len(frame))
+			}
+			for i := range got {
+				if diff := int(got[i]) - int(frame[i]); diff < -2 || diff > 2 {
+					t.Fatalf("got byte %d = %d, want %d", i, got[i], frame[i])
+				}
+			}
+		})
+	}
+}
+
+func TestJPEGBaselineCodec_Decode(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	buff := &bytes.Buffer{}
+	if err := jpeg.Encode(buff, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatalf("jpeg.Encode(_, _, _) => %v", err)
+	}
+	pixelData := NewEncapsulatedFormatBuffer([]byte{}, buff.Bytes())
+
+	info := FrameInfo{Rows: 8, Columns: 8, SamplesPerPixel: 1, BitsAllocated: 8}
+	frames, err := DecodeFrames(pixelData, JPEGBaselineUID, info, 1)
+	if err != nil {
+		t.Fatalf("DecodeFrames(_, %q, _, 1) => %v", JPEGBaselineUID, err)
+	}
+	if want := bytes.Repeat([]byte{0x80}, 64); !bytes.Equal(frames[0], want) {
+		t.Fatalf("DecodeFrames(_, %q, _, 1) => %x, want %x", JPEGBaselineUID, frames[0], want)
+	}
+}
+
+func TestJPEGBaselineCodec_invalid(t *testing.T) {
+	gray := FrameInfo{Rows: 8, Columns: 8, SamplesPerPixel: 1, BitsAllocated: 8}
+	data, err := jpegBaselineCodec{}.Encode(make([]byte, gray.Length()), gray)
+	if err != nil {
+		t.Fatalf("Encode(_, %v) => %v", gray, err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		info FrameInfo
+	}{
+		{"not a JPEG stream", []byte{0x00, 0x01}, gray},
+		{"dimension mismatch", data, FrameInfo{Rows: 4, Columns: 8, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"samples per pixel mismatch", data, FrameInfo{Rows: 8, Columns: 8, SamplesPerPixel: 3, BitsAllocated: 8}},
+		{"16 bit samples", data, FrameInfo{Rows: 8, Columns: 8, SamplesPerPixel: 1, BitsAllocated: 16}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := (jpegBaselineCodec{}).Decode(tc.data, tc.info); err == nil {
+				t.Fatalf("Decode(_, %v) => nil, expected an error", tc.info)
+			}
+		})
+	}
+
+	if _, err := (jpegBaselineCodec{}).Encode([]byte{0x00}, gray); err == nil {
+		t.Fatalf("Encode(_, %v) with short frame => nil, expected an error", gray)
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// JPEG markers used by the lossless process, see ITU-T T.81 Table B.1
+const (
+	jpegSOI  = 0xD8
+	jpegEOI  = 0xD9
+	jpegSOF3 = 0xC3
+	jpegDHT  = 0xC4
+	jpegSOS  = 0xDA
+	jpegDRI  = 0xDD
+	jpegRST0 = 0xD0
+	jpegRST7 = 0xD7
+)
+
+// jpegLosslessCodec is the Codec of the JPEG Lossless, Non-Hierarchical (Process 14) transfer
+// syntaxes, including the First-Order Prediction (Selection Value 1) transfer syntax. It decodes
+// Huffman coded lossless JPEG streams, as described in ITU-T T.81 Annex H, of 2 to 16 bit precision
+// using any of the seven predictors, with any number of components in interleaved or
+// non-interleaved scans. Encoding is not supported.
+type jpegLosslessCodec struct{}
+
+func (jpegLosslessCodec) Decode(data []byte, info FrameInfo) ([]byte, error) {
+	if info.BitsAllocated != 8 && info.BitsAllocated != 16 {
+		return nil, fmt.Errorf("unsupported BitsAllocated %d", info.BitsAllocated)
+	}
+	img, err := decodeLosslessJPEG(data)
+	if err != nil {
+		return nil, err
+	}
+	if img.rows != info.Rows || img.columns != info.Columns {
+		return nil, fmt.Errorf("got %dx%d JPEG image, expected %dx%d", img.columns, img.rows, info.Columns, info.Rows)
+	}
+	if len(img.samples) != info.SamplesPerPixel {
+		return nil, fmt.Errorf("got %d JPEG components, expected %d samples per pixel", len(img.samples), info.SamplesPerPixel)
+	}
+	if img.precision > info.BitsAllocated {
+		return nil, fmt.Errorf("JPEG precision %d exceeds BitsAllocated %d", img.precision, info.BitsAllocated)
+	}
+
+	frame := make([]byte, info.Length())
+	for sample, values := range img.samples {
+		for pixel, v := range values {
+			info.setSample(frame, pixel, sample, v)
+		}
+	}
+	return frame, nil
+}
+
+func (jpegLosslessCodec) Encode(frame []byte, info FrameInfo) ([]byte, error) {
+	return nil, fmt.Errorf("encoding lossless JPEG is not supported")
+}
+
+// losslessJPEG is a decoded lossless JPEG image
+type losslessJPEG struct {
+	precision int
+	rows      int
+	columns   int
+
+	// componentIDs holds the identifiers of the components in the order of the frame header
+	componentIDs []byte
+
+	// samples holds the samples of each component in row-major order
+	samples [][]int
+}
+
+// huffmanTable is a Huffman table decoded as described in ITU-T T.81 F.2.2.3
+type huffmanTable struct {
+	// maxCode, minCode and valPtr are indexed by code length. maxCode is -1 for lengths without codes
+	maxCode [17]int
+	minCode [17]int
+	valPtr  [17]int
+	values  []byte
+}
+
+// losslessJPEGDecoder holds the state of decoding a lossless JPEG stream
+type losslessJPEGDecoder struct {
+	data []byte
+	pos  int
+
+	// bits holds the nbits least significant bits not yet consumed from the entropy coded data
+	bits  uint32
+	nbits int
+
+	huffmanTables   [4]*huffmanTable
+	restartInterval int
+	img             *losslessJPEG
+}
+
+func decodeLosslessJPEG(data []byte) (*losslessJPEG, error) {
+	d := &losslessJPEGDecoder{data: data}
+	marker, err := d.marker()
+	if err != nil {
+		return nil, err
+	}
+	if marker != jpegSOI {
+		return nil, fmt.Errorf("missing JPEG start of image marker")
+	}
+
+	for {
+		marker, err := d.marker()
+		if err != nil {
+			return nil, err
+		}
+		if marker == jpegEOI {
+			break
+		}
+		segment, err := d.segment()
+		if err != nil {
+			return nil, fmt.Errorf("reading segment of marker %#x: %v", marker, err)
+		}
+
+		switch {
+		case marker == jpegSOF3:
+			err = d.frameHeader(segment)
+		case marker == jpegDHT:
+			err = d.huffmanTableSegment(segment)
+		case marker == jpegDRI:
+			if len(segment) != 2 {
+				return nil, fmt.Errorf("invalid define restart interval segment")
+			}
+			d.restartInterval = int(binary.BigEndian.Uint16(segment))
+		case marker == jpegSOS:
+			err = d.scan(segment)
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC8 && marker != 0xCC:
+			return nil, fmt.Errorf("unsupported JPEG process of marker %#x, expected lossless Huffman coding", marker)
+		}
+		// other segments, such as application data and comments, are skipped
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if d.img == nil || d.img.samples == nil {
+		return nil, fmt.Errorf("JPEG stream holds no image")
+	}
+	return d.img, nil
+}
+
+// marker reads a marker, skipping any fill bytes preceding it
+func (d *losslessJPEGDecoder) marker() (byte, error) {
+	if d.pos >= len(d.data) || d.data[d.pos] != 0xFF {
+		return 0, fmt.Errorf("expected JPEG marker at offset %d", d.pos)
+	}
+	for d.pos < len(d.data) && d.data[d.pos] == 0xFF {
+		d.pos++
+	}
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("unexpected end of JPEG stream")
+	}
+	marker := d.data[d.pos]
+	d.pos++
+	return marker, nil
+}
+
+// segment reads the parameters of a marker segment, excluding the 2 byte segment length
+func (d *losslessJPEGDecoder) segment() ([]byte, error) {
+	if d.pos+2 > len(d.data) {
+		return nil, fmt.Errorf("unexpected end of JPEG stream")
+	}
+	length := int(binary.BigEndian.Uint16(d.data[d.pos:]))
+	if length < 2 || d.pos+length > len(d.data) {
+		return nil, fmt.Errorf("invalid segment length %d", length)
+	}
+	segment := d.data[d.pos+2 : d.pos+length]
+	d.pos += length
+	return segment, nil
+}
+
+func (d *losslessJPEGDecoder) frameHeader(segment []byte) error {
+	if d.img != nil {
+		return fmt.Errorf("multiple JPEG frame headers")
+	}
+	if len(segment) < 6 || len(segment) != 6+3*int(segment[5]) {
+		return fmt.Errorf("invalid JPEG frame header")
+	}
+	img := &losslessJPEG{
+		precision: int(segment[0]),
+		rows:      int(binary.BigEndian.Uint16(segment[1:])),
+		columns:   int(binary.BigEndian.Uint16(segment[3:])),
+	}
+	if img.precision < 2 || img.precision > 16 {
+		return fmt.Errorf("unsupported JPEG precision %d", img.precision)
+	}
+	if img.rows == 0 || img.columns == 0 || segment[5] == 0 {
+		return fmt.Errorf("unsupported JPEG dimensions %dx%dx%d", img.columns, img.rows, segment[5])
+	}
+	for i := 0; i < int(segment[5]); i++ {
+		component := segment[6+3*i : 9+3*i]
+		if component[1] != 0x11 {
+			return fmt.Errorf("unsupported sampling factors %#x of component %d", component[1], component[0])
+		}
+		img.componentIDs = append(img.componentIDs, component[0])
+	}
+	d.img = img
+	return nil
+}
+
+func (d *losslessJPEGDecoder) huffmanTableSegment(segment []byte) error {
+	for len(segment) > 0 {
+		if len(segment) < 17 {
+			return fmt.Errorf("invalid Huffman table segment")
+		}
+		class, id := segment[0]>>4, segment[0]&0x0F
+		if class != 0 || id > 3 {
+			return fmt.Errorf("invalid Huffman table class %d and identifier %d", class, id)
+		}
+		counts := segment[1:17]
+		total := 0
+		for _, c := range counts {
+			total += int(c)
+		}
+		if len(segment) < 17+total {
+			return fmt.Errorf("invalid Huffman table segment")
+		}
+
+		table := &huffmanTable{values: segment[17 : 17+total]}
+		code, k := 0, 0
+		for length := 1; length <= 16; length++ {
+			count := int(counts[length-1])
+			table.maxCode[length] = -1
+			if count > 0 {
+				table.valPtr[length] = k
+				table.minCode[length] = code
+				code += count
+				k += count
+				table.maxCode[length] = code - 1
+			}
+			code <<= 1
+		}
+		d.huffmanTables[id] = table
+		segment = segment[17+total:]
+	}
+	return nil
+}
+
+// scan decodes the entropy coded data of the scan whose header is given, see ITU-T T.81 H.1.2
+func (d *losslessJPEGDecoder) scan(header []byte) error {
+	img := d.img
+	if img == nil {
+		return fmt.Errorf("JPEG scan precedes the frame header")
+	}
+	if len(header) < 1 || len(header) != 4+2*int(header[0]) || header[0] == 0 {
+		return fmt.Errorf("invalid JPEG scan header")
+	}
+	numComponents := int(header[0])
+	predictor := int(header[1+2*numComponents])
+	pointTransform := int(header[3+2*numComponents] & 0x0F)
+	if predictor < 1 || predictor > 7 {
+		return fmt.Errorf("unsupported predictor %d", predictor)
+	}
+	if pointTransform >= img.precision {
+		return fmt.Errorf("invalid point transform %d", pointTransform)
+	}
+
+	if img.samples == nil {
+		img.samples = make([][]int, len(img.componentIDs))
+	}
+	components := make([]int, numComponents)
+	tables := make([]*huffmanTable, numComponents)
+	for i := range components {
+		id, tableID := header[1+2*i], header[2+2*i]>>4
+		components[i] = -1
+		for c, componentID := range img.componentIDs {
+			if componentID == id {
+				components[i] = c
+			}
+		}
+		if components[i] < 0 {
+			return fmt.Errorf("scan of unknown component %d", id)
+		}
+		if img.samples[components[i]] != nil {
+			return fmt.Errorf("multiple scans of component %d", id)
+		}
+		img.samples[components[i]] = make([]int, img.rows*img.columns)
+		if tableID > 3 || d.huffmanTables[tableID] == nil {
+			return fmt.Errorf("undefined Huffman table %d", tableID)
+		}
+		tables[i] = d.huffmanTables[tableID]
+	}
+
+	d.bits, d.nbits = 0, 0
+	initial := 1 << uint(img.precision-pointTransform-1)
+	intervalStart := 0
+	for mcu := 0; mcu < img.rows*img.columns; mcu++ {
+		if d.restartInterval > 0 && mcu > 0 && mcu%d.restartInterval == 0 {
+			if err := d.restart(); err != nil {
+				return err
+			}
+			intervalStart = mcu
+		}
+
+		x, y := mcu%img.columns, mcu/img.columns
+		for i, c := range components {
+			samples := img.samples[c]
+			diff, err := d.difference(tables[i])
+			if err != nil {
+				return fmt.Errorf("decoding sample (%d, %d) of component %d: %v", x, y, img.componentIDs[c], err)
+			}
+			var prediction int
+			switch {
+			case mcu == intervalStart:
+				prediction = initial
+			case y == intervalStart/img.columns:
+				prediction = samples[mcu-1]
+			case x == 0:
+				prediction = samples[mcu-img.columns]
+			default:
+				prediction = predict(predictor, samples[mcu-1], samples[mcu-img.columns], samples[mcu-img.columns-1])
+			}
+			samples[mcu] = (prediction + diff) & 0xFFFF
+		}
+	}
+
+	if pointTransform > 0 {
+		for _, c := range components {
+			for i := range img.samples[c] {
+				img.samples[c][i] <<= uint(pointTransform)
+			}
+		}
+	}
+
+	// skip to the marker following the entropy coded data
+	for d.pos+1 < len(d.data) && !(d.data[d.pos] == 0xFF && d.data[d.pos+1] != 0x00) {
+		d.pos++
+	}
+	return nil
+}
+
+// predict returns the prediction of a sample from the reconstructed samples to its left (ra),
+// above (rb) and above left (rc), see ITU-T T.81 Table H.1
+func predict(predictor, ra, rb, rc int) int {
+	switch predictor {
+	case 1:
+		return ra
+	case 2:
+		return rb
+	case 3:
+		return rc
+	case 4:
+		return ra + rb - rc
+	case 5:
+		return ra + (rb-rc)>>1
+	case 6:
+		return rb + (ra-rc)>>1
+	default:
+		return (ra + rb) >> 1
+	}
+}
+
+// restart consumes the restart marker ending a restart interval
+func (d *losslessJPEGDecoder) restart() error {
+	d.bits, d.nbits = 0, 0
+	marker, err := d.marker()
+	if err != nil {
+		return fmt.Errorf("reading restart marker: %v", err)
+	}
+	if marker < jpegRST0 || marker > jpegRST7 {
+		return fmt.Errorf("got marker %#x, expected a restart marker", marker)
+	}
+	return nil
+}
+
+// difference decodes a Huffman coded difference, see ITU-T T.81 H.1.2.2
+func (d *losslessJPEGDecoder) difference(table *huffmanTable) (int, error) {
+	code := 0
+	for length := 1; length <= 16; length++ {
+		bit, err := d.bit()
+		if err != nil {
+			return 0, err
+		}
+		code = code<<1 | bit
+		if code <= table.maxCode[length] {
+			category := int(table.values[table.valPtr[length]+code-table.minCode[length]])
+			switch {
+			case category == 0:
+				return 0, nil
+			case category == 16:
+				return 32768, nil
+			case category > 16:
+				return 0, fmt.Errorf("invalid difference category %d", category)
+			}
+			v, err := d.receive(category)
+			if err != nil {
+				return 0, err
+			}
+			if v < 1<<uint(category-1) {
+				v -= 1<<uint(category) - 1
+			}
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid Huffman code")
+}
+
+// receive reads n bits of the entropy coded data
+func (d *losslessJPEGDecoder) receive(n int) (int, error) {
+	v := 0
+	for i := 0; i < n; i++ {
+		bit, err := d.bit()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<1 | bit
+	}
+	return v, nil
+}
+
+// bit reads a bit of the entropy coded data, removing the zero bytes stuffed after 0xFF bytes
+func (d *losslessJPEGDecoder) bit() (int, error) {
+	if d.nbits == 0 {
+		if d.pos >= len(d.data) {
+			return 0, fmt.Errorf("unexpected end of entropy coded data")
+		}
+		b := d.data[d.pos]
+		if b == 0xFF {
+			if d.pos+1 >= len(d.data) || d.data[d.pos+1] != 0x00 {
+				return 0, fmt.Errorf("unexpected marker in entropy coded data")
+			}
+			d.pos++
+		}
+		d.pos++
+		d.bits, d.nbits = uint32(b), 8
+	}
+	d.nbits--
+	return int(d.bits>>uint(d.nbits)) & 1, nil
+}
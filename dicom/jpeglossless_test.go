@@ -0,0 +1,352 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// losslessJPEGParams describe a synthetic lossless JPEG stream
+type losslessJPEGParams struct {
+	precision       int
+	predictor       int
+	pointTransform  int
+	restartInterval int
+
+	// interleaved encodes all components in a single scan rather than one scan per component
+	interleaved bool
+}
+
+// jpegBitWriter writes entropy coded data, stuffing a zero byte after each 0xFF byte
+type jpegBitWriter struct {
+	buff  *bytes.Buffer
+	bits  uint32
+	nbits uint
+}
+
+func (w *jpegBitWriter) write(v int, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		w.bits = w.bits<<1 | uint32(v>>uint(i))&1
+		w.nbits++
+		if w.nbits == 8 {
+			w.buff.WriteByte(byte(w.bits))
+			if byte(w.bits) == 0xFF {
+				w.buff.WriteByte(0x00)
+			}
+			w.bits, w.nbits = 0, 0
+		}
+	}
+}
+
+// flush pads the last byte with 1 bits
+func (w *jpegBitWriter) flush() {
+	if w.nbits > 0 {
+		w.write(0xFF, 8-w.nbits)
+	}
+}
+
+// losslessJPEGPredictors are the predictors of ITU-T T.81 Table H.1 from the samples to the left
+// (a), above (b) and above left (c) of the predicted sample. They are kept independent of the
+// decoder so that a mistake in the decoder's predictor isn't mirrored by the encoder.
+var losslessJPEGPredictors = map[int]func(a, b, c int) int{
+	1: func(a, b, c int) int { return a },
+	2: func(a, b, c int) int { return b },
+	3: func(a, b, c int) int { return c },
+	4: func(a, b, c int) int { return a + b - c },
+	5: func(a, b, c int) int { return a + ((b - c) >> 1) },
+	6: func(a, b, c int) int { return b + ((a - c) >> 1) },
+	7: func(a, b, c int) int { return (a + b) / 2 },
+}
+
+// encodeLosslessJPEG encodes the samples of each component of a rows x columns image as a lossless
+// JPEG stream. Differences are coded with a Huffman table assigning the 5 bit code c to category c.
+func encodeLosslessJPEG(samples [][]int, rows, columns int, p losslessJPEGParams) []byte {
+	buff := &bytes.Buffer{}
+	segment := func(marker byte, data ...byte) {
+		buff.Write([]byte{0xFF, marker})
+		binary.Write(buff, binary.BigEndian, uint16(len(data)+2))
+		buff.Write(data)
+	}
+
+	buff.Write([]byte{0xFF, jpegSOI})
+	frameHeader := []byte{byte(p.precision), byte(rows >> 8), byte(rows), byte(columns >> 8), byte(columns), byte(len(samples))}
+	for c := range samples {
+		frameHeader = append(frameHeader, byte(c+1), 0x11, 0x00)
+	}
+	segment(jpegSOF3, frameHeader...)
+	table := []byte{0x00, 0, 0, 0, 0, 17, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	for category := 0; category <= 16; category++ {
+		table = append(table, byte(category))
+	}
+	segment(jpegDHT, table...)
+	if p.restartInterval > 0 {
+		segment(jpegDRI, byte(p.restartInterval>>8), byte(p.restartInterval))
+	}
+
+	scans := [][]int{}
+	if p.interleaved {
+		scan := []int{}
+		for c := range samples {
+			scan = append(scan, c)
+		}
+		scans = append(scans, scan)
+	} else {
+		for c := range samples {
+			scans = append(scans, []int{c})
+		}
+	}
+
+	for _, scan := range scans {
+		scanHeader := []byte{byte(len(scan))}
+		for _, c := range scan {
+			scanHeader = append(scanHeader, byte(c+1), 0x00)
+		}
+		scanHeader = append(scanHeader, byte(p.predictor), 0x00, byte(p.pointTransform))
+		segment(jpegSOS, scanHeader...)
+
+		w := &jpegBitWriter{buff: buff}
+		intervalStart, restarts := 0, 0
+		for mcu := 0; mcu < rows*columns; mcu++ {
+			if p.restartInterval > 0 && mcu > 0 && mcu%p.restartInterval == 0 {
+				w.flush()
+				buff.Write([]byte{0xFF, byte(jpegRST0 + restarts%8)})
+				restarts++
+				intervalStart = mcu
+			}
+			x, y := mcu%columns, mcu/columns
+			for _, c := range scan {
+				s := func(i int) int { return samples[c][i] >> uint(p.pointTransform) }
+				// Prediction restarts at each restart interval as at the start of the scan, see
+				// ITU-T T.81 H.1.2.1.
+				var prediction int
+				switch {
+				case mcu == intervalStart:
+					prediction = 1 << uint(p.precision-p.pointTransform-1)
+				case y == intervalStart/columns:
+					prediction = s(mcu - 1)
+				case x == 0:
+					prediction = s(mcu - columns)
+				default:
+					prediction = losslessJPEGPredictors[p.predictor](s(mcu-1), s(mcu-columns), s(mcu-columns-1))
+				}
+
+				diff := (s(mcu) - prediction) & 0xFFFF
+				if diff >= 0x8000 {
+					diff -= 0x10000
+				}
+				if diff == -0x8000 {
+					w.write(16, 5)
+					continue
+				}
+				magnitude, category := diff, uint(0)
+				if magnitude < 0 {
+					magnitude = -magnitude
+				}
+				for ; magnitude > 0; magnitude >>= 1 {
+					category++
+				}
+				w.write(int(category), 5)
+				if diff < 0 {
+					diff += 1<<category - 1
+				}
+				w.write(diff, category)
+			}
+		}
+		w.flush()
+	}
+
+	buff.Write([]byte{0xFF, jpegEOI})
+	return buff.Bytes()
+}
+
+// syntheticSamples returns samples of each component with runs, gradients and extreme values
+func syntheticSamples(components, rows, columns, precision, pointTransform int) [][]int {
+	max := 1<<uint(precision) - 1
+	samples := make([][]int, components)
+	for c := range samples {
+		samples[c] = make([]int, rows*columns)
+		for i := range samples[c] {
+			x, y := i%columns, i/columns
+			v := (x*37 + y*101 + c*13) % (max + 1)
+			switch {
+			case x == 2 && y == 2:
+				v = max
+			case x == 3 && y == 2:
+				v = 0
+			case x == 4 && y == 2:
+				// a difference of 32768 from the left neighbor at 16 bit precision
+				v = (max + 1) / 2
+			case y == 1:
+				v = 5 + c
+			}
+			samples[c][i] = v >> uint(pointTransform) << uint(pointTransform)
+		}
+	}
+	return samples
+}
+
+func TestJPEGLosslessCodec_Decode(t *testing.T) {
+	tests := []struct {
+		name            string
+		samplesPerPixel int
+		bitsAllocated   int
+		params          losslessJPEGParams
+	}{
+		{"8 bit first-order prediction", 1, 8, losslessJPEGParams{precision: 8, predictor: 1}},
+		{"12 bit", 1, 16, losslessJPEGParams{precision: 12, predictor: 1}},
+		{"16 bit", 1, 16, losslessJPEGParams{precision: 16, predictor: 1}},
+		{"predictor 2", 1, 16, losslessJPEGParams{precision: 16, predictor: 2}},
+		{"predictor 3", 1, 16, losslessJPEGParams{precision: 16, predictor: 3}},
+		{"predictor 4", 1, 16, losslessJPEGParams{precision: 16, predictor: 4}},
+		{"predictor 5", 1, 16, losslessJPEGParams{precision: 16, predictor: 5}},
+		{"predictor 6", 1, 16, losslessJPEGParams{precision: 16, predictor: 6}},
+		{"predictor 7", 1, 16, losslessJPEGParams{precision: 16, predictor: 7}},
+		{"point transform", 1, 16, losslessJPEGParams{precision: 12, predictor: 7, pointTransform: 2}},
+		{"restart interval of whole rows", 1, 8, losslessJPEGParams{precision: 8, predictor: 4, restartInterval: 7}},
+		{"restart interval within rows", 1, 8, losslessJPEGParams{precision: 8, predictor: 6, restartInterval: 5}},
+		{"interleaved components", 3, 8, losslessJPEGParams{precision: 8, predictor: 1, interleaved: true}},
+		{"non-interleaved components", 3, 16, losslessJPEGParams{precision: 10, predictor: 5}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rows, columns := 6, 7
+			samples := syntheticSamples(tc.samplesPerPixel, rows, columns, tc.params.precision, tc.params.pointTransform)
+			data := encodeLosslessJPEG(samples, rows, columns, tc.params)
+
+			for planarConfiguration := 0; planarConfiguration < 2; planarConfiguration++ {
+				info := FrameInfo{
+					Rows:                rows,
+					Columns:             columns,
+					SamplesPerPixel:     tc.samplesPerPixel,
+					BitsAllocated:       tc.bitsAllocated,
+					PlanarConfiguration: planarConfiguration,
+				}
+				want := make([]byte, info.Length())
+				for sample, values := range samples {
+					for pixel, v := range values {
+						if info.BitsAllocated == 8 {
+							want[info.sampleOffset(pixel, sample)] = byte(v)
+						} else {
+							binary.LittleEndian.PutUint16(want[info.sampleOffset(pixel, sample):], uint16(v))
+						}
+					}
+				}
+
+				got, err := jpegLosslessCodec{}.Decode(data, info)
+				if err != nil {
+					t.Fatalf("Decode(_, %+v) => %v", info, err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("Decode(_, %+v) => %x, want %x", info, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJPEGLosslessCodec_invalid(t *testing.T) {
+	samples := syntheticSamples(1, 4, 4, 12, 0)
+	valid := encodeLosslessJPEG(samples, 4, 4, losslessJPEGParams{precision: 12, predictor: 1})
+	baseline, err := jpegBaselineCodec{}.Encode(make([]byte, 64), FrameInfo{Rows: 8, Columns: 8, SamplesPerPixel: 1, BitsAllocated: 8})
+	if err != nil {
+		t.Fatalf("encoding JPEG baseline stream: %v", err)
+	}
+	info := FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 16}
+
+	tests := []struct {
+		name string
+		data []byte
+		info FrameInfo
+	}{
+		{"not a JPEG stream", []byte{0x00, 0x01}, info},
+		{"truncated", valid[:len(valid)-6], info},
+		{"baseline process", baseline, FrameInfo{Rows: 8, Columns: 8, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"dimension mismatch", valid, FrameInfo{Rows: 2, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 16}},
+		{"samples per pixel mismatch", valid, FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 3, BitsAllocated: 16}},
+		{"precision exceeds bits allocated", valid, FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"unsupported bits allocated", valid, FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 32}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := (jpegLosslessCodec{}).Decode(tc.data, tc.info); err == nil {
+				t.Fatalf("Decode(_, %+v) => nil, expected an error", tc.info)
+			}
+		})
+	}
+
+	if _, err := (jpegLosslessCodec{}).Encode(make([]byte, info.Length()), info); err == nil {
+		t.Fatalf("Encode(_, %+v) => nil, expected an error", info)
+	}
+}
+
+func TestDecodeFrames_jpegLosslessFromIterator(t *testing.T) {
+	rows, columns := 5, 4
+	params := losslessJPEGParams{precision: 16, predictor: 1}
+	var frames, fragments [][]byte
+	for i := 0; i < 2; i++ {
+		samples := syntheticSamples(1, rows, columns, params.precision, 0)
+		samples[0][0] = i
+		fragments = append(fragments, encodeLosslessJPEG(samples, rows, columns, params))
+		frame := make([]byte, 2*rows*columns)
+		for pixel, v := range samples[0] {
+			binary.LittleEndian.PutUint16(frame[2*pixel:], uint16(v))
+		}
+		frames = append(frames, frame)
+	}
+
+	dataSet := NewDataSet(map[DataElementTag]interface{}{
+		TransferSyntaxUIDTag: []string{JPEGLosslessSV1UID},
+		PixelDataTag:         NewEncapsulatedFormatBuffer([]byte{}, fragments...),
+	})
+	dataSet.Elements[PixelDataTag].VR = OBVR
+	file := &bytes.Buffer{}
+	if err := Construct(file, dataSet); err != nil {
+		t.Fatalf("Construct(_, _) => %v", err)
+	}
+
+	iter, err := NewDataElementIterator(file)
+	if err != nil {
+		t.Fatalf("NewDataElementIterator(_) => %v", err)
+	}
+	defer iter.Close()
+	for element, err := iter.Next(); err != io.EOF; element, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("iter.Next() => %v", err)
+		}
+		if element.Tag != PixelDataTag {
+			continue
+		}
+		pixelData, err := element.ValueField.(BulkDataIterator).ToBuffer()
+		if err != nil {
+			t.Fatalf("ToBuffer() => %v", err)
+		}
+		info := FrameInfo{Rows: rows, Columns: columns, SamplesPerPixel: 1, BitsAllocated: 16}
+		got, err := DecodeFrames(pixelData, JPEGLosslessSV1UID, info, len(frames))
+		if err != nil {
+			t.Fatalf("DecodeFrames(_, _, _, %d) => %v", len(frames), err)
+		}
+		if !reflect.DeepEqual(got, frames) {
+			t.Fatalf("DecodeFrames(_, _, _, %d) => %x, want %x", len(frames), got, frames)
+		}
+		return
+	}
+	t.Fatalf("pixel data not found")
+}
@@ -0,0 +1,952 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// JPEG-LS markers, see ITU-T T.87 Table C.1
+const (
+	jpegSOF55 = 0xF7
+	jpegLSE   = 0xF8
+)
+
+const (
+	// jlsContexts is the number of regular contexts. The two run interruption contexts follow them.
+	jlsContexts = 365
+
+	// jlsReset is the default value of RESET, the threshold of halving the context counters
+	jlsReset = 64
+)
+
+// jlsRunOrder is the J array of ITU-T T.87 A.7.1.2 giving the order of run length codes
+var jlsRunOrder = [32]uint{0, 0, 0, 0, 1, 1, 1, 1, 2, 2, 2, 2, 3, 3, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// JPEGLSInterleaveMode is the interleave mode of the components of a JPEG-LS image, see
+// ITU-T T.87 B.2.3
+type JPEGLSInterleaveMode int
+
+const (
+	// JPEGLSInterleaveNone encodes each component in a separate scan
+	JPEGLSInterleaveNone JPEGLSInterleaveMode = 0
+	// JPEGLSInterleaveLine encodes the components of each line after each other in a single scan
+	JPEGLSInterleaveLine JPEGLSInterleaveMode = 1
+	// JPEGLSInterleaveSample encodes the components of each pixel after each other in a single scan
+	JPEGLSInterleaveSample JPEGLSInterleaveMode = 2
+)
+
+// JPEGLSCodec is the Codec of the JPEG-LS Lossless and Near-Lossless transfer syntaxes, as described
+// in ITU-T T.87. Decoding supports lossless and near-lossless images of 2 to 16 bit precision in
+// any interleave mode, as given by the JPEG-LS stream. Encoding uses the parameters of the codec.
+// Restart intervals and mapping tables are not supported. The zero value encodes losslessly with
+// non-interleaved components and is the Codec registered for both transfer syntaxes.
+type JPEGLSCodec struct {
+	// NearLossless is the NEAR parameter of encoding: the maximum difference between a sample and
+	// its decoded value. 0 is lossless.
+	NearLossless int
+
+	// InterleaveMode is the interleave mode of encoding images with multiple samples per pixel
+	InterleaveMode JPEGLSInterleaveMode
+}
+
+// Decode decodes a JPEG-LS stream into a native frame of 8 or 16 bit samples
+func (c JPEGLSCodec) Decode(data []byte, info FrameInfo) ([]byte, error) {
+	if info.BitsAllocated != 8 && info.BitsAllocated != 16 {
+		return nil, fmt.Errorf("unsupported BitsAllocated %d", info.BitsAllocated)
+	}
+	img, err := decodeJPEGLS(data)
+	if err != nil {
+		return nil, err
+	}
+	if img.rows != info.Rows || img.columns != info.Columns {
+		return nil, fmt.Errorf("got %dx%d JPEG-LS image, expected %dx%d", img.columns, img.rows, info.Columns, info.Rows)
+	}
+	if len(img.samples) != info.SamplesPerPixel {
+		return nil, fmt.Errorf("got %d JPEG-LS components, expected %d samples per pixel", len(img.samples), info.SamplesPerPixel)
+	}
+	if img.precision > info.BitsAllocated {
+		return nil, fmt.Errorf("JPEG-LS precision %d exceeds BitsAllocated %d", img.precision, info.BitsAllocated)
+	}
+
+	frame := make([]byte, info.Length())
+	for sample, values := range img.samples {
+		for pixel, v := range values {
+			info.setSample(frame, pixel, sample, v)
+		}
+	}
+	return frame, nil
+}
+
+// Encode encodes a native frame of 8 or 16 bit samples into a JPEG-LS stream whose precision is
+// the BitsStored of info
+func (c JPEGLSCodec) Encode(frame []byte, info FrameInfo) ([]byte, error) {
+	if info.BitsAllocated != 8 && info.BitsAllocated != 16 {
+		return nil, fmt.Errorf("unsupported BitsAllocated %d", info.BitsAllocated)
+	}
+	if info.Rows <= 0 || info.Columns <= 0 || info.Rows > 0xFFFF || info.Columns > 0xFFFF {
+		return nil, fmt.Errorf("invalid frame dimensions %dx%d", info.Rows, info.Columns)
+	}
+	if info.SamplesPerPixel <= 0 || info.SamplesPerPixel > 255 {
+		return nil, fmt.Errorf("unsupported SamplesPerPixel %d", info.SamplesPerPixel)
+	}
+	if len(frame) != info.Length() {
+		return nil, fmt.Errorf("got frame of length %d, expected %d", len(frame), info.Length())
+	}
+	if c.InterleaveMode < JPEGLSInterleaveNone || c.InterleaveMode > JPEGLSInterleaveSample {
+		return nil, fmt.Errorf("invalid interleave mode %d", c.InterleaveMode)
+	}
+	precision := info.bitsStored()
+	params, err := newJLSParams(precision, c.NearLossless, jlsPreset{})
+	if err != nil {
+		return nil, err
+	}
+
+	img := &jlsImage{precision: precision, rows: info.Rows, columns: info.Columns}
+	for sample := 0; sample < info.SamplesPerPixel; sample++ {
+		values := make([]int, info.Rows*info.Columns)
+		for pixel := range values {
+			values[pixel] = info.sample(frame, pixel, sample) & params.maxVal
+		}
+		img.samples = append(img.samples, values)
+		img.componentIDs = append(img.componentIDs, byte(sample+1))
+	}
+
+	buff := &bytes.Buffer{}
+	segment := func(marker byte, data ...byte) {
+		buff.Write([]byte{0xFF, marker})
+		binary.Write(buff, binary.BigEndian, uint16(len(data)+2))
+		buff.Write(data)
+	}
+	buff.Write([]byte{0xFF, jpegSOI})
+	frameHeader := []byte{byte(precision), byte(info.Rows >> 8), byte(info.Rows), byte(info.Columns >> 8), byte(info.Columns), byte(info.SamplesPerPixel)}
+	for _, id := range img.componentIDs {
+		frameHeader = append(frameHeader, id, 0x11, 0x00)
+	}
+	segment(jpegSOF55, frameHeader...)
+
+	mode := c.InterleaveMode
+	if info.SamplesPerPixel == 1 {
+		mode = JPEGLSInterleaveNone
+	}
+	scans := [][]int{}
+	if mode == JPEGLSInterleaveNone {
+		for component := range img.samples {
+			scans = append(scans, []int{component})
+		}
+	} else {
+		scan := []int{}
+		for component := range img.samples {
+			scan = append(scan, component)
+		}
+		scans = append(scans, scan)
+	}
+	for _, components := range scans {
+		scanHeader := []byte{byte(len(components))}
+		for _, component := range components {
+			scanHeader = append(scanHeader, img.componentIDs[component], 0x00)
+		}
+		scanHeader = append(scanHeader, byte(c.NearLossless), byte(mode), 0x00)
+		segment(jpegSOS, scanHeader...)
+
+		w := &jlsBitWriter{buff: buff, capacity: 8}
+		s := newJLSScan(params, w, nil)
+		if err := s.code(img, components, mode); err != nil {
+			return nil, err
+		}
+		w.flush()
+	}
+	buff.Write([]byte{0xFF, jpegEOI})
+	return buff.Bytes(), nil
+}
+
+// jlsImage is a JPEG-LS image
+type jlsImage struct {
+	precision int
+	rows      int
+	columns   int
+
+	// componentIDs holds the identifiers of the components in the order of the frame header
+	componentIDs []byte
+
+	// samples holds the samples of each component in row-major order
+	samples [][]int
+}
+
+// jlsPreset holds the coding parameters of an LSE marker segment, see ITU-T T.87 C.2.4.1.1. Zero
+// values select the defaults.
+type jlsPreset struct {
+	maxVal, t1, t2, t3, reset int
+}
+
+// jlsParams holds the coding parameters of a scan, see ITU-T T.87 A.2.1
+type jlsParams struct {
+	maxVal     int
+	near       int
+	t1, t2, t3 int
+	reset      int
+	rangeSize  int
+	qbpp       int
+	limit      int
+}
+
+func newJLSParams(precision, near int, preset jlsPreset) (jlsParams, error) {
+	if precision < 2 || precision > 16 {
+		return jlsParams{}, fmt.Errorf("unsupported JPEG-LS precision %d", precision)
+	}
+	p := jlsParams{maxVal: 1<<uint(precision) - 1, near: near, reset: jlsReset}
+	if preset.maxVal != 0 {
+		p.maxVal = preset.maxVal
+	}
+	if preset.reset != 0 {
+		p.reset = preset.reset
+	}
+	maxNear := p.maxVal / 2
+	if maxNear > 255 {
+		maxNear = 255
+	}
+	if near < 0 || near > maxNear {
+		return jlsParams{}, fmt.Errorf("invalid NEAR %d", near)
+	}
+
+	p.rangeSize = (p.maxVal+2*near)/(2*near+1) + 1
+	p.qbpp = bits.Len(uint(p.rangeSize - 1))
+	bpp := bits.Len(uint(p.maxVal))
+	if bpp < 2 {
+		bpp = 2
+	}
+	if bpp > 8 {
+		p.limit = 4 * bpp
+	} else {
+		p.limit = 2 * (bpp + 8)
+	}
+
+	// default thresholds of ITU-T T.87 C.2.4.1.1.1
+	clamp := func(i, j int) int {
+		if i > p.maxVal || i < j {
+			return j
+		}
+		return i
+	}
+	max := func(a, b int) int {
+		if a > b {
+			return a
+		}
+		return b
+	}
+	if p.maxVal >= 128 {
+		maxVal := p.maxVal
+		if maxVal > 4095 {
+			maxVal = 4095
+		}
+		factor := (maxVal + 128) / 256
+		p.t1 = clamp(factor*(3-2)+2+3*near, near+1)
+		p.t2 = clamp(factor*(7-3)+3+5*near, p.t1)
+		p.t3 = clamp(factor*(21-4)+4+7*near, p.t2)
+	} else {
+		factor := 256 / (p.maxVal + 1)
+		p.t1 = clamp(max(2, 3/factor+3*near), near+1)
+		p.t2 = clamp(max(3, 7/factor+5*near), p.t1)
+		p.t3 = clamp(max(4, 21/factor+7*near), p.t2)
+	}
+	if preset.t1 != 0 {
+		p.t1 = preset.t1
+	}
+	if preset.t2 != 0 {
+		p.t2 = preset.t2
+	}
+	if preset.t3 != 0 {
+		p.t3 = preset.t3
+	}
+	return p, nil
+}
+
+// quantizeGradient returns the region of a local gradient, see ITU-T T.87 A.3.3
+func (p *jlsParams) quantizeGradient(d int) int {
+	switch {
+	case d <= -p.t3:
+		return -4
+	case d <= -p.t2:
+		return -3
+	case d <= -p.t1:
+		return -2
+	case d < -p.near:
+		return -1
+	case d <= p.near:
+		return 0
+	case d < p.t1:
+		return 1
+	case d < p.t2:
+		return 2
+	case d < p.t3:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// quantizeError quantizes a prediction error in near-lossless coding and reduces it modulo RANGE,
+// see ITU-T T.87 A.4.4 and A.4.5
+func (p *jlsParams) quantizeError(e int) int {
+	if e > 0 {
+		e = (e + p.near) / (2*p.near + 1)
+	} else {
+		e = -(p.near - e) / (2*p.near + 1)
+	}
+	if e < 0 {
+		e += p.rangeSize
+	}
+	if e >= (p.rangeSize+1)/2 {
+		e -= p.rangeSize
+	}
+	return e
+}
+
+// reconstruct returns the reconstructed value of a sample from its prediction and quantized error
+func (p *jlsParams) reconstruct(prediction, e int) int {
+	v := prediction + e*(2*p.near+1)
+	if v < -p.near {
+		v += p.rangeSize * (2*p.near + 1)
+	} else if v > p.maxVal+p.near {
+		v -= p.rangeSize * (2*p.near + 1)
+	}
+	return p.clamp(v)
+}
+
+func (p *jlsParams) clamp(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > p.maxVal {
+		return p.maxVal
+	}
+	return v
+}
+
+// jlsScan holds the context variables of coding a scan. Exactly one of w and r is set: the scan
+// is encoded to w or decoded from r.
+type jlsScan struct {
+	p jlsParams
+	w *jlsBitWriter
+	r *jlsBitReader
+
+	// a, b, c and n are the context variables of ITU-T T.87 A.2.1, followed by the variables of
+	// the two run interruption contexts. nn is only used by the run interruption contexts.
+	a, b, c, n, nn [jlsContexts + 2]int
+}
+
+func newJLSScan(p jlsParams, w *jlsBitWriter, r *jlsBitReader) *jlsScan {
+	s := &jlsScan{p: p, w: w, r: r}
+	initialA := (p.rangeSize + 32) / 64
+	if initialA < 2 {
+		initialA = 2
+	}
+	for q := range s.a {
+		s.a[q] = initialA
+		s.n[q] = 1
+	}
+	return s
+}
+
+// code encodes or decodes the samples of the given components of img in the interleave mode
+func (s *jlsScan) code(img *jlsImage, components []int, mode JPEGLSInterleaveMode) error {
+	// lines are padded with a sample on each side, see ITU-T T.87 A.2.1
+	prev := make([][]int, len(components))
+	cur := make([][]int, len(components))
+	for i := range components {
+		prev[i] = make([]int, img.columns+2)
+		cur[i] = make([]int, img.columns+2)
+	}
+	runIndex := make([]int, len(components))
+
+	for y := 0; y < img.rows; y++ {
+		in := make([][]int, len(components))
+		for i, component := range components {
+			in[i] = img.samples[component][y*img.columns : (y+1)*img.columns]
+			cur[i][0] = prev[i][1]
+			prev[i][img.columns+1] = prev[i][img.columns]
+		}
+
+		if mode == JPEGLSInterleaveSample {
+			if err := s.line(cur, prev, in, &runIndex[0]); err != nil {
+				return fmt.Errorf("coding line %d: %v", y, err)
+			}
+		} else {
+			for i := range components {
+				if err := s.line(cur[i:i+1], prev[i:i+1], in[i:i+1], &runIndex[i]); err != nil {
+					return fmt.Errorf("coding line %d of component %d: %v", y, img.componentIDs[components[i]], err)
+				}
+			}
+		}
+
+		if s.r != nil {
+			for i := range components {
+				copy(in[i], cur[i][1:img.columns+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return nil
+}
+
+// line codes a line of the given components, whose samples are coded pixel by pixel. The
+// reconstructed samples are stored in cur and the samples being encoded are read from in.
+func (s *jlsScan) line(cur, prev, in [][]int, runIndex *int) error {
+	columns := len(in[0])
+	for x := 0; x < columns; {
+		// x+1 is the index of the current sample in the padded lines
+		run := true
+		for i := range cur {
+			ra, rb, rc, rd := cur[i][x], prev[i][x+1], prev[i][x], prev[i][x+2]
+			if s.p.quantizeGradient(rd-rb) != 0 || s.p.quantizeGradient(rb-rc) != 0 || s.p.quantizeGradient(rc-ra) != 0 {
+				run = false
+			}
+		}
+		if run {
+			next, err := s.runMode(cur, prev, in, x, runIndex)
+			if err != nil {
+				return err
+			}
+			x = next
+			continue
+		}
+
+		for i := range cur {
+			ix := 0
+			if s.w != nil {
+				ix = in[i][x]
+			}
+			rx, err := s.regular(cur[i][x], prev[i][x+1], prev[i][x], prev[i][x+2], ix)
+			if err != nil {
+				return err
+			}
+			cur[i][x+1] = rx
+		}
+		x++
+	}
+	return nil
+}
+
+// regular codes a sample in the regular mode of ITU-T T.87 A.3 to A.6 and returns its
+// reconstructed value
+func (s *jlsScan) regular(ra, rb, rc, rd, ix int) (int, error) {
+	q1, q2, q3 := s.p.quantizeGradient(rd-rb), s.p.quantizeGradient(rb-rc), s.p.quantizeGradient(rc-ra)
+	sign := 1
+	if q1 < 0 || (q1 == 0 && q2 < 0) || (q1 == 0 && q2 == 0 && q3 < 0) {
+		sign, q1, q2, q3 = -1, -q1, -q2, -q3
+	}
+	q := (q1*9+q2)*9 + q3
+
+	// median edge detector
+	var px int
+	switch {
+	case rc >= ra && rc >= rb:
+		px = ra
+		if rb < ra {
+			px = rb
+		}
+	case rc <= ra && rc <= rb:
+		px = ra
+		if rb > ra {
+			px = rb
+		}
+	default:
+		px = ra + rb - rc
+	}
+	px = s.p.clamp(px + sign*s.c[q])
+
+	k := uint(0)
+	for s.n[q]<<k < s.a[q] {
+		k++
+	}
+	// the error mapping is inverted when the context is biased, see ITU-T T.87 A.5.2
+	invert := s.p.near == 0 && k == 0 && 2*s.b[q] <= -s.n[q]
+
+	var errval int
+	if s.w != nil {
+		errval = s.p.quantizeError(sign * (ix - px))
+		mapped := 2 * errval
+		if errval < 0 {
+			mapped = -2*errval - 1
+		}
+		if invert {
+			mapped = 2*errval + 1
+			if errval < 0 {
+				mapped = -2 * (errval + 1)
+			}
+		}
+		s.w.golomb(k, s.p.limit, s.p.qbpp, mapped)
+	} else {
+		mapped, err := s.r.golomb(k, s.p.limit, s.p.qbpp)
+		if err != nil {
+			return 0, err
+		}
+		errval = mapped / 2
+		if mapped%2 != 0 {
+			errval = -(mapped + 1) / 2
+		}
+		if invert {
+			errval = (mapped - 1) / 2
+			if mapped%2 == 0 {
+				errval = -mapped/2 - 1
+			}
+		}
+	}
+
+	// context update, see ITU-T T.87 A.6
+	s.b[q] += errval * (2*s.p.near + 1)
+	if errval < 0 {
+		s.a[q] -= errval
+	} else {
+		s.a[q] += errval
+	}
+	if s.n[q] == s.p.reset {
+		s.a[q] >>= 1
+		s.b[q] >>= 1
+		s.n[q] >>= 1
+	}
+	s.n[q]++
+	if s.b[q] <= -s.n[q] {
+		s.b[q] += s.n[q]
+		if s.c[q] > -128 {
+			s.c[q]--
+		}
+		if s.b[q] <= -s.n[q] {
+			s.b[q] = -s.n[q] + 1
+		}
+	} else if s.b[q] > 0 {
+		s.b[q] -= s.n[q]
+		if s.c[q] < 127 {
+			s.c[q]++
+		}
+		if s.b[q] > 0 {
+			s.b[q] = 0
+		}
+	}
+
+	return s.p.reconstruct(px, sign*errval), nil
+}
+
+// runMode codes a run of pixels equal to the pixel to the left of x, followed by the pixel
+// interrupting the run unless the run reaches the end of the line, see ITU-T T.87 A.7. The index of
+// the pixel following the coded pixels is returned.
+func (s *jlsScan) runMode(cur, prev, in [][]int, x int, runIndex *int) (int, error) {
+	remaining := len(in[0]) - x
+	count := 0
+	if s.w != nil {
+		for ; count < remaining; count++ {
+			equal := true
+			for i := range cur {
+				if d := in[i][x+count] - cur[i][x]; d > s.p.near || d < -s.p.near {
+					equal = false
+				}
+			}
+			if !equal {
+				break
+			}
+		}
+
+		n := count
+		for n >= 1<<jlsRunOrder[*runIndex] {
+			s.w.bits(1, 1)
+			n -= 1 << jlsRunOrder[*runIndex]
+			if *runIndex < 31 {
+				*runIndex++
+			}
+		}
+		if count == remaining {
+			if n > 0 {
+				s.w.bits(1, 1)
+			}
+		} else {
+			s.w.bits(0, 1)
+			s.w.bits(n, jlsRunOrder[*runIndex])
+		}
+	} else {
+		for count < remaining {
+			bit, err := s.r.bits(1)
+			if err != nil {
+				return 0, err
+			}
+			if bit == 0 {
+				n, err := s.r.bits(jlsRunOrder[*runIndex])
+				if err != nil {
+					return 0, err
+				}
+				count += n
+				if count >= remaining {
+					return 0, fmt.Errorf("run of %d samples exceeds the line", count)
+				}
+				break
+			}
+			block := 1 << jlsRunOrder[*runIndex]
+			if block > remaining-count {
+				block = remaining - count
+			}
+			if block == 1<<jlsRunOrder[*runIndex] && *runIndex < 31 {
+				*runIndex++
+			}
+			count += block
+		}
+	}
+
+	for i := range cur {
+		for j := 1; j <= count; j++ {
+			cur[i][x+j] = cur[i][x]
+		}
+	}
+	x += count
+	if count == remaining {
+		return x, nil
+	}
+
+	for i := range cur {
+		ix := 0
+		if s.w != nil {
+			ix = in[i][x]
+		}
+		ra, rb := cur[i][x], prev[i][x+1]
+		var rx int
+		var err error
+		if len(cur) == 1 && ra-rb <= s.p.near && rb-ra <= s.p.near {
+			rx, err = s.interruption(1, ra, 1, ix, *runIndex)
+		} else {
+			sign := 1
+			if rb < ra {
+				sign = -1
+			}
+			rx, err = s.interruption(0, rb, sign, ix, *runIndex)
+		}
+		if err != nil {
+			return 0, err
+		}
+		cur[i][x+1] = rx
+	}
+	if *runIndex > 0 {
+		*runIndex--
+	}
+	return x + 1, nil
+}
+
+// interruption codes the sample interrupting a run with the given run interruption type,
+// prediction and sign, see ITU-T T.87 A.7.2, and returns its reconstructed value
+func (s *jlsScan) interruption(riType, px, sign, ix, runIndex int) (int, error) {
+	q := jlsContexts + riType
+	temp := s.a[q]
+	if riType == 1 {
+		temp += s.n[q] >> 1
+	}
+	k := uint(0)
+	for s.n[q]<<k < temp {
+		k++
+	}
+	limit := s.p.limit - int(jlsRunOrder[runIndex]) - 1
+	negativeMap := k != 0 || 2*s.nn[q] >= s.n[q]
+
+	var errval, mapped int
+	if s.w != nil {
+		errval = s.p.quantizeError(sign * (ix - px))
+		abs, m := errval, 0
+		if errval < 0 {
+			abs = -errval
+			if negativeMap {
+				m = 1
+			}
+		} else if errval > 0 && !negativeMap {
+			m = 1
+		}
+		mapped = 2*abs - riType - m
+		s.w.golomb(k, limit, s.p.qbpp, mapped)
+	} else {
+		var err error
+		mapped, err = s.r.golomb(k, limit, s.p.qbpp)
+		if err != nil {
+			return 0, err
+		}
+		t := mapped + riType
+		m := t & 1
+		errval = (t + m) / 2
+		if (m == 1) == negativeMap {
+			errval = -errval
+		}
+	}
+
+	if errval < 0 {
+		s.nn[q]++
+	}
+	s.a[q] += (mapped + 1 - riType) >> 1
+	if s.n[q] == s.p.reset {
+		s.a[q] >>= 1
+		s.n[q] >>= 1
+		s.nn[q] >>= 1
+	}
+	s.n[q]++
+
+	return s.p.reconstruct(px, sign*errval), nil
+}
+
+// jlsBitWriter writes the bits of a JPEG-LS scan. A 0 bit is stuffed after each 0xFF byte, see
+// ITU-T T.87 A.1.
+type jlsBitWriter struct {
+	buff *bytes.Buffer
+
+	// cur holds the n bits of the current byte, which holds capacity bits
+	cur      byte
+	n        uint
+	capacity uint
+	lastFF   bool
+}
+
+func (w *jlsBitWriter) bits(v int, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>uint(i))&1
+		w.n++
+		if w.n == w.capacity {
+			w.emit()
+		}
+	}
+}
+
+func (w *jlsBitWriter) emit() {
+	w.buff.WriteByte(w.cur)
+	w.lastFF = w.cur == 0xFF
+	w.capacity = 8
+	if w.lastFF {
+		w.capacity = 7
+	}
+	w.cur, w.n = 0, 0
+}
+
+// golomb writes a value with the limited length Golomb code of ITU-T T.87 A.5.3
+func (w *jlsBitWriter) golomb(k uint, limit, qbpp, v int) {
+	high := v >> k
+	if high < limit-qbpp-1 {
+		w.bits(0, uint(high))
+		w.bits(1, 1)
+		w.bits(v, k)
+		return
+	}
+	w.bits(0, uint(limit-qbpp-1))
+	w.bits(1, 1)
+	w.bits(v-1, uint(qbpp))
+}
+
+// flush pads the current byte with 0 bits, so that a following marker can be detected
+func (w *jlsBitWriter) flush() {
+	if w.n > 0 {
+		w.cur <<= w.capacity - w.n
+		w.emit()
+	}
+	if w.lastFF {
+		w.emit()
+	}
+}
+
+// jlsBitReader reads the bits of a JPEG-LS scan, removing the bits stuffed after 0xFF bytes
+type jlsBitReader struct {
+	data []byte
+	pos  int
+
+	// cur holds the n bits of the current byte not yet read
+	cur    byte
+	n      uint
+	lastFF bool
+}
+
+func (r *jlsBitReader) bits(n uint) (int, error) {
+	v := 0
+	for i := uint(0); i < n; i++ {
+		if r.n == 0 {
+			if r.pos >= len(r.data) {
+				return 0, fmt.Errorf("unexpected end of JPEG-LS scan")
+			}
+			r.cur, r.n = r.data[r.pos], 8
+			if r.lastFF {
+				if r.cur&0x80 != 0 {
+					return 0, fmt.Errorf("unexpected marker in JPEG-LS scan")
+				}
+				r.n = 7
+			}
+			r.lastFF = r.cur == 0xFF
+			r.pos++
+		}
+		r.n--
+		v = v<<1 | int(r.cur>>r.n)&1
+	}
+	return v, nil
+}
+
+// golomb reads a value with the limited length Golomb code of ITU-T T.87 A.5.3
+func (r *jlsBitReader) golomb(k uint, limit, qbpp int) (int, error) {
+	high := 0
+	for {
+		bit, err := r.bits(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			break
+		}
+		high++
+		if high > limit {
+			return 0, fmt.Errorf("invalid Golomb code")
+		}
+	}
+	if high >= limit-qbpp-1 {
+		v, err := r.bits(uint(qbpp))
+		return v + 1, err
+	}
+	v, err := r.bits(k)
+	return high<<k | v, err
+}
+
+func decodeJPEGLS(data []byte) (*jlsImage, error) {
+	d := &losslessJPEGDecoder{data: data}
+	marker, err := d.marker()
+	if err != nil {
+		return nil, err
+	}
+	if marker != jpegSOI {
+		return nil, fmt.Errorf("missing JPEG start of image marker")
+	}
+
+	var img *jlsImage
+	var preset jlsPreset
+	for {
+		marker, err := d.marker()
+		if err != nil {
+			return nil, err
+		}
+		if marker == jpegEOI {
+			break
+		}
+		segment, err := d.segment()
+		if err != nil {
+			return nil, fmt.Errorf("reading segment of marker %#x: %v", marker, err)
+		}
+
+		switch {
+		case marker == jpegSOF55:
+			if img != nil {
+				return nil, fmt.Errorf("multiple JPEG-LS frame headers")
+			}
+			if err := d.frameHeader(segment); err != nil {
+				return nil, err
+			}
+			img = &jlsImage{
+				precision:    d.img.precision,
+				rows:         d.img.rows,
+				columns:      d.img.columns,
+				componentIDs: d.img.componentIDs,
+				samples:      make([][]int, len(d.img.componentIDs)),
+			}
+		case marker == jpegLSE:
+			if len(segment) != 11 || segment[0] != 1 {
+				return nil, fmt.Errorf("unsupported JPEG-LS preset parameters")
+			}
+			preset = jlsPreset{
+				maxVal: int(binary.BigEndian.Uint16(segment[1:])),
+				t1:     int(binary.BigEndian.Uint16(segment[3:])),
+				t2:     int(binary.BigEndian.Uint16(segment[5:])),
+				t3:     int(binary.BigEndian.Uint16(segment[7:])),
+				reset:  int(binary.BigEndian.Uint16(segment[9:])),
+			}
+		case marker == jpegDRI:
+			if len(segment) != 2 || binary.BigEndian.Uint16(segment) != 0 {
+				return nil, fmt.Errorf("JPEG-LS restart intervals are not supported")
+			}
+		case marker == jpegSOS:
+			if img == nil {
+				return nil, fmt.Errorf("JPEG-LS scan precedes the frame header")
+			}
+			if err := decodeJPEGLSScan(d, img, segment, preset); err != nil {
+				return nil, err
+			}
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC:
+			return nil, fmt.Errorf("unsupported JPEG process of marker %#x, expected JPEG-LS", marker)
+		}
+		// other segments, such as application data and comments, are skipped
+	}
+
+	if img == nil {
+		return nil, fmt.Errorf("JPEG-LS stream holds no image")
+	}
+	for i, samples := range img.samples {
+		if samples == nil {
+			return nil, fmt.Errorf("no scan of component %d", img.componentIDs[i])
+		}
+	}
+	return img, nil
+}
+
+// decodeJPEGLSScan decodes the scan whose header is given and advances d to the marker following
+// the scan
+func decodeJPEGLSScan(d *losslessJPEGDecoder, img *jlsImage, header []byte, preset jlsPreset) error {
+	if len(header) < 1 || header[0] == 0 || len(header) != 4+2*int(header[0]) {
+		return fmt.Errorf("invalid JPEG-LS scan header")
+	}
+	numComponents := int(header[0])
+	near := int(header[1+2*numComponents])
+	mode := JPEGLSInterleaveMode(header[2+2*numComponents])
+	if header[3+2*numComponents] != 0 {
+		return fmt.Errorf("JPEG-LS point transforms are not supported")
+	}
+	if mode < JPEGLSInterleaveNone || mode > JPEGLSInterleaveSample {
+		return fmt.Errorf("invalid interleave mode %d", mode)
+	}
+	if mode == JPEGLSInterleaveNone && numComponents != 1 {
+		return fmt.Errorf("non-interleaved JPEG-LS scan of %d components", numComponents)
+	}
+
+	components := make([]int, numComponents)
+	for i := range components {
+		id := header[1+2*i]
+		if header[2+2*i] != 0 {
+			return fmt.Errorf("JPEG-LS mapping tables are not supported")
+		}
+		components[i] = -1
+		for c, componentID := range img.componentIDs {
+			if componentID == id {
+				components[i] = c
+			}
+		}
+		if components[i] < 0 {
+			return fmt.Errorf("scan of unknown component %d", id)
+		}
+		if img.samples[components[i]] != nil {
+			return fmt.Errorf("multiple scans of component %d", id)
+		}
+		img.samples[components[i]] = make([]int, img.rows*img.columns)
+	}
+
+	params, err := newJLSParams(img.precision, near, preset)
+	if err != nil {
+		return err
+	}
+	r := &jlsBitReader{data: d.data, pos: d.pos}
+	if err := newJLSScan(params, nil, r).code(img, components, mode); err != nil {
+		return err
+	}
+
+	// skip to the marker following the scan, whose second byte has its most significant bit set
+	d.pos = r.pos
+	for d.pos+1 < len(d.data) && !(d.data[d.pos] == 0xFF && d.data[d.pos+1]&0x80 != 0) {
+		d.pos++
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// annexHImage and annexHStream are the example image of ITU-T T.87 H.3 and its lossless encoding
+var (
+	annexHImage = []byte{
+		0, 0, 90, 74,
+		68, 50, 43, 205,
+		64, 145, 145, 145,
+		100, 145, 145, 145,
+	}
+	annexHStream = []byte{
+		0xFF, 0xD8, 0xFF, 0xF7, 0x00, 0x0B, 0x08, 0x00, 0x04, 0x00, 0x04, 0x01, 0x01, 0x11, 0x00, 0xFF,
+		0xDA, 0x00, 0x08, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x6C, 0x80, 0x20, 0x8E,
+		0x01, 0xC0, 0x00, 0x00, 0x57, 0x40, 0x00, 0x00, 0x6E, 0xE6, 0x00, 0x00, 0x01, 0xBC, 0x18, 0x00,
+		0x00, 0x05, 0xD8, 0x00, 0x00, 0x91, 0x60, 0xFF, 0xD9,
+	}
+	annexHInfo = FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 8}
+)
+
+// syntheticFrame returns a native frame with flat regions, gradients and noise
+func syntheticFrame(info FrameInfo) []byte {
+	frame := make([]byte, info.Length())
+	max := 1<<uint(info.bitsStored()) - 1
+	seed := uint32(1)
+	for pixel := 0; pixel < info.Rows*info.Columns; pixel++ {
+		x, y := pixel%info.Columns, pixel/info.Columns
+		for sample := 0; sample < info.SamplesPerPixel; sample++ {
+			seed = seed*1103515245 + 12345
+			var v int
+			switch {
+			case y < info.Rows/4:
+				v = max / (2 + sample)
+			case y < info.Rows/2:
+				v = (x*max/info.Columns + sample*7) % (max + 1)
+			default:
+				v = int(seed>>8) % (max + 1)
+			}
+			info.setSample(frame, pixel, sample, v)
+		}
+	}
+	return frame
+}
+
+func TestJPEGLSCodec_annexH(t *testing.T) {
+	got, err := JPEGLSCodec{}.Decode(annexHStream, annexHInfo)
+	if err != nil {
+		t.Fatalf("Decode(_, %+v) => %v", annexHInfo, err)
+	}
+	if !bytes.Equal(got, annexHImage) {
+		t.Errorf("Decode(_, %+v) => %v, want %v", annexHInfo, got, annexHImage)
+	}
+
+	encoded, err := JPEGLSCodec{}.Encode(annexHImage, annexHInfo)
+	if err != nil {
+		t.Fatalf("Encode(_, %+v) => %v", annexHInfo, err)
+	}
+	if !bytes.Equal(encoded, annexHStream) {
+		t.Errorf("Encode(_, %+v) => %x, want %x", annexHInfo, encoded, annexHStream)
+	}
+}
+
+func TestJPEGLSCodec_presetParameters(t *testing.T) {
+	// default parameters given explicitly in an LSE segment following the frame header
+	lse := []byte{0xFF, jpegLSE, 0x00, 0x0D, 0x01, 0x00, 0xFF, 0x00, 0x03, 0x00, 0x07, 0x00, 0x15, 0x00, 0x40}
+	data := append(append(append([]byte{}, annexHStream[:15]...), lse...), annexHStream[15:]...)
+
+	got, err := JPEGLSCodec{}.Decode(data, annexHInfo)
+	if err != nil {
+		t.Fatalf("Decode(_, %+v) => %v", annexHInfo, err)
+	}
+	if !bytes.Equal(got, annexHImage) {
+		t.Errorf("Decode(_, %+v) => %v, want %v", annexHInfo, got, annexHImage)
+	}
+}
+
+func TestJPEGLSCodec_roundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec JPEGLSCodec
+		info  FrameInfo
+	}{
+		{"2 bit", JPEGLSCodec{}, FrameInfo{Rows: 9, Columns: 11, SamplesPerPixel: 1, BitsAllocated: 8, BitsStored: 2}},
+		{"8 bit", JPEGLSCodec{}, FrameInfo{Rows: 16, Columns: 17, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"12 bit", JPEGLSCodec{}, FrameInfo{Rows: 16, Columns: 13, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12}},
+		{"16 bit", JPEGLSCodec{}, FrameInfo{Rows: 12, Columns: 16, SamplesPerPixel: 1, BitsAllocated: 16}},
+		{"near-lossless 8 bit", JPEGLSCodec{NearLossless: 3}, FrameInfo{Rows: 16, Columns: 17, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"near-lossless 16 bit", JPEGLSCodec{NearLossless: 10}, FrameInfo{Rows: 12, Columns: 16, SamplesPerPixel: 1, BitsAllocated: 16}},
+		{"color non-interleaved", JPEGLSCodec{}, FrameInfo{Rows: 8, Columns: 9, SamplesPerPixel: 3, BitsAllocated: 8}},
+		{"color line interleaved", JPEGLSCodec{InterleaveMode: JPEGLSInterleaveLine}, FrameInfo{Rows: 8, Columns: 9, SamplesPerPixel: 3, BitsAllocated: 8}},
+		{"color sample interleaved", JPEGLSCodec{InterleaveMode: JPEGLSInterleaveSample}, FrameInfo{Rows: 8, Columns: 9, SamplesPerPixel: 3, BitsAllocated: 8}},
+		{"color-by-plane", JPEGLSCodec{InterleaveMode: JPEGLSInterleaveSample}, FrameInfo{Rows: 8, Columns: 9, SamplesPerPixel: 3, BitsAllocated: 16, BitsStored: 10, PlanarConfiguration: 1}},
+		{"near-lossless sample interleaved", JPEGLSCodec{NearLossless: 2, InterleaveMode: JPEGLSInterleaveSample}, FrameInfo{Rows: 8, Columns: 9, SamplesPerPixel: 3, BitsAllocated: 8}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			frame := syntheticFrame(tc.info)
+			data, err := tc.codec.Encode(frame, tc.info)
+			if err != nil {
+				t.Fatalf("Encode(_, %+v) => %v", tc.info, err)
+			}
+			got, err := tc.codec.Decode(data, tc.info)
+			if err != nil {
+				t.Fatalf("Decode(_, %+v) => %v", tc.info, err)
+			}
+
+			for pixel := 0; pixel < tc.info.Rows*tc.info.Columns; pixel++ {
+				for sample := 0; sample < tc.info.SamplesPerPixel; sample++ {
+					want, got := tc.info.sample(frame, pixel, sample), tc.info.sample(got, pixel, sample)
+					if diff := got - want; diff > tc.codec.NearLossless || diff < -tc.codec.NearLossless {
+						t.Fatalf("got sample %d of pixel %d = %d, want %d within %d", sample, pixel, got, want, tc.codec.NearLossless)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestJPEGLSCodec_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		info FrameInfo
+	}{
+		{"not a JPEG stream", []byte{0x00, 0x01}, annexHInfo},
+		{"truncated", annexHStream[:40], annexHInfo},
+		{"dimension mismatch", annexHStream, FrameInfo{Rows: 2, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"samples per pixel mismatch", annexHStream, FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 3, BitsAllocated: 8}},
+		{"unsupported bits allocated", annexHStream, FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 32}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := (JPEGLSCodec{}).Decode(tc.data, tc.info); err == nil {
+				t.Fatalf("Decode(_, %+v) => nil, expected an error", tc.info)
+			}
+		})
+	}
+
+	encodeTests := []struct {
+		name  string
+		codec JPEGLSCodec
+		frame []byte
+		info  FrameInfo
+	}{
+		{"short frame", JPEGLSCodec{}, annexHImage[:3], annexHInfo},
+		{"negative NEAR", JPEGLSCodec{NearLossless: -1}, annexHImage, annexHInfo},
+		{"NEAR too large", JPEGLSCodec{NearLossless: 200}, annexHImage, annexHInfo},
+		{"invalid interleave mode", JPEGLSCodec{InterleaveMode: 3}, annexHImage, annexHInfo},
+		{"1 bit samples", JPEGLSCodec{}, annexHImage, FrameInfo{Rows: 4, Columns: 4, SamplesPerPixel: 1, BitsAllocated: 8, BitsStored: 1}},
+	}
+
+	for _, tc := range encodeTests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.codec.Encode(tc.frame, tc.info); err == nil {
+				t.Fatalf("Encode(_, %+v) => nil, expected an error", tc.info)
+			}
+		})
+	}
+}
+
+func TestEncodeFrames_jpegLS(t *testing.T) {
+	info := FrameInfo{Rows: 10, Columns: 12, SamplesPerPixel: 3, BitsAllocated: 8}
+	frames := [][]byte{syntheticFrame(info), make([]byte, info.Length())}
+	pixelData, err := EncodeFrames(frames, JPEGLSLosslessUID, info)
+	if err != nil {
+		t.Fatalf("EncodeFrames(_, %q, _) => %v", JPEGLSLosslessUID, err)
+	}
+
+	w := &bytes.Buffer{}
+	if err := pixelData.write(w, explicitVRLittleEndian); err != nil {
+		t.Fatalf("writing pixel data: %v", err)
+	}
+	iter := NewEncapsulatedFormatIterator(w, 0)
+	buffer, err := iter.ToBuffer()
+	if err != nil {
+		t.Fatalf("ToBuffer() => %v", err)
+	}
+	if _, err := iter.Next(); err != io.EOF {
+		t.Fatalf("iter.Next() => %v, want %v", err, io.EOF)
+	}
+
+	got, err := DecodeFrames(buffer, JPEGLSLosslessUID, info, len(frames))
+	if err != nil {
+		t.Fatalf("DecodeFrames(_, %q, _, %d) => %v", JPEGLSLosslessUID, len(frames), err)
+	}
+	if !reflect.DeepEqual(got, frames) {
+		t.Fatalf("DecodeFrames(_, %q, _, %d) => %x, want %x", JPEGLSLosslessUID, len(frames), got, frames)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Recovery identifies a deviation from the DICOM file format that was tolerated when parsing with
+// the Lenient option.
+type Recovery int
+
+const (
+	// MissingPreamble indicates the file does not start with the 128 byte preamble. The "DICM"
+	// prefix may or may not be present.
+	MissingPreamble Recovery = iota
+
+	// MissingFileMetaInformation indicates the file has no File Meta Information group (0002,xxxx)
+	// and starts directly with the data set, as is the case for ACR-NEMA files.
+	MissingFileMetaInformation
+
+	// MissingFileMetaInformationGroupLength indicates the File Meta Information group does not start
+	// with the FileMetaInformationGroupLength (0002,0000) element.
+	MissingFileMetaInformationGroupLength
+
+	// GuessedTransferSyntax indicates the file does not specify its transfer syntax. The syntax was
+	// inferred from the encoding of the first data element.
+	GuessedTransferSyntax
+
+	// CorrectedVREncoding indicates the data set declares an explicit VR transfer syntax but is
+	// encoded with implicit VRs, or the reverse. The data set was read with the VR encoding detected
+	// from its first data element. Unlike other recoveries, this does not require the Lenient option.
+	CorrectedVREncoding
+)
+
+func (r Recovery) String() string {
+	switch r {
+	case MissingPreamble:
+		return "missing preamble"
+	case MissingFileMetaInformation:
+		return "missing file meta information"
+	case MissingFileMetaInformationGroupLength:
+		return "missing file meta information group length"
+	case GuessedTransferSyntax:
+		return "guessed transfer syntax"
+	case CorrectedVREncoding:
+		return "corrected VR encoding"
+	default:
+		return fmt.Sprintf("Recovery(%d)", int(r))
+	}
+}
+
+const (
+	preambleLength = 128
+	dicomPrefix    = "DICM"
+)
+
+// lenientHeader is the result of reading the beginning of a potentially non-conformant DICOM file
+type lenientHeader struct {
+	// metaHeaderBytes are the bytes of the File Meta Information elements found in the file
+	metaHeaderBytes []byte
+
+	// syntax is the transfer syntax of the data set following the File Meta Information
+	syntax transferSyntax
+
+	// length is the number of bytes preceding the data set
+	length int64
+
+	recoveries []Recovery
+}
+
+// readLenientHeader reads the preamble, prefix and File Meta Information of a DICOM file, tolerating
+// any of them being absent. Upon return, br is positioned at the first element of the data set.
+func readLenientHeader(br *bufio.Reader, limiter *parseLimiter) (*lenientHeader, error) {
+	header := &lenientHeader{}
+
+	// Peek returns fewer bytes along with an error for files shorter than the preamble, in which
+	// case the preamble is missing.
+	prefix, _ := br.Peek(preambleLength + len(dicomPrefix))
+	switch {
+	case len(prefix) == preambleLength+len(dicomPrefix) && string(prefix[preambleLength:]) == dicomPrefix:
+		header.length = preambleLength + int64(len(dicomPrefix))
+	case len(prefix) >= len(dicomPrefix) && string(prefix[:len(dicomPrefix)]) == dicomPrefix:
+		header.length = int64(len(dicomPrefix))
+		header.recoveries = append(header.recoveries, MissingPreamble)
+	default:
+		header.recoveries = append(header.recoveries, MissingPreamble)
+	}
+	if _, err := br.Discard(int(header.length)); err != nil {
+		return nil, fmt.Errorf("skipping preamble: %v", err)
+	}
+
+	// File Meta Information is always encoded in explicit VR little endian.
+	tag, err := peekTag(br, binary.LittleEndian)
+	if err != nil {
+		return nil, fmt.Errorf("reading first tag: %v", err)
+	}
+	if !tag.IsMetaElement() {
+		header.recoveries = append(header.recoveries, MissingFileMetaInformation)
+		return guessLenientSyntax(br, header)
+	}
+
+	if tag == FileMetaInformationGroupLengthTag {
+		header.metaHeaderBytes, err = bufferMetadataHeader(&dcmReader{cr: &countReader{br, 0}, limiter: limiter})
+	} else {
+		header.recoveries = append(header.recoveries, MissingFileMetaInformationGroupLength)
+		header.metaHeaderBytes, err = bufferMetaGroup(br, limiter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading meta header: %v", err)
+	}
+	header.length += int64(len(header.metaHeaderBytes))
+
+	syntax, err := findSyntax(header.metaHeaderBytes)
+	if err != nil {
+		return guessLenientSyntax(br, header)
+	}
+	header.syntax = syntax
+
+	return header, nil
+}
+
+// bufferMetaGroup returns the bytes of the File Meta Information elements at the start of br,
+// relying on the group number of each element to find the end of the group instead of the
+// FileMetaInformationGroupLength.
+func bufferMetaGroup(br *bufio.Reader, limiter *parseLimiter) ([]byte, error) {
+	buff := &bytes.Buffer{}
+	dr := &dcmReader{cr: &countReader{io.TeeReader(br, buff), 0}, limiter: limiter}
+
+	for {
+		tag, err := peekTag(br, binary.LittleEndian)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !tag.IsMetaElement() {
+			break
+		}
+
+		element, err := readDataElement(dr, explicitVRLittleEndian)
+		if err != nil {
+			return nil, fmt.Errorf("reading meta element: %v", err)
+		}
+		// Streamed value fields must be consumed for their bytes to be captured.
+		if closer, ok := element.ValueField.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				return nil, fmt.Errorf("reading value of meta element %v: %v", element.Tag, err)
+			}
+		}
+	}
+
+	return buff.Bytes(), nil
+}
+
+func guessLenientSyntax(br *bufio.Reader, header *lenientHeader) (*lenientHeader, error) {
+	first, err := br.Peek(tagSize + vrSize)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading first data element: %v", err)
+	}
+	header.syntax = guessSyntax(first)
+	header.recoveries = append(header.recoveries, GuessedTransferSyntax)
+	return header, nil
+}
+
+// guessSyntax returns the transfer syntax most likely to have been used to encode the data element
+// starting with the bytes in b.
+func guessSyntax(b []byte) transferSyntax {
+	if len(b) < tagSize+vrSize {
+		return implicitVRLittleEndian
+	}
+
+	// Data sets start with elements of low group numbers like (0008,xxxx), so the byte order that
+	// produces the smaller group number is most likely to be correct.
+	order := binary.ByteOrder(binary.LittleEndian)
+	if binary.BigEndian.Uint16(b) < binary.LittleEndian.Uint16(b) {
+		order = binary.BigEndian
+	}
+
+	if _, err := lookupVRByName(string(b[tagSize : tagSize+vrSize])); err != nil {
+		// There is no implicit VR big endian transfer syntax.
+		return implicitVRLittleEndian
+	}
+	if order == binary.BigEndian {
+		return explicitVRBigEndian
+	}
+	return explicitVRLittleEndian
+}
+
+// peekTag returns the tag at the start of br without advancing it. io.EOF is returned if br has no
+// remaining bytes.
+func peekTag(br *bufio.Reader, order binary.ByteOrder) (DataElementTag, error) {
+	b, err := br.Peek(tagSize)
+	if len(b) == 0 && err == io.EOF {
+		return 0, io.EOF
+	}
+	if len(b) < tagSize {
+		return 0, fmt.Errorf("reading tag: %v", io.ErrUnexpectedEOF)
+	}
+	return newDcmReader(bytes.NewReader(b)).Tag(order)
+}
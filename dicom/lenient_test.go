@@ -0,0 +1,181 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestParse_lenient(t *testing.T) {
+	signatureLength := 132
+	groupLengthElementLength := 12
+
+	tests := []struct {
+		name           string
+		file           string
+		syntax         transferSyntax
+		transform      func([]byte) []byte
+		want           *DataSet
+		wantRecoveries []Recovery
+	}{
+		{
+			"conformant files do not require recovery",
+			"ExplicitVRLittleEndian.dcm",
+			explicitVRLittleEndian,
+			func(b []byte) []byte { return b },
+			createExpectedDataSet(bufferedPixelData, 198, ExplicitVRLittleEndianUID),
+			nil,
+		},
+		{
+			"files with DICM prefix but no preamble",
+			"ExplicitVRLittleEndian.dcm",
+			explicitVRLittleEndian,
+			func(b []byte) []byte { return b[preambleLength:] },
+			createExpectedDataSet(bufferedPixelData, 198, ExplicitVRLittleEndianUID),
+			[]Recovery{MissingPreamble},
+		},
+		{
+			"files without preamble and DICM prefix",
+			"ImplicitVRLittleEndian.dcm",
+			implicitVRLittleEndian,
+			func(b []byte) []byte { return b[signatureLength:] },
+			createExpectedDataSet(bufferedPixelData, 196, ImplicitVRLittleEndianUID),
+			[]Recovery{MissingPreamble},
+		},
+		{
+			"files with meta group lacking the FileMetaInformationGroupLength",
+			"ExplicitVRBigEndian.dcm",
+			explicitVRBigEndian,
+			func(b []byte) []byte {
+				return append(append([]byte{}, b[:signatureLength]...), b[signatureLength+groupLengthElementLength:]...)
+			},
+			withoutElements(createExpectedDataSet(bufferedPixelData, 198, ExplicitVRBigEndianUID), FileMetaInformationGroupLengthTag),
+			[]Recovery{MissingFileMetaInformationGroupLength},
+		},
+		{
+			"implicit VR little endian files starting directly at the data set",
+			"ImplicitVRLittleEndian.dcm",
+			implicitVRLittleEndian,
+			func(b []byte) []byte { return b[signatureLength+groupLengthElementLength+196:] },
+			withoutMetaElements(createExpectedDataSet(bufferedPixelData, 196, ImplicitVRLittleEndianUID)),
+			[]Recovery{MissingPreamble, MissingFileMetaInformation, GuessedTransferSyntax},
+		},
+		{
+			"explicit VR little endian files starting directly at the data set",
+			"ExplicitVRLittleEndianUndefLen.dcm",
+			explicitVRLittleEndian,
+			func(b []byte) []byte { return b[signatureLength+groupLengthElementLength+198:] },
+			withoutMetaElements(createExpectedDataSet(bufferedPixelData, 198, ExplicitVRLittleEndianUID)),
+			[]Recovery{MissingPreamble, MissingFileMetaInformation, GuessedTransferSyntax},
+		},
+		{
+			"explicit VR big endian files starting directly at the data set",
+			"ExplicitVRBigEndian.dcm",
+			explicitVRBigEndian,
+			func(b []byte) []byte { return b[signatureLength+groupLengthElementLength+198:] },
+			withoutMetaElements(createExpectedDataSet(bufferedPixelData, 198, ExplicitVRBigEndianUID)),
+			[]Recovery{MissingPreamble, MissingFileMetaInformation, GuessedTransferSyntax},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in := tc.transform(mustReadFile(tc.file, t))
+
+			got, err := Parse(bytes.NewReader(in), Lenient)
+			if err != nil {
+				t.Fatalf("Parse(_, Lenient) => %v", err)
+			}
+
+			compareDataSets(got, tc.want, tc.syntax.byteOrder(), t)
+			if !reflect.DeepEqual(got.Recoveries, tc.wantRecoveries) {
+				t.Fatalf("got recoveries %v, want %v", got.Recoveries, tc.wantRecoveries)
+			}
+		})
+	}
+}
+
+func TestParse_nonConformantFilesRejectedWithoutLenient(t *testing.T) {
+	in := mustReadFile("ImplicitVRLittleEndian.dcm", t)[preambleLength:]
+	if _, err := Parse(bytes.NewReader(in)); err == nil {
+		t.Fatalf("expected error to be returned")
+	}
+}
+
+func TestGuessSyntax(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want transferSyntax
+	}{
+		{
+			"explicit VR little endian",
+			[]byte{0x08, 0x00, 0x05, 0x00, 'C', 'S'},
+			explicitVRLittleEndian,
+		},
+		{
+			"explicit VR big endian",
+			[]byte{0x00, 0x08, 0x00, 0x05, 'C', 'S'},
+			explicitVRBigEndian,
+		},
+		{
+			"implicit VR little endian",
+			[]byte{0x08, 0x00, 0x05, 0x00, 0x0A, 0x00},
+			implicitVRLittleEndian,
+		},
+		{
+			"too few bytes defaults to implicit VR little endian",
+			[]byte{0x08, 0x00},
+			implicitVRLittleEndian,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := guessSyntax(tc.in); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func mustReadFile(name string, t *testing.T) []byte {
+	r, err := openFile(name)
+	if err != nil {
+		t.Fatalf("opening file: %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading file: %v", err)
+	}
+	return b
+}
+
+func withoutElements(dataSet *DataSet, tags ...DataElementTag) *DataSet {
+	for _, tag := range tags {
+		delete(dataSet.Elements, tag)
+	}
+	return dataSet
+}
+
+func withoutMetaElements(dataSet *DataSet) *DataSet {
+	for tag := range dataSet.MetaElements().Elements {
+		delete(dataSet.Elements, tag)
+	}
+	return dataSet
+}
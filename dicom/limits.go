@@ -0,0 +1,212 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Limits bounds the resources used to parse untrusted input. A zero value for any field means the
+// corresponding resource is not limited.
+type Limits struct {
+	// MaxValueLength is the maximum length in bytes of a DataElement value that is buffered into
+	// memory. The fragments of encapsulated pixel data are limited in total. Values that are
+	// streamed, such as bulk data referenced with ReferenceBulkData, are not subject to this limit.
+	MaxValueLength int64
+
+	// MaxSequenceDepth is the maximum number of sequences a DataElement can be nested in
+	MaxSequenceDepth int
+
+	// MaxSequenceItems is the maximum number of sequence items in the parsed DataSet, counting
+	// the items of nested sequences
+	MaxSequenceItems int
+
+	// MaxBytes is the maximum number of bytes read from the input. For deflated transfer syntaxes,
+	// the inflated data set is limited to MaxBytes as well.
+	MaxBytes int64
+}
+
+// WithLimits returns an option that makes parsing fail with a *LimitError when the input exceeds
+// any of the given limits.
+func WithLimits(limits Limits) ParseOption {
+	return ParseOption{setting: func(settings *parseSettings) {
+		settings.limits = limits
+	}}
+}
+
+// Limit identifies one of the fields of Limits
+type Limit int
+
+const (
+	// ValueLengthLimit identifies Limits.MaxValueLength
+	ValueLengthLimit Limit = iota
+
+	// SequenceDepthLimit identifies Limits.MaxSequenceDepth
+	SequenceDepthLimit
+
+	// SequenceItemsLimit identifies Limits.MaxSequenceItems
+	SequenceItemsLimit
+
+	// BytesLimit identifies Limits.MaxBytes
+	BytesLimit
+)
+
+func (l Limit) String() string {
+	switch l {
+	case ValueLengthLimit:
+		return "value length"
+	case SequenceDepthLimit:
+		return "sequence depth"
+	case SequenceItemsLimit:
+		return "sequence items"
+	case BytesLimit:
+		return "bytes"
+	default:
+		return fmt.Sprintf("Limit(%d)", int(l))
+	}
+}
+
+// LimitError is returned when parsing exceeds one of the configured Limits
+type LimitError struct {
+	// Limit is the limit that was exceeded
+	Limit Limit
+
+	// Tag is the tag of the DataElement that exceeded the limit. It is zero for limits that are not
+	// attributable to a single DataElement, such as BytesLimit.
+	Tag DataElementTag
+
+	// Max is the configured value of the limit
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	if e.Tag == 0 {
+		return fmt.Sprintf("%v limit of %d exceeded", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("%v limit of %d exceeded by element %v", e.Limit, e.Max, e.Tag)
+}
+
+// parseLimiter enforces the Limits and the cancellation of the context of a single parse. A nil
+// *parseLimiter enforces nothing.
+type parseLimiter struct {
+	ctx    context.Context
+	limits Limits
+	items  int
+
+	// err is the first limit or context error encountered. It is returned by result in place of
+	// the error it caused, which may have been wrapped by the time it reaches the caller.
+	err error
+}
+
+func newParseLimiter(ctx context.Context, limits Limits) *parseLimiter {
+	return &parseLimiter{ctx: ctx, limits: limits}
+}
+
+func (l *parseLimiter) fail(err error) error {
+	if l.err == nil {
+		l.err = err
+	}
+	return err
+}
+
+// result returns the first limit or context error encountered, or err if there was none
+func (l *parseLimiter) result(err error) error {
+	if l != nil && l.err != nil {
+		return l.err
+	}
+	return err
+}
+
+// checkContext returns an error if the context of the parse is done. Once a limit is exceeded, the
+// error is returned as well so that iterators stop reading hostile input, even when closed.
+func (l *parseLimiter) checkContext() error {
+	if l == nil {
+		return nil
+	}
+	if l.err != nil {
+		return l.err
+	}
+	if err := l.ctx.Err(); err != nil {
+		return l.fail(err)
+	}
+	return nil
+}
+
+// checkValueLength returns an error if a value of the given length can't be buffered in memory
+func (l *parseLimiter) checkValueLength(tag DataElementTag, length int64) error {
+	if l == nil {
+		return nil
+	}
+	if l.limits.MaxValueLength > 0 && length > l.limits.MaxValueLength {
+		return l.fail(&LimitError{Limit: ValueLengthLimit, Tag: tag, Max: l.limits.MaxValueLength})
+	}
+	// A value longer than the whole input can't be read, so it is rejected before any memory is
+	// allocated for it.
+	if l.limits.MaxBytes > 0 && length > l.limits.MaxBytes {
+		return l.fail(&LimitError{Limit: BytesLimit, Tag: tag, Max: l.limits.MaxBytes})
+	}
+	return nil
+}
+
+// checkSequenceItem returns an error if reading another item of the sequence with the given tag
+// and nesting depth would exceed the limits
+func (l *parseLimiter) checkSequenceItem(tag DataElementTag, depth int) error {
+	if l == nil {
+		return nil
+	}
+	if l.limits.MaxSequenceDepth > 0 && depth > l.limits.MaxSequenceDepth {
+		return l.fail(&LimitError{Limit: SequenceDepthLimit, Tag: tag, Max: int64(l.limits.MaxSequenceDepth)})
+	}
+	l.items++
+	if l.limits.MaxSequenceItems > 0 && l.items > l.limits.MaxSequenceItems {
+		return l.fail(&LimitError{Limit: SequenceItemsLimit, Tag: tag, Max: int64(l.limits.MaxSequenceItems)})
+	}
+	return l.checkContext()
+}
+
+// reader returns an io.Reader that reads from r and fails once more than Limits.MaxBytes bytes are
+// read from it
+func (l *parseLimiter) reader(r io.Reader) io.Reader {
+	if l == nil || l.limits.MaxBytes <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, limiter: l}
+}
+
+type limitedReader struct {
+	r         io.Reader
+	limiter   *parseLimiter
+	bytesRead int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	max := lr.limiter.limits.MaxBytes
+	if lr.bytesRead > max {
+		return 0, lr.limiter.fail(&LimitError{Limit: BytesLimit, Max: max})
+	}
+	// Reading one byte past the limit distinguishes inputs of exactly MaxBytes bytes, which end
+	// with io.EOF, from longer inputs.
+	if remaining := max - lr.bytesRead + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := lr.r.Read(p)
+	lr.bytesRead += int64(n)
+	if lr.bytesRead > max {
+		return n - 1, lr.limiter.fail(&LimitError{Limit: BytesLimit, Max: max})
+	}
+	return n, err
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseWithContext_limits(t *testing.T) {
+	// An implicit VR little endian file whose data set claims a PatientName of almost 4 GB.
+	hostile := append(mustReadFile("ImplicitVRLittleEndian.dcm", t)[:132+12+196],
+		0x10, 0x00, 0x10, 0x00, // PatientName Tag
+		0xF0, 0xFF, 0xFF, 0xFF, // Length
+		'J', 'o', 'e',
+	)
+	explicit := mustReadFile("ExplicitVRLittleEndian.dcm", t)
+
+	tests := []struct {
+		name   string
+		in     []byte
+		limits Limits
+		want   error
+	}{
+		{
+			"buffered values longer than the maximum value length",
+			hostile,
+			Limits{MaxValueLength: 1024},
+			&LimitError{ValueLengthLimit, PatientNameTag, 1024},
+		},
+		{
+			"buffered values longer than the maximum number of bytes",
+			hostile,
+			Limits{MaxBytes: 1024},
+			&LimitError{BytesLimit, PatientNameTag, 1024},
+		},
+		{
+			"sequences nested deeper than the maximum depth",
+			explicit,
+			Limits{MaxSequenceDepth: 1},
+			&LimitError{SequenceDepthLimit, ReferencedImageSequenceTag, 1},
+		},
+		{
+			"more sequence items than the maximum",
+			explicit,
+			Limits{MaxSequenceItems: 1},
+			&LimitError{SequenceItemsLimit, ReferencedImageSequenceTag, 1},
+		},
+		{
+			"inputs longer than the maximum number of bytes",
+			explicit,
+			Limits{MaxBytes: int64(len(explicit)) - 1},
+			&LimitError{BytesLimit, 0, int64(len(explicit)) - 1},
+		},
+		{
+			"inputs within all limits",
+			explicit,
+			Limits{MaxValueLength: 198, MaxSequenceDepth: 2, MaxSequenceItems: 2, MaxBytes: int64(len(explicit))},
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseWithContext(context.Background(), bytes.NewReader(tc.in), WithLimits(tc.limits))
+			if !reflect.DeepEqual(err, tc.want) {
+				t.Fatalf("ParseWithContext(_, _, WithLimits(%+v)) => %v, want %v", tc.limits, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDataSet_encapsulatedPixelDataLimits(t *testing.T) {
+	encapsulated := func(fragmentLengths ...int) []byte {
+		in := []byte{0xE0, 0x7F, 0x10, 0x00, 'O', 'B', 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF}
+		in = append(in, 0xFE, 0xFF, 0x00, 0xE0, 0x00, 0x00, 0x00, 0x00) // Basic Offset Table
+		for _, length := range fragmentLengths {
+			in = append(in, 0xFE, 0xFF, 0x00, 0xE0, byte(length), byte(length>>8), 0x00, 0x00)
+			in = append(in, make([]byte, length)...)
+		}
+		return append(in, 0xFE, 0xFF, 0xDD, 0xE0, 0x00, 0x00, 0x00, 0x00)
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{
+			"fragment longer than the maximum value length",
+			encapsulated(1000),
+			&LimitError{ValueLengthLimit, PixelDataTag, 100},
+		},
+		{
+			"fragments longer than the maximum value length in total",
+			encapsulated(60, 60),
+			&LimitError{ValueLengthLimit, PixelDataTag, 100},
+		},
+		{
+			"fragments within the maximum value length",
+			encapsulated(40, 60),
+			nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseDataSet(bytes.NewReader(tc.in), JPEGBaselineUID, WithLimits(Limits{MaxValueLength: 100}))
+			if !reflect.DeepEqual(err, tc.want) {
+				t.Fatalf("ParseDataSet(_, _, _) => %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseWithContext_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ParseWithContext(ctx, bytes.NewReader(mustReadFile("ExplicitVRLittleEndian.dcm", t)))
+	if err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestParseDataSet_limits(t *testing.T) {
+	in := []byte{
+		0x08, 0x00, 0x60, 0x00, // Modality Tag
+		0x02, 0x00, 0x00, 0x00, // Length
+		'M', 'R',
+	}
+
+	_, err := ParseDataSet(bytes.NewReader(in), ImplicitVRLittleEndianUID, WithLimits(Limits{MaxValueLength: 1}))
+	if want := (&LimitError{ValueLengthLimit, ModalityTag, 1}); !reflect.DeepEqual(err, want) {
+		t.Fatalf("got error %v, want %v", err, want)
+	}
+}
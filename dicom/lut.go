@@ -0,0 +1,148 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"fmt"
+)
+
+// LUT is a lookup table given by an LUT Descriptor and LUT Data, such as those of the Modality LUT
+// and VOI LUT modules. See the DICOM standard part3 linked below.
+// http://dicom.nema.org/medical/dicom/current/output/html/part03.html#sect_C.11.1.1
+type LUT struct {
+	// FirstMapped is the input value mapped to the first entry
+	FirstMapped int
+
+	// Bits is the number of bits of each entry
+	Bits int
+
+	// Data holds the entries of the LUT
+	Data []int
+}
+
+// Lookup returns the entry of the LUT for the input value v. Values below FirstMapped map to the
+// first entry and values past the last entry map to the last entry.
+func (l *LUT) Lookup(v int) int {
+	i := v - l.FirstMapped
+	if i < 0 {
+		i = 0
+	} else if i >= len(l.Data) {
+		i = len(l.Data) - 1
+	}
+	return l.Data[i]
+}
+
+// newLUT returns the LUT of the item given by its LUT Descriptor and LUT Data elements. The first
+// mapped value of the descriptor is interpreted as a signed value if signed is true, regardless of
+// the VR of the descriptor which is ambiguous in implicit VR transfer syntaxes.
+func newLUT(item *DataSet, descriptorTag, dataTag DataElementTag, signed bool) (*LUT, error) {
+	lut, entries, err := readLUTDescriptor(item, descriptorTag, signed)
+	if err != nil {
+		return nil, err
+	}
+	words, err := readLUTWords(item, dataTag)
+	if err != nil {
+		return nil, err
+	}
+	if err := lut.setData(words, entries); err != nil {
+		return nil, fmt.Errorf("reading LUT data %v: %v", dataTag, err)
+	}
+	return lut, nil
+}
+
+// readLUTDescriptor returns the LUT described by the LUT Descriptor of the item, without data, and
+// its number of entries
+func readLUTDescriptor(item *DataSet, descriptorTag DataElementTag, signed bool) (*LUT, int, error) {
+	descriptor, ok := item.Elements[descriptorTag]
+	if !ok {
+		return nil, 0, fmt.Errorf("missing LUT descriptor %v", descriptorTag)
+	}
+	values, err := lutWords(descriptor)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading LUT descriptor %v: %v", descriptorTag, err)
+	}
+	if len(values) != 3 {
+		return nil, 0, fmt.Errorf("got %d values of LUT descriptor %v, expected 3", len(values), descriptorTag)
+	}
+	// 0 entries means 2^16 entries
+	entries := int(values[0])
+	if entries == 0 {
+		entries = 1 << 16
+	}
+	firstMapped := int(values[1])
+	if signed {
+		firstMapped = int(int16(values[1]))
+	}
+	bits := int(values[2])
+	if bits < 1 || bits > 16 {
+		return nil, 0, fmt.Errorf("invalid number of bits %d of LUT descriptor %v", bits, descriptorTag)
+	}
+	return &LUT{FirstMapped: firstMapped, Bits: bits}, entries, nil
+}
+
+// readLUTWords returns the words of the LUT data element of the item
+func readLUTWords(item *DataSet, dataTag DataElementTag) ([]uint16, error) {
+	element, ok := item.Elements[dataTag]
+	if !ok {
+		return nil, fmt.Errorf("missing LUT data %v", dataTag)
+	}
+	words, err := lutWords(element)
+	if err != nil {
+		return nil, fmt.Errorf("reading LUT data %v: %v", dataTag, err)
+	}
+	return words, nil
+}
+
+// setData sets the entries of the LUT from the words of its LUT data. 8 bit entries may be packed
+// two per word or be stored one per word, in the low byte or, as some implementations do, in the
+// high byte.
+func (l *LUT) setData(words []uint16, entries int) error {
+	l.Data = make([]int, 0, entries)
+	switch {
+	case len(words) >= entries:
+		shift := uint(0)
+		if l.Bits == 8 {
+			for _, w := range words[:entries] {
+				if w > 0xFF {
+					shift = 8
+					break
+				}
+			}
+		}
+		for _, w := range words[:entries] {
+			l.Data = append(l.Data, int(w>>shift)&(1<<uint(l.Bits)-1))
+		}
+	case l.Bits == 8 && len(words) == (entries+1)/2:
+		// 8 bit entries packed two per word, the first entry in the low byte
+		for i := 0; i < entries; i++ {
+			l.Data = append(l.Data, int(words[i/2]>>(8*uint(i%2))&0xFF))
+		}
+	default:
+		return fmt.Errorf("got %d words, expected %d entries", len(words), entries)
+	}
+	return nil
+}
+
+// lutWords returns the values of a US, SS or OW DataElement as 16-bit words
+func lutWords(element *DataElement) ([]uint16, error) {
+	if v, ok := element.ValueField.([]int16); ok {
+		words := make([]uint16, 0, len(v))
+		for _, w := range v {
+			words = append(words, uint16(w))
+		}
+		return words, nil
+	}
+	return element.Uint16Values()
+}
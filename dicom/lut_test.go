@@ -0,0 +1,150 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"reflect"
+	"testing"
+)
+
+func lutItem(descriptor, data interface{}) *DataSet {
+	return NewDataSet(map[DataElementTag]interface{}{
+		LUTDescriptorTag: descriptor,
+		LUTDataTag:       data,
+	})
+}
+
+func TestNewLUT(t *testing.T) {
+	tests := []struct {
+		name   string
+		item   *DataSet
+		signed bool
+		want   *LUT
+	}{
+		{
+			"US data",
+			lutItem([]uint16{3, 10, 12}, []uint16{0, 0x800, 0xFFF}),
+			false,
+			&LUT{FirstMapped: 10, Bits: 12, Data: []int{0, 0x800, 0xFFF}},
+		},
+		{
+			"OW data",
+			lutItem([]uint16{2, 0, 16}, NewBulkDataBuffer([]byte{0x01, 0x02, 0x03, 0x04})),
+			false,
+			&LUT{FirstMapped: 0, Bits: 16, Data: []int{0x0201, 0x0403}},
+		},
+		{
+			"signed first mapped value",
+			lutItem([]uint16{2, 0xFC00, 8}, []uint16{1, 2}),
+			true,
+			&LUT{FirstMapped: -1024, Bits: 8, Data: []int{1, 2}},
+		},
+		{
+			"SS descriptor",
+			lutItem([]int16{2, -1024, 8}, []uint16{1, 2}),
+			true,
+			&LUT{FirstMapped: -1024, Bits: 8, Data: []int{1, 2}},
+		},
+		{
+			"unsigned first mapped value",
+			lutItem([]uint16{2, 0xFC00, 8}, []uint16{1, 2}),
+			false,
+			&LUT{FirstMapped: 0xFC00, Bits: 8, Data: []int{1, 2}},
+		},
+		{
+			"packed 8 bit entries",
+			lutItem([]uint16{3, 0, 8}, []uint16{0x0201, 0x0003}),
+			false,
+			&LUT{FirstMapped: 0, Bits: 8, Data: []int{1, 2, 3}},
+		},
+		{
+			"entries are masked to the number of bits",
+			lutItem([]uint16{1, 0, 12}, []uint16{0x1234}),
+			false,
+			&LUT{FirstMapped: 0, Bits: 12, Data: []int{0x234}},
+		},
+		{
+			"8 bit entries in the high byte",
+			lutItem([]uint16{2, 0, 8}, []uint16{0x0000, 0xFF00}),
+			false,
+			&LUT{FirstMapped: 0, Bits: 8, Data: []int{0x00, 0xFF}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := newLUT(tc.item, LUTDescriptorTag, LUTDataTag, tc.signed)
+			if err != nil {
+				t.Fatalf("newLUT(_, _, _, %v) => %v", tc.signed, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("newLUT(_, _, _, %v) => %+v, want %+v", tc.signed, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewLUT_entries(t *testing.T) {
+	// 0 entries means 2^16 entries
+	got, err := newLUT(lutItem([]uint16{0, 0, 16}, make([]uint16, 1<<16)), LUTDescriptorTag, LUTDataTag, false)
+	if err != nil {
+		t.Fatalf("newLUT(_) => %v", err)
+	}
+	if len(got.Data) != 1<<16 {
+		t.Fatalf("got %d entries, want %d", len(got.Data), 1<<16)
+	}
+}
+
+func TestNewLUT_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		item *DataSet
+	}{
+		{"missing descriptor", NewDataSet(map[DataElementTag]interface{}{LUTDataTag: []uint16{1}})},
+		{"missing data", NewDataSet(map[DataElementTag]interface{}{LUTDescriptorTag: []uint16{1, 0, 8}})},
+		{"descriptor with 2 values", lutItem([]uint16{1, 0}, []uint16{1})},
+		{"invalid bits", lutItem([]uint16{1, 0, 17}, []uint16{1})},
+		{"too few entries", lutItem([]uint16{3, 0, 16}, []uint16{1, 2})},
+		{"unexpected data type", lutItem([]uint16{1, 0, 8}, []string{"1"})},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newLUT(tc.item, LUTDescriptorTag, LUTDataTag, false); err == nil {
+				t.Fatalf("newLUT(_) => nil, expected an error")
+			}
+		})
+	}
+}
+
+func TestLUT_Lookup(t *testing.T) {
+	lut := &LUT{FirstMapped: -1, Bits: 8, Data: []int{10, 20, 30}}
+	tests := []struct {
+		in   int
+		want int
+	}{
+		{-5, 10},
+		{-1, 10},
+		{0, 20},
+		{1, 30},
+		{100, 30},
+	}
+
+	for _, tc := range tests {
+		if got := lut.Lookup(tc.in); got != tc.want {
+			t.Errorf("Lookup(%d) => %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
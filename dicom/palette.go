@@ -0,0 +1,187 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"fmt"
+	"image"
+)
+
+// PhotometricPaletteColor is a single sample per pixel which is an index into the red, green and
+// blue LUTs of a PaletteColorLUT
+const PhotometricPaletteColor PhotometricInterpretation = "PALETTE COLOR"
+
+// segment opcodes of segmented palette color LUTs
+const (
+	discreteSegment = 0
+	linearSegment   = 1
+	indirectSegment = 2
+)
+
+// PaletteColorLUT is the Palette Color Lookup Table of PALETTE COLOR pixel data, see the DICOM
+// standard part3 linked below.
+// http://dicom.nema.org/medical/dicom/current/output/html/part03.html#sect_C.7.6.3.1.5
+type PaletteColorLUT struct {
+	Red   *LUT
+	Green *LUT
+	Blue  *LUT
+}
+
+// NewPaletteColorLUT returns the PaletteColorLUT of the DataSet, read from the red, green and blue
+// Palette Color Lookup Table Descriptor elements and either the Palette Color Lookup Table Data or
+// the Segmented Palette Color Lookup Table Data elements. The first mapped value of the
+// descriptors is signed if the PixelRepresentation of the DataSet is 1.
+func NewPaletteColorLUT(ds *DataSet) (*PaletteColorLUT, error) {
+	signed := false
+	if element, ok := ds.Elements[PixelRepresentationTag]; ok {
+		v, err := element.IntValue()
+		if err != nil {
+			return nil, fmt.Errorf("reading PixelRepresentation: %v", err)
+		}
+		signed = v == 1
+	}
+
+	palette := &PaletteColorLUT{}
+	for _, c := range []struct {
+		lut                                  **LUT
+		descriptorTag, dataTag, segmentedTag DataElementTag
+	}{
+		{&palette.Red, RedPaletteColorLookupTableDescriptorTag, RedPaletteColorLookupTableDataTag, SegmentedRedPaletteColorLookupTableDataTag},
+		{&palette.Green, GreenPaletteColorLookupTableDescriptorTag, GreenPaletteColorLookupTableDataTag, SegmentedGreenPaletteColorLookupTableDataTag},
+		{&palette.Blue, BluePaletteColorLookupTableDescriptorTag, BluePaletteColorLookupTableDataTag, SegmentedBluePaletteColorLookupTableDataTag},
+	} {
+		if _, ok := ds.Elements[c.segmentedTag]; !ok {
+			lut, err := newLUT(ds, c.descriptorTag, c.dataTag, signed)
+			if err != nil {
+				return nil, err
+			}
+			*c.lut = lut
+			continue
+		}
+
+		lut, entries, err := readLUTDescriptor(ds, c.descriptorTag, signed)
+		if err != nil {
+			return nil, err
+		}
+		words, err := readLUTWords(ds, c.segmentedTag)
+		if err != nil {
+			return nil, err
+		}
+		expanded, err := expandSegments(nil, words, true)
+		if err != nil {
+			return nil, fmt.Errorf("expanding %v: %v", c.segmentedTag, err)
+		}
+		if err := lut.setData(expanded, entries); err != nil {
+			return nil, fmt.Errorf("reading LUT data %v: %v", c.segmentedTag, err)
+		}
+		*c.lut = lut
+	}
+	return palette, nil
+}
+
+// expandSegments appends the entries of the segments of segmented palette color LUT data to
+// entries, see the DICOM standard part3 linked below. Indirect segments are only allowed if
+// indirect is true.
+// http://dicom.nema.org/medical/dicom/current/output/html/part03.html#sect_C.7.9.2
+func expandSegments(entries, words []uint16, indirect bool) ([]uint16, error) {
+	for i := 0; i < len(words); {
+		if i+1 >= len(words) {
+			return nil, fmt.Errorf("truncated segment at word %d", i)
+		}
+		opcode, length := words[i], int(words[i+1])
+		switch opcode {
+		case discreteSegment:
+			if i+2+length > len(words) {
+				return nil, fmt.Errorf("truncated discrete segment at word %d", i)
+			}
+			entries = append(entries, words[i+2:i+2+length]...)
+			i += 2 + length
+		case linearSegment:
+			if i+2 >= len(words) {
+				return nil, fmt.Errorf("truncated linear segment at word %d", i)
+			}
+			if len(entries) == 0 {
+				return nil, fmt.Errorf("linear segment at word %d does not follow a segment", i)
+			}
+			// the segment interpolates from the last entry of the previous segment
+			y0, y1 := float64(entries[len(entries)-1]), float64(words[i+2])
+			for j := 1; j <= length; j++ {
+				entries = append(entries, uint16(y0+(y1-y0)*float64(j)/float64(length)+0.5))
+			}
+			i += 3
+		case indirectSegment:
+			if !indirect {
+				return nil, fmt.Errorf("nested indirect segment at word %d", i)
+			}
+			if i+3 >= len(words) {
+				return nil, fmt.Errorf("truncated indirect segment at word %d", i)
+			}
+			// the byte offset of the first copied segment, least significant word first
+			offset := (int(words[i+3])<<16 | int(words[i+2])) / 2
+			end, err := segmentsEnd(words, offset, length)
+			if err != nil {
+				return nil, fmt.Errorf("indirect segment at word %d: %v", i, err)
+			}
+			if entries, err = expandSegments(entries, words[offset:end], false); err != nil {
+				return nil, fmt.Errorf("indirect segment at word %d: %v", i, err)
+			}
+			i += 4
+		default:
+			return nil, fmt.Errorf("unknown segment opcode %d at word %d", opcode, i)
+		}
+	}
+	return entries, nil
+}
+
+// segmentsEnd returns the index of the word following the n segments starting at word start
+func segmentsEnd(words []uint16, start, n int) (int, error) {
+	i := start
+	for ; n > 0; n-- {
+		if i+1 >= len(words) {
+			return 0, fmt.Errorf("segments past the end of the LUT data")
+		}
+		switch words[i] {
+		case discreteSegment:
+			i += 2 + int(words[i+1])
+		case linearSegment:
+			i += 3
+		case indirectSegment:
+			i += 4
+		default:
+			return 0, fmt.Errorf("unknown segment opcode %d at word %d", words[i], i)
+		}
+	}
+	if i > len(words) {
+		return 0, fmt.Errorf("segments past the end of the LUT data")
+	}
+	return i, nil
+}
+
+// Image returns the PALETTE COLOR frame as an *image.RGBA, with the red, green and blue entries
+// scaled to 8 bits
+func (p *PaletteColorLUT) Image(frame *Frame) (*image.RGBA, error) {
+	info := frame.Info
+	if info.SamplesPerPixel != 1 {
+		return nil, fmt.Errorf("got %d samples per pixel, %v expects 1", info.SamplesPerPixel, PhotometricPaletteColor)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, info.Columns, info.Rows))
+	for pixel, v := range frame.Samples {
+		for c, lut := range []*LUT{p.Red, p.Green, p.Blue} {
+			img.Pix[4*pixel+c] = byte(lut.Lookup(v) * 0xFF / (1<<uint(lut.Bits) - 1))
+		}
+		img.Pix[4*pixel+3] = 0xFF
+	}
+	return img, nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestExpandSegments(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []uint16
+		want  []uint16
+	}{
+		{
+			"discrete",
+			[]uint16{0, 3, 10, 20, 30},
+			[]uint16{10, 20, 30},
+		},
+		{
+			"linear segments interpolate from the previous entry",
+			[]uint16{0, 1, 10, 1, 2, 50, 1, 3, 20},
+			[]uint16{10, 30, 50, 40, 30, 20},
+		},
+		{
+			"indirect segments copy segments at a byte offset",
+			[]uint16{0, 2, 0, 100, 1, 4, 200, 2, 2, 0, 0},
+			[]uint16{0, 100, 125, 150, 175, 200, 0, 100, 125, 150, 175, 200},
+		},
+		{
+			"copied linear segments interpolate from the entry before the indirect segment",
+			[]uint16{0, 1, 0, 1, 2, 100, 0, 1, 200, 2, 1, 6, 0},
+			[]uint16{0, 50, 100, 200, 150, 100},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := expandSegments(nil, tc.words, true)
+			if err != nil {
+				t.Fatalf("expandSegments(_, %v, true) => %v", tc.words, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expandSegments(_, %v, true) => %v, want %v", tc.words, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpandSegments_invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []uint16
+	}{
+		{"unknown opcode", []uint16{3, 1, 0}},
+		{"truncated discrete segment", []uint16{0, 3, 10, 20}},
+		{"truncated linear segment", []uint16{0, 1, 10, 1, 2}},
+		{"linear segment first", []uint16{1, 2, 50}},
+		{"truncated indirect segment", []uint16{0, 1, 10, 2, 1, 0}},
+		{"indirect segment past the end", []uint16{0, 1, 10, 2, 1, 100, 0}},
+		{"nested indirect segment", []uint16{0, 1, 10, 2, 1, 6, 0, 2, 1, 0, 0}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := expandSegments(nil, tc.words, true); err == nil {
+				t.Fatalf("expandSegments(_, %v, true) => nil, expected an error", tc.words)
+			}
+		})
+	}
+}
+
+func TestNewPaletteColorLUT(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements map[DataElementTag]interface{}
+		want     *PaletteColorLUT
+	}{
+		{
+			"16 bit entries",
+			map[DataElementTag]interface{}{
+				RedPaletteColorLookupTableDescriptorTag:   []uint16{2, 0, 16},
+				GreenPaletteColorLookupTableDescriptorTag: []uint16{2, 0, 16},
+				BluePaletteColorLookupTableDescriptorTag:  []uint16{2, 0, 16},
+				RedPaletteColorLookupTableDataTag:         []uint16{0xFFFF, 0},
+				GreenPaletteColorLookupTableDataTag:       []uint16{0, 0xFFFF},
+				BluePaletteColorLookupTableDataTag:        []uint16{0x8000, 0x8000},
+			},
+			&PaletteColorLUT{
+				Red:   &LUT{FirstMapped: 0, Bits: 16, Data: []int{0xFFFF, 0}},
+				Green: &LUT{FirstMapped: 0, Bits: 16, Data: []int{0, 0xFFFF}},
+				Blue:  &LUT{FirstMapped: 0, Bits: 16, Data: []int{0x8000, 0x8000}},
+			},
+		},
+		{
+			"8 bit entries with signed first mapped value",
+			map[DataElementTag]interface{}{
+				PixelRepresentationTag:                    []uint16{1},
+				RedPaletteColorLookupTableDescriptorTag:   []int16{2, -1, 8},
+				GreenPaletteColorLookupTableDescriptorTag: []int16{2, -1, 8},
+				BluePaletteColorLookupTableDescriptorTag:  []int16{2, -1, 8},
+				RedPaletteColorLookupTableDataTag:         []uint16{0xFF00, 0x0000},
+				GreenPaletteColorLookupTableDataTag:       []uint16{0x00FF},
+				BluePaletteColorLookupTableDataTag:        []uint16{0x0010, 0x0020},
+			},
+			&PaletteColorLUT{
+				Red:   &LUT{FirstMapped: -1, Bits: 8, Data: []int{0xFF, 0}},
+				Green: &LUT{FirstMapped: -1, Bits: 8, Data: []int{0xFF, 0}},
+				Blue:  &LUT{FirstMapped: -1, Bits: 8, Data: []int{0x10, 0x20}},
+			},
+		},
+		{
+			"segmented",
+			map[DataElementTag]interface{}{
+				RedPaletteColorLookupTableDescriptorTag:      []uint16{3, 0, 16},
+				GreenPaletteColorLookupTableDescriptorTag:    []uint16{3, 0, 16},
+				BluePaletteColorLookupTableDescriptorTag:     []uint16{3, 0, 16},
+				SegmentedRedPaletteColorLookupTableDataTag:   []uint16{0, 1, 0, 1, 2, 0xFFFF},
+				SegmentedGreenPaletteColorLookupTableDataTag: []uint16{0, 3, 1, 2, 3},
+				SegmentedBluePaletteColorLookupTableDataTag:  []uint16{0, 1, 7, 2, 1, 0, 0, 2, 1, 0, 0},
+			},
+			&PaletteColorLUT{
+				Red:   &LUT{FirstMapped: 0, Bits: 16, Data: []int{0, 0x8000, 0xFFFF}},
+				Green: &LUT{FirstMapped: 0, Bits: 16, Data: []int{1, 2, 3}},
+				Blue:  &LUT{FirstMapped: 0, Bits: 16, Data: []int{7, 7, 7}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewPaletteColorLUT(NewDataSet(tc.elements))
+			if err != nil {
+				t.Fatalf("NewPaletteColorLUT(_) => %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("NewPaletteColorLUT(_) => %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewPaletteColorLUT_invalid(t *testing.T) {
+	descriptors := map[DataElementTag]interface{}{
+		RedPaletteColorLookupTableDescriptorTag:   []uint16{2, 0, 8},
+		GreenPaletteColorLookupTableDescriptorTag: []uint16{2, 0, 8},
+		BluePaletteColorLookupTableDescriptorTag:  []uint16{2, 0, 8},
+	}
+
+	tests := []struct {
+		name     string
+		elements map[DataElementTag]interface{}
+	}{
+		{
+			"missing data",
+			map[DataElementTag]interface{}{
+				RedPaletteColorLookupTableDataTag:   []uint16{1, 2},
+				GreenPaletteColorLookupTableDataTag: []uint16{1, 2},
+			},
+		},
+		{
+			"too few entries",
+			map[DataElementTag]interface{}{
+				RedPaletteColorLookupTableDataTag:   []uint16{1, 2},
+				GreenPaletteColorLookupTableDataTag: []uint16{1, 2},
+				BluePaletteColorLookupTableDataTag:  []uint16{},
+			},
+		},
+		{
+			"too few segmented entries",
+			map[DataElementTag]interface{}{
+				RedPaletteColorLookupTableDataTag:           []uint16{1, 2},
+				GreenPaletteColorLookupTableDataTag:         []uint16{1, 2},
+				SegmentedBluePaletteColorLookupTableDataTag: []uint16{},
+			},
+		},
+		{
+			"invalid segment",
+			map[DataElementTag]interface{}{
+				RedPaletteColorLookupTableDataTag:           []uint16{1, 2},
+				GreenPaletteColorLookupTableDataTag:         []uint16{1, 2},
+				SegmentedBluePaletteColorLookupTableDataTag: []uint16{5, 1, 7},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			elements := map[DataElementTag]interface{}{}
+			for tag, v := range descriptors {
+				elements[tag] = v
+			}
+			for tag, v := range tc.elements {
+				elements[tag] = v
+			}
+			if _, err := NewPaletteColorLUT(NewDataSet(elements)); err == nil {
+				t.Fatalf("NewPaletteColorLUT(_) => nil, expected an error")
+			}
+		})
+	}
+}
+
+func TestPixelData_Image_paletteColor(t *testing.T) {
+	ds := NewDataSet(map[DataElementTag]interface{}{
+		RowsTag:                                 []uint16{1},
+		ColumnsTag:                              []uint16{3},
+		BitsAllocatedTag:                        []uint16{8},
+		PhotometricInterpretationTag:            []string{"PALETTE COLOR "},
+		RedPaletteColorLookupTableDescriptorTag: []uint16{2, 1, 16},
+		GreenPaletteColorLookupTableDescriptorTag: []uint16{2, 1, 16},
+		BluePaletteColorLookupTableDescriptorTag:  []uint16{2, 1, 16},
+		RedPaletteColorLookupTableDataTag:         []uint16{0xFFFF, 0},
+		GreenPaletteColorLookupTableDataTag:       []uint16{0, 0xFFFF},
+		BluePaletteColorLookupTableDataTag:        []uint16{0x8080, 0x4040},
+		PixelDataTag:                              NewBulkDataBuffer([]byte{0, 1, 2}),
+	})
+	pixelData, err := NewPixelData(ds)
+	if err != nil {
+		t.Fatalf("NewPixelData(_) => %v", err)
+	}
+	got, err := pixelData.Image(0)
+	if err != nil {
+		t.Fatalf("Image(0) => %v", err)
+	}
+
+	// values below the first mapped value map to the first entry
+	want := &image.RGBA{
+		Pix:    []byte{0xFF, 0x00, 0x80, 0xFF, 0xFF, 0x00, 0x80, 0xFF, 0x00, 0xFF, 0x40, 0xFF},
+		Stride: 12,
+		Rect:   image.Rect(0, 0, 3, 1),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Image(0) => %v, want %v", got, want)
+	}
+}
@@ -16,10 +16,12 @@ package dicom
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"strings"
 	"unicode"
 )
@@ -30,31 +32,161 @@ import (
 // By default, BulkDataIterators are transformed into their appropriate buffered types for the VR:
 // BulkDataBuffer for OW, OB, UN
 // []uint32 for OL
+// []uint64 for OV
 // []float64 for OD
 // []float32 for OF
 // []string for UR, UT, UC
 // This behaviour can be overridden by supplying a ParseOption that transforms DataElements with
 // ValueField of type BulkDataIterator to a ValueField other than BulkDataIterator.
 func Parse(r io.Reader, opts ...ParseOption) (*DataSet, error) {
-	iter, err := NewDataElementIterator(r)
+	dataSet, _, err := parseFile(context.Background(), r, opts...)
+	return dataSet, err
+}
+
+// ParseWithContext parses a DICOM file like Parse. Parsing stops with the error of ctx if ctx is
+// done before all DataElements are read. When parsing untrusted input, the WithLimits option
+// should be given to bound the memory and time spent on hostile files; exceeding a limit stops
+// parsing with a *LimitError.
+func ParseWithContext(ctx context.Context, r io.Reader, opts ...ParseOption) (*DataSet, error) {
+	dataSet, _, err := parseFile(ctx, r, opts...)
+	return dataSet, err
+}
+
+// ParseDataSet parses a data set encoded in the transfer syntax identified by syntaxUID, returning
+// the DataSet defined by applying options sequentially in the order given to DataElements in the
+// data set. r must not contain the preamble, DICOM signature or File Meta Information of a DICOM
+// file. See NewDataSetIterator for details. An error is returned if syntaxUID is not known to
+// LookupTransferSyntax, unless the Lenient option is given, in which case the data set is read as
+// explicit VR little endian and the unknown transfer syntax is reported in the Diagnostics of the
+// returned DataSet.
+func ParseDataSet(r io.Reader, syntaxUID string, opts ...ParseOption) (*DataSet, error) {
+	settings := newParseSettings(opts...)
+	limiter := newParseLimiter(context.Background(), settings.limits)
+	iter, recoveries, err := newDataSetIterator(r, syntaxUID, settings, limiter)
+	if err != nil {
+		return nil, limiter.result(fmt.Errorf("creating new data set iterator: %v", err))
+	}
+
+	dataSet, err := collectDataSet(iter, syntaxUID, settings, limiter, opts...)
+	if err != nil {
+		return nil, limiter.result(err)
+	}
+	dataSet.Recoveries = recoveries
+	if !isKnownTransferSyntax(syntaxUID) {
+		dataSet.Diagnostics = append([]Diagnostic{{
+			Category: UnknownTransferSyntax,
+			Tag:      TransferSyntaxUIDTag,
+			Message:  fmt.Sprintf("transfer syntax %q", syntaxUID),
+		}}, dataSet.Diagnostics...)
+	}
+	return dataSet, nil
+}
+
+// ParseReaderAt parses a DICOM file of the given size represented as an io.ReaderAt in the same way
+// as Parse. In addition, the returned DataSet retains r so that BulkDataReferences created by
+// options such as ReferenceBulkData can later be resolved to bytes with DataSet.OpenBulkData.
+// This allows large bulk data like PixelData to be referenced instead of being held in memory.
+func ParseReaderAt(r io.ReaderAt, size int64, opts ...ParseOption) (*DataSet, error) {
+	dataSet, syntax, err := parseFile(context.Background(), io.NewSectionReader(r, 0, size), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The offsets of BulkDataReferences in a deflated data set are positions within the inflated
+	// stream, so they can't be resolved against r.
+	if !syntax.isDeflated() {
+		dataSet.source = io.NewSectionReader(r, 0, size)
+	}
+	return dataSet, nil
+}
+
+// OpenFile parses the named DICOM file with ParseReaderAt. The file is kept open so that the
+// returned DataSet can serve DataSet.OpenBulkData. It is the callers responsibility to call
+// DataSet.Close when done with the DataSet.
+func OpenFile(name string, opts ...ParseOption) (*DataSet, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("getting file size: %v", err)
+	}
+
+	dataSet, err := ParseReaderAt(f, info.Size(), opts...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	dataSet.closer = f
+	return dataSet, nil
+}
+
+func parseFile(ctx context.Context, r io.Reader, opts ...ParseOption) (*DataSet, transferSyntax, error) {
+	settings := newParseSettings(opts...)
+	limiter := newParseLimiter(ctx, settings.limits)
+	iter, recoveries, err := newFileDataElementIterator(r, settings, limiter)
+	if err != nil {
+		return nil, nil, limiter.result(fmt.Errorf("creating new data element iterator: %v", err))
+	}
+
+	dataSet, err := collectDataSet(iter, "", settings, limiter, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("creating new data element iterator: %v", err)
+		return nil, nil, limiter.result(err)
+	}
+	dataSet.Recoveries = recoveries
+	return dataSet, iter.syntax(), nil
+}
+
+// collectDataSet returns the top level DataSet defined by the elements in the DataElementIterator
+// and closes the iterator. Unlike CollectDataElements, settings that only apply to the top level
+// DataSet of a parse, such as StopAtTag and limits, are respected. syntaxUID is the UID of the
+// transfer syntax of a data set without File Meta Information, or empty for DICOM files.
+func collectDataSet(iter DataElementIterator, syntaxUID string, settings parseSettings, limiter *parseLimiter, opts ...ParseOption) (*DataSet, error) {
+	var stopIter *stopAtTagIterator
+	if settings.stopAtTag != nil {
+		stopIter = &stopAtTagIterator{DataElementIterator: iter, tag: *settings.stopAtTag}
+		iter = stopIter
 	}
 	defer iter.Close()
 
-	return CollectDataElements(iter, opts...)
+	var diagnostics []Diagnostic
+	ctx := newParseContext(iter.syntax())
+	ctx.syntaxUID = syntaxUID
+	ctx.limiter = limiter
+	ctx.diagnostics = &diagnostics
+	dataSet, err := collectDataElements(iter, ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	dataSet.Truncated = stopIter != nil && stopIter.stopped
+	dataSet.Diagnostics = diagnostics
+	return dataSet, nil
 }
 
 // CollectDataElements returns the DataSet defined by the elements in the DataElementIterator.
 // The options will be applied in the order given. The DataElementIterator will be closed.
 func CollectDataElements(iter DataElementIterator, opts ...ParseOption) (*DataSet, error) {
-	ds := &DataSet{map[DataElementTag]*DataElement{}, iter.Length()}
+	return collectDataElements(iter, newParseContext(iter.syntax()), opts...)
+}
+
+func collectDataElements(iter DataElementIterator, ctx *ParseContext, opts ...ParseOption) (*DataSet, error) {
+	ds := &DataSet{Elements: map[DataElementTag]*DataElement{}, Length: iter.Length()}
+	checker := newElementChecker(ctx)
 
 	for elem, err := iter.Next(); err != io.EOF; elem, err = iter.Next() {
 		if err != nil {
 			return nil, err
 		}
-		processedElement, err := processElement(elem, iter.syntax().byteOrder(), opts...)
+		ctx.offset = iter.offset()
+		if elem.Tag == TransferSyntaxUIDTag && ctx.parent == nil {
+			if uid, err := elem.StringValue(); err == nil {
+				ctx.syntaxUID = uid
+			}
+		}
+		checker.check(elem)
+		processedElement, err := processElement(elem, ctx, opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -68,12 +200,26 @@ func CollectDataElements(iter DataElementIterator, opts ...ParseOption) (*DataSe
 // CollectSequence returns the Sequence defined by the items in the SequenceIterator.
 // The options will be applied in the order given. The SequenceIterator will be closed.
 func CollectSequence(iter SequenceIterator, opts ...ParseOption) (*Sequence, error) {
+	return collectSequence(iter, nil, 0, opts...)
+}
+
+// collectSequence returns the Sequence with the given tag defined by the items in the
+// SequenceIterator. Options are applied to the items in the context of parent, or in new top level
+// contexts if parent is nil.
+func collectSequence(iter SequenceIterator, parent *ParseContext, tag DataElementTag, opts ...ParseOption) (*Sequence, error) {
 	var seq = &Sequence{[]*DataSet{}}
 	for obj, err := iter.Next(); err != io.EOF; obj, err = iter.Next() {
 		if err != nil {
 			return nil, err
 		}
-		dataSet, err := CollectDataElements(obj, opts...)
+		ctx := newParseContext(obj.syntax())
+		if parent != nil {
+			ctx = parent.item(tag, len(seq.Items), obj.syntax())
+			if err := ctx.limiter.checkSequenceItem(tag, len(ctx.Path)); err != nil {
+				return nil, err
+			}
+		}
+		dataSet, err := collectDataElements(obj, ctx, opts...)
 		if err != nil {
 			return nil, err
 		}
@@ -113,34 +259,41 @@ func CollectFragmentReferences(iter BulkDataIterator) ([]BulkDataReference, erro
 			return nil, err
 		}
 
-		refs = append(refs, BulkDataReference{ByteRegion{r.Offset, fragmentSize}})
+		refs = append(refs, BulkDataReference{
+			Reference: ByteRegion{r.Offset, fragmentSize},
+			BitOffset: r.BitOffset,
+			BitLength: r.BitLength,
+		})
 	}
 
 	return refs, nil
 }
 
-func processElement(element *DataElement, order binary.ByteOrder, opts ...ParseOption) (*DataElement, error) {
+func processElement(element *DataElement, ctx *ParseContext, opts ...ParseOption) (*DataElement, error) {
 	if seqIter, ok := element.ValueField.(SequenceIterator); ok {
 		// for sequence elements, apply options in post-order. (i.e process sequence items before
 		// the sequence element)
 		// Processing sequence items first protects options transforming SQ DataElements from the misuse
 		// of the SequenceIterator (e.g. not collecting sequence items correctly)
-		seq, err := CollectSequence(seqIter, opts...)
+		seq, err := collectSequence(seqIter, ctx, element.Tag, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("collecting sequence: %v", err)
 		}
 
 		processedSeq := &DataElement{element.Tag, element.VR, seq, element.ValueLength}
-		return processElement(processedSeq, order, opts...)
+		return processElement(processedSeq, ctx, opts...)
 	}
 
-	return applyOptions(element, order, opts...)
+	return applyOptions(element, ctx, opts...)
 }
 
-func applyOptions(element *DataElement, order binary.ByteOrder, opts ...ParseOption) (*DataElement, error) {
+func applyOptions(element *DataElement, ctx *ParseContext, opts ...ParseOption) (*DataElement, error) {
 	var err error
 	for i, opt := range opts {
-		element, err = opt.transform(element)
+		if opt.transform == nil {
+			continue
+		}
+		element, err = opt.transform(ctx, element)
 		if err != nil {
 			return nil, fmt.Errorf("applying option %v: %v", i, err)
 		}
@@ -149,17 +302,65 @@ func applyOptions(element *DataElement, order binary.ByteOrder, opts ...ParseOpt
 		}
 	}
 
-	if _, ok := element.ValueField.(BulkDataIterator); ok {
+	if iter, ok := element.ValueField.(BulkDataIterator); ok {
+		if element.ValueLength != UndefinedLength {
+			if err := ctx.limiter.checkValueLength(element.Tag, int64(element.ValueLength)); err != nil {
+				return nil, err
+			}
+		} else if encapsulated, ok := iter.(*encapsulatedFormatIterator); ok {
+			// the length of encapsulated pixel data is only known once all its fragments are read
+			fragments, err := bufferFragments(encapsulated, element.Tag, ctx.limiter)
+			if err != nil {
+				return nil, err
+			}
+			return &DataElement{element.Tag, element.VR, encapsulatedFormatBuffer(fragments), element.ValueLength}, nil
+		}
 		// As documented in Parse, when the options given do not collect data from the
 		// BulkDataIterator we must collect the data in the byte stream somehow otherwise the
 		// returned DataSet will not be coherent since it would contain a bunch of empty
 		// BulkDataIterators.
-		element, err = bufferBulkData(element, order)
+		element, err = bufferBulkData(element, ctx.ByteOrder())
 	}
 
 	return element, err
 }
 
+// fragmentChunkSize is the number of bytes of a fragment read between checks of the value length
+// limit by bufferFragments
+const fragmentChunkSize = 32 << 10
+
+// bufferFragments returns the fragments of the encapsulated pixel data with the given tag. The
+// length of each fragment and the total length of the fragments are checked against the limits
+// while they are read, so that hostile fragments are rejected before they are read whole.
+func bufferFragments(iter *encapsulatedFormatIterator, tag DataElementTag, limiter *parseLimiter) ([][]byte, error) {
+	var fragments [][]byte
+	var total int64
+	for r, err := iter.Next(); err != io.EOF; r, err = iter.Next() {
+		if err != nil {
+			return nil, fmt.Errorf("buffering fragments: %v", err)
+		}
+		fragment := &bytes.Buffer{}
+		for {
+			n, err := io.CopyN(fragment, r, fragmentChunkSize)
+			total += n
+			if err := limiter.checkValueLength(tag, int64(fragment.Len())); err != nil {
+				return nil, err
+			}
+			if err := limiter.checkValueLength(tag, total); err != nil {
+				return nil, err
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("reading fragment: %v", err)
+			}
+		}
+		fragments = append(fragments, fragment.Bytes())
+	}
+	return fragments, nil
+}
+
 func bufferBulkData(element *DataElement, order binary.ByteOrder) (*DataElement, error) {
 	if fragmentIterator, ok := element.ValueField.(BulkDataIterator); ok {
 		fragments, err := fragmentIterator.ToBuffer()
@@ -190,6 +391,8 @@ func emptyFragmentForType(vr *VR) (interface{}, error) {
 		return []string{}, nil
 	case OLVR:
 		return []uint32{}, nil
+	case OVVR:
+		return []uint64{}, nil
 	case ODVR:
 		return []float64{}, nil
 	case OFVR:
@@ -213,6 +416,8 @@ func decodeFragment(buff []byte, order binary.ByteOrder, vr *VR) (interface{}, e
 		return []string{strings.TrimRightFunc(string(buff), unicode.IsSpace)}, nil
 	case OLVR:
 		valueField = make([]uint32, len(buff)/4)
+	case OVVR:
+		valueField = make([]uint64, len(buff)/8)
 	case ODVR:
 		valueField = make([]float64, len(buff)/8)
 	case OFVR:
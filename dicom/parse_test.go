@@ -15,10 +15,13 @@
 package dicom
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
 	"testing"
 )
 
@@ -246,17 +249,17 @@ func TestParse_multiFrameSupport(t *testing.T) {
 		[]byte("\335\314\377\356\021\000"),
 	}
 	frameRefsUncompressed := []BulkDataReference{
-		{ByteRegion{452, 6}},
-		{ByteRegion{458, 6}},
-		{ByteRegion{464, 6}},
-		{ByteRegion{470, 6}},
+		{Reference: ByteRegion{452, 6}},
+		{Reference: ByteRegion{458, 6}},
+		{Reference: ByteRegion{464, 6}},
+		{Reference: ByteRegion{470, 6}},
 	}
 	frameRefsCompressed := []BulkDataReference{
-		{ByteRegion{422, 0}},
-		{ByteRegion{430, 6}},
-		{ByteRegion{444, 6}},
-		{ByteRegion{458, 6}},
-		{ByteRegion{472, 6}},
+		{Reference: ByteRegion{422, 0}},
+		{Reference: ByteRegion{430, 6}},
+		{Reference: ByteRegion{444, 6}},
+		{Reference: ByteRegion{458, 6}},
+		{Reference: ByteRegion{472, 6}},
 	}
 	referenceOpt := ReferenceBulkData(DefaultBulkDataDefinition)
 
@@ -320,10 +323,21 @@ func TestParse_multiFrameSupport(t *testing.T) {
 			&DataElement{PixelDataTag, OBVR, frameRefsCompressed[1:], 24},
 		},
 		{
-			"when bits allocated is 0, pixel data is excluded",
+			"when bits allocated is 1, frames are split at bit offsets",
 			"MultiFrameUncompressed_BitsAllocatedOne.dcm",
 			[]ParseOption{SplitUncompressedPixelDataFrames()},
-			nil,
+			&DataElement{PixelDataTag, OWVR, NewBitPackedFrameBuffer(6, []byte{0x34}, []byte{0x08}, []byte{0x01}, []byte{0x1E}), 26},
+		},
+		{
+			"when bits allocated is 1, and given ReferenceBulkData, frame refs have bit offsets",
+			"MultiFrameUncompressed_BitsAllocatedOne.dcm",
+			[]ParseOption{SplitUncompressedPixelDataFrames(), referenceOpt},
+			&DataElement{PixelDataTag, OWVR, []BulkDataReference{
+				{Reference: ByteRegion{452, 1}, BitOffset: 0, BitLength: 6},
+				{Reference: ByteRegion{452, 2}, BitOffset: 6, BitLength: 6},
+				{Reference: ByteRegion{453, 2}, BitOffset: 4, BitLength: 6},
+				{Reference: ByteRegion{454, 1}, BitOffset: 2, BitLength: 6},
+			}, 26},
 		},
 		{
 			"when pixel meta tags are missing, pixel data is excluded",
@@ -353,6 +367,190 @@ func TestParse_multiFrameSupport(t *testing.T) {
 	}
 }
 
+func TestParseDataSet(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		metaLength int
+		syntaxUID  string
+		syntax     transferSyntax
+	}{
+		{
+			"explicit VR little endian",
+			"ExplicitVRLittleEndian.dcm",
+			198,
+			ExplicitVRLittleEndianUID,
+			explicitVRLittleEndian,
+		},
+		{
+			"explicit VR big endian with undefined lengths",
+			"ExplicitVRBigEndianUndefLen.dcm",
+			198,
+			ExplicitVRBigEndianUID,
+			explicitVRBigEndian,
+		},
+		{
+			"implicit VR little endian",
+			"ImplicitVRLittleEndian.dcm",
+			196,
+			ImplicitVRLittleEndianUID,
+			implicitVRLittleEndian,
+		},
+		{
+			"deflated explicit VR little endian",
+			"DeflatedExplicitVRLittleEndian.dcm",
+			200,
+			DeflatedExplicitVRLittleEndianUID,
+			deflatedExplicitVRLittleEndian,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dataSetBytes := mustReadFile(tc.file, t)[132+12+tc.metaLength:]
+
+			got, err := ParseDataSet(bytes.NewReader(dataSetBytes), tc.syntaxUID)
+			if err != nil {
+				t.Fatalf("ParseDataSet(_, %v) => %v", tc.syntaxUID, err)
+			}
+
+			want := withoutMetaElements(createExpectedDataSet(bufferedPixelData, uint32(tc.metaLength), tc.syntaxUID))
+			compareDataSets(got, want, tc.syntax.byteOrder(), t)
+		})
+	}
+}
+
+func TestParseDataSet_options(t *testing.T) {
+	dataSetBytes := mustReadFile("ExplicitVRLittleEndian.dcm", t)[132+12+198:]
+
+	got, err := ParseDataSet(bytes.NewReader(dataSetBytes), ExplicitVRLittleEndianUID,
+		ReferenceBulkData(DefaultBulkDataDefinition),
+		excludeTagRange(ReferencedImageSequenceTag, ReferencedImageSequenceTag))
+	if err != nil {
+		t.Fatalf("ParseDataSet(_, _) => %v", err)
+	}
+
+	// bulk data offsets are relative to the start of the data set
+	compareDataElements(got.Elements[PixelDataTag], referencedPixelDataElement(462-(132+12+198), 4), binary.LittleEndian, t)
+
+	seqItem := mustGetFirstSeqItem(got.Elements[ReferencedStudySequenceTag], t)
+	if _, ok := seqItem.Elements[ReferencedImageSequenceTag]; ok {
+		t.Fatalf("expected options to be applied to sequence items")
+	}
+}
+
+func TestParseReaderAt_OpenBulkData(t *testing.T) {
+	frames := [][]byte{
+		[]byte("4\022xV\252\231"),
+		[]byte("\356\335\000\377\021\000"),
+		[]byte("UDwf\231\210"),
+		[]byte("\335\314\377\356\021\000"),
+	}
+	referenceOpt := ReferenceBulkData(DefaultBulkDataDefinition)
+
+	tests := []struct {
+		name string
+		file string
+		opts []ParseOption
+		want [][]byte
+	}{
+		{
+			"references to native frames resolve to the frame bytes",
+			"MultiFrameUncompressed.dcm",
+			[]ParseOption{SplitUncompressedPixelDataFrames(), referenceOpt},
+			frames,
+		},
+		{
+			"references to encapsulated fragments resolve to the fragment bytes",
+			"MultiFrameCompressed.dcm",
+			[]ParseOption{DropBasicOffsetTable, referenceOpt},
+			frames,
+		},
+		{
+			"references to unsplit pixel data resolve to the whole value field",
+			"ExplicitVRBigEndian.dcm",
+			[]ParseOption{referenceOpt},
+			[][]byte{{0x11, 0x11, 0x22, 0x22}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := mustOpenOSFile(tc.file, t)
+			defer f.Close()
+
+			info, err := f.Stat()
+			if err != nil {
+				t.Fatalf("f.Stat() => %v", err)
+			}
+			dataSet, err := ParseReaderAt(f, info.Size(), tc.opts...)
+			if err != nil {
+				t.Fatalf("ParseReaderAt(_, %v) => %v", info.Size(), err)
+			}
+
+			refs, ok := dataSet.Elements[PixelDataTag].ValueField.([]BulkDataReference)
+			if !ok {
+				t.Fatalf("unexpected type %T for pixel data (expected []BulkDataReference)", dataSet.Elements[PixelDataTag].ValueField)
+			}
+			if len(refs) != len(tc.want) {
+				t.Fatalf("got %v references, want %v", len(refs), len(tc.want))
+			}
+			for i, ref := range refs {
+				r, err := dataSet.OpenBulkData(ref)
+				if err != nil {
+					t.Fatalf("OpenBulkData(%v) => %v", ref, err)
+				}
+				got, err := ioutil.ReadAll(r)
+				if err != nil {
+					t.Fatalf("reading bulk data: %v", err)
+				}
+				if !bytes.Equal(got, tc.want[i]) {
+					t.Fatalf("reference %v: got %v, want %v", i, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseReaderAt_deflatedReferencesNotResolvable(t *testing.T) {
+	f := mustOpenOSFile("DeflatedExplicitVRLittleEndian.dcm", t)
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("f.Stat() => %v", err)
+	}
+	dataSet, err := ParseReaderAt(f, info.Size(), ReferenceBulkData(DefaultBulkDataDefinition))
+	if err != nil {
+		t.Fatalf("ParseReaderAt(_, %v) => %v", info.Size(), err)
+	}
+
+	refs := dataSet.Elements[PixelDataTag].ValueField.([]BulkDataReference)
+	if _, err := dataSet.OpenBulkData(refs[0]); err == nil {
+		t.Fatalf("expected error to be returned")
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	dataSet, err := OpenFile(path.Join("../", "testdata/ExplicitVRLittleEndian.dcm"), ReferenceBulkData(DefaultBulkDataDefinition))
+	if err != nil {
+		t.Fatalf("OpenFile(_) => %v", err)
+	}
+	defer dataSet.Close()
+
+	r, err := dataSet.OpenBulkData(referencedPixelDataElement(462, 4).ValueField.([]BulkDataReference)[0])
+	if err != nil {
+		t.Fatalf("OpenBulkData(_) => %v", err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading bulk data: %v", err)
+	}
+	if want := []byte{0x11, 0x11, 0x22, 0x22}; !bytes.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
 func TestBufferBulkData(t *testing.T) {
 	length := uint32(len(sampleBytes))
 	tests := []struct {
@@ -551,6 +749,14 @@ func (emptyBulkDataIterator) write(w io.Writer, syntax transferSyntax) error {
 	return nil
 }
 
+func mustOpenOSFile(name string, t *testing.T) *os.File {
+	f, err := os.Open(path.Join("../", "testdata/"+name))
+	if err != nil {
+		t.Fatalf("opening file: %v", err)
+	}
+	return f
+}
+
 func parse(file string, t *testing.T, opts ...ParseOption) *DataSet {
 	f, err := openFile(file)
 	if err != nil {
@@ -564,12 +770,12 @@ func parse(file string, t *testing.T, opts ...ParseOption) *DataSet {
 }
 
 func referencedPixelDataElement(offset, length int) *DataElement {
-	refs := []BulkDataReference{{ByteRegion{int64(offset), int64(length)}}}
+	refs := []BulkDataReference{{Reference: ByteRegion{int64(offset), int64(length)}}}
 	return &DataElement{PixelDataTag, OWVR, refs, uint32(length)}
 }
 
 func createExpectedDataSet(pixelElement *DataElement, metaLength uint32, transferSyntaxUID string) *DataSet {
-	expectedDataSet := &DataSet{map[DataElementTag]*DataElement{}, UndefinedLength}
+	expectedDataSet := &DataSet{Elements: map[DataElementTag]*DataElement{}, Length: UndefinedLength}
 
 	for _, elem := range expectedElements {
 		expectedDataSet.Elements[elem.Tag] = elem
@@ -587,6 +793,15 @@ func createExpectedDataSet(pixelElement *DataElement, metaLength uint32, transfe
 		[]string{transferSyntaxUID},
 		uint32(len(transferSyntaxUID)),
 	}
+	if buffer, ok := pixelElement.ValueField.(bytesValue); ok && transferSyntaxUID == ExplicitVRBigEndianUID {
+		// OW values carry the byte order of the syntax they are read from
+		pixelElement = &DataElement{
+			pixelElement.Tag,
+			pixelElement.VR,
+			NewBulkDataBufferWithByteOrder(binary.BigEndian, buffer...),
+			pixelElement.ValueLength,
+		}
+	}
 	expectedDataSet.Elements[PixelDataTag] = pixelElement
 
 	return expectedDataSet
@@ -0,0 +1,136 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"encoding/binary"
+)
+
+// SequenceItem identifies an item of a sequence by the tag of the sequence DataElement and the
+// zero-based index of the item within the sequence.
+type SequenceItem struct {
+	Tag   DataElementTag
+	Index int
+}
+
+// ParseContext describes the DataSet containing the DataElement being transformed by a ParseOption.
+// A new ParseContext is created for each call to Parse and for each sequence item, so options that
+// keep their state in the ParseContext are safe to reuse across parses, and DataElements of a
+// sequence item do not affect the state of the enclosing DataSet.
+type ParseContext struct {
+	// Path lists the sequence items enclosing the DataElement, from the outermost to the innermost.
+	// Path is empty for DataElements of the top level DataSet.
+	Path []SequenceItem
+
+	syntax  transferSyntax
+	values  map[interface{}]interface{}
+	parent  *ParseContext
+	limiter *parseLimiter
+
+	// syntaxUID is the UID of the transfer syntax declared for the top level DataSet, or empty if
+	// it is not known
+	syntaxUID string
+
+	// offset is the byte offset of the DataElement being transformed
+	offset int64
+
+	// diagnostics collects the Diagnostics of the parse. It is nil when Diagnostics are not
+	// collected, e.g. for CollectDataElements.
+	diagnostics *[]Diagnostic
+}
+
+func newParseContext(syntax transferSyntax) *ParseContext {
+	return &ParseContext{syntax: syntax}
+}
+
+// item returns the context of the index-th item of the sequence with the given tag
+func (c *ParseContext) item(tag DataElementTag, index int, syntax transferSyntax) *ParseContext {
+	path := make([]SequenceItem, len(c.Path), len(c.Path)+1)
+	copy(path, c.Path)
+	return &ParseContext{
+		Path:        append(path, SequenceItem{tag, index}),
+		syntax:      syntax,
+		syntaxUID:   c.syntaxUID,
+		parent:      c,
+		limiter:     c.limiter,
+		diagnostics: c.diagnostics,
+	}
+}
+
+// Parent returns the context of the DataSet enclosing the current sequence item. nil is returned
+// for the top level DataSet.
+func (c *ParseContext) Parent() *ParseContext {
+	return c.parent
+}
+
+// ByteOrder returns the byte order of the transfer syntax the DataSet is encoded in
+func (c *ParseContext) ByteOrder() binary.ByteOrder {
+	return c.syntax.byteOrder()
+}
+
+// ExplicitVR returns true if the transfer syntax the DataSet is encoded in has explicit VRs
+func (c *ParseContext) ExplicitVR() bool {
+	_, ok := c.syntax.(explicitSyntax)
+	return ok
+}
+
+// TransferSyntax returns the transfer syntax declared for the DataSet, either by the
+// TransferSyntaxUID element of the File Meta Information or by the caller of ParseDataSet. Sequence
+// items share the transfer syntax of the top level DataSet. If no transfer syntax was declared or
+// its UID is unknown, only the UID, ByteOrder, ExplicitVR and Deflated fields are set, describing
+// how the DataSet is read. See ByteOrder and ExplicitVR for the encoding of the current DataSet,
+// which differs from the declared one for items of sequences with VR UN and for recovered files.
+func (c *ParseContext) TransferSyntax() TransferSyntax {
+	if ts, err := LookupTransferSyntax(c.syntaxUID); err == nil {
+		return ts
+	}
+	return TransferSyntax{
+		UID:        c.syntaxUID,
+		ByteOrder:  c.ByteOrder(),
+		ExplicitVR: c.ExplicitVR(),
+		Deflated:   c.syntax.isDeflated(),
+	}
+}
+
+// Warn records a Diagnostic of the given category about the DataElement with the given tag. The
+// Diagnostic has the Path of the context and the offset of the DataElement being transformed.
+// Diagnostics are reported in DataSet.Diagnostics of the DataSet returned by Parse.
+func (c *ParseContext) Warn(category DiagnosticCategory, tag DataElementTag, message string) {
+	if c.diagnostics == nil {
+		return
+	}
+	*c.diagnostics = append(*c.diagnostics, Diagnostic{
+		Category: category,
+		Path:     c.Path,
+		Tag:      tag,
+		Offset:   c.offset,
+		Message:  message,
+	})
+}
+
+// Value returns the value stored for key in the scratch store of the current DataSet, or nil if
+// no value is stored. Values of enclosing DataSets are not returned, see Parent.
+func (c *ParseContext) Value(key interface{}) interface{} {
+	return c.values[key]
+}
+
+// SetValue stores the value for key in the scratch store of the current DataSet. Keys should be of
+// an unexported type defined by the option to avoid collisions with other options.
+func (c *ParseContext) SetValue(key, value interface{}) {
+	if c.values == nil {
+		c.values = map[interface{}]interface{}{}
+	}
+	c.values[key] = value
+}
@@ -0,0 +1,173 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestParseContext_path(t *testing.T) {
+	paths := map[DataElementTag][]SequenceItem{}
+	recordPaths := ParseOptionWithContextTransform(func(ctx *ParseContext, element *DataElement) (*DataElement, error) {
+		paths[element.Tag] = ctx.Path
+		return element, nil
+	})
+
+	parse("ExplicitVRLittleEndian.dcm", t, recordPaths)
+
+	tests := []struct {
+		name string
+		tag  DataElementTag
+		want []SequenceItem
+	}{
+		{
+			"top level elements have an empty path",
+			PixelDataTag,
+			nil,
+		},
+		{
+			"sequence elements are in the context of the enclosing data set",
+			ReferencedStudySequenceTag,
+			nil,
+		},
+		{
+			"elements of sequence items include the enclosing sequence",
+			ReferencedImageSequenceTag,
+			[]SequenceItem{{ReferencedStudySequenceTag, 0}},
+		},
+		{
+			"elements of nested sequence items include all enclosing sequences",
+			ReferencedSOPInstanceUIDTag,
+			[]SequenceItem{{ReferencedStudySequenceTag, 0}, {ReferencedImageSequenceTag, 0}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := paths[tc.tag]; !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got path %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseContext_itemValuesAreIndependent(t *testing.T) {
+	key := "key"
+	parent := newParseContext(explicitVRLittleEndian)
+	parent.SetValue(key, "parent")
+
+	first := parent.item(ReferencedStudySequenceTag, 0, implicitVRLittleEndian)
+	if got := first.Value(key); got != nil {
+		t.Fatalf("got item value %v, want nil", got)
+	}
+	first.SetValue(key, "item")
+
+	if got := parent.Value(key); got != "parent" {
+		t.Fatalf("got parent value %v, want %v", got, "parent")
+	}
+	if got := first.Parent(); got != parent {
+		t.Fatalf("got parent %v, want %v", got, parent)
+	}
+	if got := first.ByteOrder(); got != binary.LittleEndian {
+		t.Fatalf("got byte order %v, want %v", got, binary.LittleEndian)
+	}
+	if first.ExplicitVR() {
+		t.Fatalf("expected item of implicit VR sequence to have implicit VRs")
+	}
+
+	second := parent.item(ReferencedStudySequenceTag, 1, implicitVRLittleEndian)
+	nested := first.item(ReferencedImageSequenceTag, 0, implicitVRLittleEndian)
+	want := []SequenceItem{{ReferencedStudySequenceTag, 1}}
+	if !reflect.DeepEqual(second.Path, want) {
+		t.Fatalf("got path %v, want %v", second.Path, want)
+	}
+	want = []SequenceItem{{ReferencedStudySequenceTag, 0}, {ReferencedImageSequenceTag, 0}}
+	if !reflect.DeepEqual(nested.Path, want) {
+		t.Fatalf("got path %v, want %v", nested.Path, want)
+	}
+}
+
+func TestParseContext_TransferSyntax(t *testing.T) {
+	var got TransferSyntax
+	recordSyntax := ParseOptionWithContextTransform(func(ctx *ParseContext, element *DataElement) (*DataElement, error) {
+		if element.Tag == PixelDataTag {
+			got = ctx.TransferSyntax()
+		}
+		return element, nil
+	})
+	pixelData := []byte{0xE0, 0x7F, 0x10, 0x00, 'O', 'B', 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02}
+
+	tests := []struct {
+		name  string
+		parse func() (*DataSet, error)
+		want  TransferSyntax
+	}{
+		{
+			"declared in the File Meta Information",
+			func() (*DataSet, error) {
+				f, err := openFile("ExplicitVRBigEndian.dcm")
+				if err != nil {
+					return nil, err
+				}
+				return Parse(f, recordSyntax)
+			},
+			mustLookupTransferSyntax(t, ExplicitVRBigEndianUID),
+		},
+		{
+			"given to ParseDataSet",
+			func() (*DataSet, error) {
+				return ParseDataSet(bytes.NewReader(pixelData), ExplicitVRLittleEndianUID, recordSyntax)
+			},
+			mustLookupTransferSyntax(t, ExplicitVRLittleEndianUID),
+		},
+		{
+			"unknown transfer syntax",
+			func() (*DataSet, error) {
+				return ParseDataSet(bytes.NewReader(pixelData), "1.2.3.4", Lenient, recordSyntax)
+			},
+			TransferSyntax{UID: "1.2.3.4", ByteOrder: binary.LittleEndian, ExplicitVR: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got = TransferSyntax{}
+			if _, err := tc.parse(); err != nil {
+				t.Fatalf("parsing: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	parent := newParseContext(explicitVRLittleEndian)
+	parent.syntaxUID = ExplicitVRLittleEndianUID
+	item := parent.item(ReferencedStudySequenceTag, 0, implicitVRLittleEndian)
+	if got, want := item.TransferSyntax(), mustLookupTransferSyntax(t, ExplicitVRLittleEndianUID); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got item transfer syntax %+v, want %+v", got, want)
+	}
+}
+
+func mustLookupTransferSyntax(t *testing.T, uid string) TransferSyntax {
+	ts, err := LookupTransferSyntax(uid)
+	if err != nil {
+		t.Fatalf("LookupTransferSyntax(%q) => %v", uid, err)
+	}
+	return ts
+}
@@ -15,14 +15,41 @@
 package dicom
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 )
 
 // ParseOption configures the behavior of the Parse function.
 type ParseOption struct {
-	transform func(*DataElement) (*DataElement, error)
+	transform func(*ParseContext, *DataElement) (*DataElement, error)
+
+	// setting configures how the DICOM file is read rather than transforming its DataElements.
+	setting func(*parseSettings)
+}
+
+// parseSettings holds the configuration of Parse that is not expressed as a DataElement transform
+type parseSettings struct {
+	lenient bool
+
+	// stopAtTag is the tag at which parsing of the top level data set ends, if set
+	stopAtTag *DataElementTag
+
+	limits Limits
+}
+
+func newParseSettings(opts ...ParseOption) parseSettings {
+	settings := parseSettings{}
+	for _, opt := range opts {
+		if opt.setting != nil {
+			opt.setting(&settings)
+		}
+	}
+	return settings
 }
 
 // ParseOptionWithTransform returns a ParseOption that applies the given transformation to each DataElement in
@@ -33,9 +60,31 @@ type ParseOption struct {
 // the returned DataSet of Parse. If a nil DataElement is returned, this DataElement will be
 // excluded from the DataSet returned from Parse.
 func ParseOptionWithTransform(t func(*DataElement) (*DataElement, error)) ParseOption {
-	return ParseOption{t}
+	return ParseOption{transform: func(_ *ParseContext, element *DataElement) (*DataElement, error) {
+		return t(element)
+	}}
+}
+
+// ParseOptionWithContextTransform returns a ParseOption that behaves like ParseOptionWithTransform
+// but also passes the ParseContext of each DataElement to the transform. Options that depend on
+// other DataElements of the DataSet should keep their state in the ParseContext rather than in
+// variables shared across calls, so the same option can be reused across parses and DataElements
+// of nested sequence items do not interfere with the enclosing DataSet.
+func ParseOptionWithContextTransform(t func(*ParseContext, *DataElement) (*DataElement, error)) ParseOption {
+	return ParseOption{transform: t}
 }
 
+// Lenient allows Parse to read files that do not conform to the DICOM file format defined in PS3.10
+// http://dicom.nema.org/medical/dicom/current/output/html/part10.html#chapter_7. Files missing the
+// preamble or the "DICM" prefix, files without a File Meta Information group (such as ACR-NEMA 2.0
+// files) and File Meta Information groups missing the FileMetaInformationGroupLength (0002,0000)
+// are accepted. When the transfer syntax is not specified by the file, it is guessed from the first
+// data element. The recoveries applied are reported in DataSet.Recoveries. ParseDataSet reads a
+// data set in an unknown transfer syntax as explicit VR little endian.
+var Lenient = ParseOption{setting: func(settings *parseSettings) {
+	settings.lenient = true
+}}
+
 // ReferenceBulkData ensures that all DataElements with ValueField of type BulkDataIterator are
 // transformed to []BulkDataReference when bulkDataDefinition returns true and their default
 // buffered types otherwise
@@ -45,6 +94,17 @@ func ReferenceBulkData(bulkDataDefinition func(*DataElement) bool) ParseOption {
 	})
 }
 
+// StopAtTag returns an option that ends parsing at the first top level DataElement with a tag
+// greater than or equal to the given tag. The DataElement with the given tag and all following
+// DataElements are excluded from the returned DataSet and are not read from the input. When
+// parsing ends early, the returned DataSet is marked as Truncated. For example,
+// StopAtTag(PixelDataTag) reads the metadata of an image without reading its pixel data.
+func StopAtTag(tag DataElementTag) ParseOption {
+	return ParseOption{setting: func(settings *parseSettings) {
+		settings.stopAtTag = &tag
+	}}
+}
+
 // DropGroupLengths will exclude all group length elements (gggg,0000) from the returned DataSet
 var DropGroupLengths = ParseOptionWithTransform(func(element *DataElement) (*DataElement, error) {
 	if element.Tag.ElementNumber() == 0 {
@@ -56,6 +116,7 @@ var DropGroupLengths = ParseOptionWithTransform(func(element *DataElement) (*Dat
 // DropBasicOffsetTable will exclude the basic offset table fragment from pixel data encoded using
 // the encapsulated (compressed) format. For more information on the offset table and encapsulated
 // formats please see http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_A.4.
+// The offset table is needed by NewFrameIndex to locate frames spanning several fragments.
 var DropBasicOffsetTable = ParseOptionWithTransform(func(element *DataElement) (*DataElement, error) {
 	iter, ok := element.ValueField.(BulkDataIterator)
 	if !ok {
@@ -86,21 +147,40 @@ func DefaultBulkDataDefinition(elem *DataElement) bool {
 // data will be excluded from the returned DataSet.
 // http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.7.6.3.html
 //
+// Frames of pixel data with 1 bit allocated are packed contiguously and may start and end mid-byte.
+// Their BulkDataReaders and BulkDataReferences cover all bytes containing bits of the frame, with the
+// frame located by BitOffset and BitLength, see UnpackBits. Their BulkDataBuffer holds each frame
+// starting at the least significant bit of its first byte, see NewBitPackedFrameBuffer. The bytes
+// of each word of OW pixel data in a big endian transfer syntax are swapped before frames are
+// located, so their offsets locate bytes of the swapped words.
+//
 // Note this option should be applied before all other options that modify pixel data.
 func SplitUncompressedPixelDataFrames() ParseOption {
-	metadata := map[DataElementTag]int64{
-		RowsTag:            0,
-		ColumnsTag:         0,
-		SamplesPerPixelTag: 0,
-		BitsAllocatedTag:   0,
-		NumberOfFramesTag:  0,
-	}
-
-	return ParseOptionWithTransform(func(element *DataElement) (*DataElement, error) {
+	return ParseOptionWithContextTransform(func(ctx *ParseContext, element *DataElement) (*DataElement, error) {
 		if element.ValueLength <= 0 {
 			return element, nil
 		}
 
+		metadata, ok := ctx.Value(imagePixelMetadataKey{}).(map[DataElementTag]int64)
+		if !ok {
+			metadata = map[DataElementTag]int64{
+				RowsTag:            0,
+				ColumnsTag:         0,
+				SamplesPerPixelTag: 0,
+				BitsAllocatedTag:   0,
+				NumberOfFramesTag:  0,
+			}
+			ctx.SetValue(imagePixelMetadataKey{}, metadata)
+		}
+
+		if element.Tag == PhotometricInterpretationTag {
+			// the chrominance samples of native YBR_FULL_422 and YBR_PARTIAL_422 frames are
+			// subsampled horizontally
+			v, _ := element.StringValue()
+			ctx.SetValue(photometricInterpretationKey{}, PhotometricInterpretation(strings.TrimSpace(v)))
+			return element, nil
+		}
+
 		if _, ok := metadata[element.Tag]; ok {
 			v, err := element.IntValue()
 			if err != nil {
@@ -110,29 +190,51 @@ func SplitUncompressedPixelDataFrames() ParseOption {
 		}
 
 		if element.Tag == PixelDataTag {
-			return toMultiFrame(element, metadata)
+			return toMultiFrame(ctx, element, metadata)
 		}
 
 		return element, nil
 	})
 }
 
-func toMultiFrame(element *DataElement, metadata map[DataElementTag]int64) (*DataElement, error) {
+// imagePixelMetadataKey is the ParseContext key of the image pixel module values read by
+// SplitUncompressedPixelDataFrames
+type imagePixelMetadataKey struct{}
+
+// photometricInterpretationKey is the ParseContext key of the PhotometricInterpretation read by
+// SplitUncompressedPixelDataFrames
+type photometricInterpretationKey struct{}
+
+func toMultiFrame(ctx *ParseContext, element *DataElement, metadata map[DataElementTag]int64) (*DataElement, error) {
 	if element.ValueLength == UndefinedLength {
 		// If the pixel data is in the encapsulated format (indicated by having undefined length), the
 		// option SplitUncompressedPixelDataFrames does not do anything as specified.
 		return element, nil
 	}
 
+	if metadata[BitsAllocatedTag] == 1 {
+		return toBitPackedMultiFrame(ctx, element, metadata)
+	}
+
 	if metadata[BitsAllocatedTag]%8 != 0 {
 		// BitsAllocated must be a multiple of 8 or 1 as specified in PS3.5
 		// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_8.1.1
-		// TODO support BitsAllocated=1
+		ctx.Warn(DroppedElement, element.Tag, fmt.Sprintf("unsupported BitsAllocated %d", metadata[BitsAllocatedTag]))
 		return nil, nil
 	}
 
-	frameLength := (metadata[RowsTag] * metadata[ColumnsTag] * metadata[SamplesPerPixelTag] * metadata[BitsAllocatedTag]) / 8
+	info := FrameInfo{
+		Rows:            int(metadata[RowsTag]),
+		Columns:         int(metadata[ColumnsTag]),
+		SamplesPerPixel: int(metadata[SamplesPerPixelTag]),
+		BitsAllocated:   int(metadata[BitsAllocatedTag]),
+	}
+	frameLength := int64(info.Length())
+	if pi, _ := ctx.Value(photometricInterpretationKey{}).(PhotometricInterpretation); pi.subsampled() {
+		frameLength = int64(info.subsampledLength())
+	}
 	if frameLength <= 0 {
+		ctx.Warn(DroppedElement, element.Tag, fmt.Sprintf("invalid frame length %d from image pixel module", frameLength))
 		return nil, nil
 	}
 
@@ -157,6 +259,7 @@ type nativeMultiFrame struct {
 	numberOfFrames     int64
 	framesRead         int64
 	currentFrame       *BulkDataReader
+	order              binary.ByteOrder
 }
 
 func newNativeMultiFrame(iter BulkDataIterator, frameLength, numberOfFrames int64) (BulkDataIterator, error) {
@@ -172,7 +275,7 @@ func newNativeMultiFrame(iter BulkDataIterator, frameLength, numberOfFrames int6
 		return nil, fmt.Errorf("internal error: cannot convert multiple fragments to native multi-frame")
 	}
 
-	return &nativeMultiFrame{r, frameLength, numberOfFrames, 0, nil}, nil
+	return &nativeMultiFrame{r, frameLength, numberOfFrames, 0, nil, iter.ByteOrder()}, nil
 }
 
 func (it *nativeMultiFrame) Next() (*BulkDataReader, error) {
@@ -190,7 +293,7 @@ func (it *nativeMultiFrame) Next() (*BulkDataReader, error) {
 	frameBytes := io.LimitReader(it.underlyingFragment, it.frameLength)
 	frameOffset := it.underlyingFragment.Offset + (it.framesRead * it.frameLength)
 
-	it.currentFrame = &BulkDataReader{frameBytes, frameOffset}
+	it.currentFrame = &BulkDataReader{Reader: frameBytes, Offset: frameOffset}
 
 	it.framesRead++
 
@@ -202,7 +305,7 @@ func (it *nativeMultiFrame) ToBuffer() (BulkDataBuffer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("collecting frames from native multi-frame: %v", err)
 	}
-	return NewBulkDataBuffer(frames...), nil
+	return NewBulkDataBufferWithByteOrder(it.order, frames...), nil
 }
 
 func (it *nativeMultiFrame) Close() error {
@@ -218,6 +321,10 @@ func (it *nativeMultiFrame) Length() int64 {
 	return it.numberOfFrames * it.frameLength
 }
 
+func (it *nativeMultiFrame) ByteOrder() binary.ByteOrder {
+	return it.order
+}
+
 func (it *nativeMultiFrame) write(w io.Writer, syntax transferSyntax) error {
 	// TODO option should not depend on internal type
 	return writeByteFragments(w, func() (io.Reader, error) {
@@ -225,6 +332,162 @@ func (it *nativeMultiFrame) write(w io.Writer, syntax transferSyntax) error {
 	})
 }
 
+// toBitPackedMultiFrame splits pixel data with 1 bit allocated into frames, which are packed
+// contiguously and so may start and end mid-byte
+func toBitPackedMultiFrame(ctx *ParseContext, element *DataElement, metadata map[DataElementTag]int64) (*DataElement, error) {
+	frameBits := metadata[RowsTag] * metadata[ColumnsTag] * metadata[SamplesPerPixelTag]
+	if frameBits <= 0 {
+		ctx.Warn(DroppedElement, element.Tag, fmt.Sprintf("invalid frame length %d bits from image pixel module", frameBits))
+		return nil, nil
+	}
+	// frames are buffered whole, so their length is bounded like that of any buffered value
+	frameLength := (frameBits + 7) / 8
+	if frameLength > int64(element.ValueLength) {
+		ctx.Warn(DroppedElement, element.Tag, fmt.Sprintf("frame of %d bits exceeds pixel data of length %d", frameBits, element.ValueLength))
+		return nil, nil
+	}
+	if err := ctx.limiter.checkValueLength(element.Tag, frameLength); err != nil {
+		return nil, err
+	}
+
+	numberOfFrames := metadata[NumberOfFramesTag]
+	if numberOfFrames <= 0 {
+		numberOfFrames = 1
+	}
+
+	if bulkData, ok := element.ValueField.(BulkDataIterator); ok {
+		// the bits of big endian OW words are in the order of the frames once the words are swapped
+		swapWords := element.VR == OWVR && bulkData.ByteOrder() == binary.BigEndian
+		multiFrame, err := newBitPackedMultiFrame(bulkData, frameBits, numberOfFrames, swapWords)
+		if err != nil {
+			return nil, err
+		}
+		element.ValueField = multiFrame
+	}
+	return element, nil
+}
+
+// bitPackedMultiFrame is a BulkDataIterator over the frames of pixel data with 1 bit allocated. As
+// frames may start and end mid-byte, each BulkDataReader reads all bytes holding bits of its frame,
+// including the bytes shared with the previous and next frames, and the frame is located within
+// them by BitOffset and BitLength. Frames are buffered one at a time.
+type bitPackedMultiFrame struct {
+	underlyingFragment *BulkDataReader
+	frameBits          int64
+	numberOfFrames     int64
+	framesRead         int64
+
+	// last is the last byte read from the underlying fragment, which holds the first bits of the
+	// next frame when the previous frame ends mid-byte
+	last byte
+}
+
+// newBitPackedMultiFrame returns the frames of the single fragment of iter. If swapWords is true,
+// the bytes of each 2 byte word of the fragment are swapped before the frames are located.
+func newBitPackedMultiFrame(iter BulkDataIterator, frameBits, numberOfFrames int64, swapWords bool) (BulkDataIterator, error) {
+	r, err := iter.Next()
+	if err != nil {
+		return nil, fmt.Errorf("retreiving image fragment: %v", err)
+	}
+	if _, err := iter.Next(); err != io.EOF {
+		return nil, fmt.Errorf("internal error: cannot convert multiple fragments to native multi-frame")
+	}
+	if swapWords {
+		// byteSwapReader reads whole words, which bufio.Reader buffers for reads of any length
+		r = &BulkDataReader{Reader: bufio.NewReader(&byteSwapReader{r: r, size: 2}), Offset: r.Offset}
+	}
+	return &bitPackedMultiFrame{underlyingFragment: r, frameBits: frameBits, numberOfFrames: numberOfFrames}, nil
+}
+
+func (it *bitPackedMultiFrame) Next() (*BulkDataReader, error) {
+	frame, _, err := it.next()
+	if err != nil {
+		return nil, err
+	}
+	start := (it.framesRead - 1) * it.frameBits
+	return &BulkDataReader{
+		Reader:    bytes.NewReader(frame),
+		Offset:    it.underlyingFragment.Offset + start/8,
+		BitOffset: start % 8,
+		BitLength: it.frameBits,
+	}, nil
+}
+
+// next returns the bytes holding the bits of the next frame and the bytes among them which were
+// not shared with the previous frame
+func (it *bitPackedMultiFrame) next() (frame, unshared []byte, err error) {
+	if it.framesRead >= it.numberOfFrames {
+		// This handles the case when there are trailing nulls remaining after all image frames.
+		io.Copy(ioutil.Discard, it.underlyingFragment)
+		return nil, nil, io.EOF
+	}
+
+	start, end := it.framesRead*it.frameBits, (it.framesRead+1)*it.frameBits
+	length := (end+7)/8 - (start+7)/8
+	// the frame is read through a bounded reader so that a frame longer than the fragment fails
+	// before its whole length is allocated
+	unshared, err = ioutil.ReadAll(io.LimitReader(it.underlyingFragment, length))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading frame %d: %v", it.framesRead, err)
+	}
+	if int64(len(unshared)) < length {
+		return nil, nil, fmt.Errorf("reading frame %d: %v", it.framesRead, io.ErrUnexpectedEOF)
+	}
+	frame = unshared
+	if start%8 != 0 {
+		frame = append([]byte{it.last}, unshared...)
+	}
+	if len(unshared) > 0 {
+		it.last = unshared[len(unshared)-1]
+	}
+	it.framesRead++
+	return frame, unshared, nil
+}
+
+func (it *bitPackedMultiFrame) ToBuffer() (BulkDataBuffer, error) {
+	var frames [][]byte
+	for frame, _, err := it.next(); err != io.EOF; frame, _, err = it.next() {
+		if err != nil {
+			return nil, fmt.Errorf("collecting frames from bit packed multi-frame: %v", err)
+		}
+		start := (it.framesRead - 1) * it.frameBits
+		aligned, err := alignBits(frame, start%8, it.frameBits)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, aligned)
+	}
+	return NewBitPackedFrameBuffer(it.frameBits, frames...), nil
+}
+
+func (it *bitPackedMultiFrame) Close() error {
+	for _, _, err := it.next(); err != io.EOF; _, _, err = it.next() {
+		if err != nil {
+			return fmt.Errorf("discarding frame: %v", err)
+		}
+	}
+	return nil
+}
+
+func (it *bitPackedMultiFrame) Length() int64 {
+	return (it.numberOfFrames*it.frameBits + 7) / 8
+}
+
+func (it *bitPackedMultiFrame) ByteOrder() binary.ByteOrder {
+	return binary.LittleEndian
+}
+
+func (it *bitPackedMultiFrame) write(w io.Writer, syntax transferSyntax) error {
+	// bytes shared by consecutive frames are written once
+	return writeByteFragments(w, func() (io.Reader, error) {
+		_, unshared, err := it.next()
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(unshared), nil
+	})
+}
+
 func referenceBulkData(element *DataElement, isBulkData func(*DataElement) bool) (*DataElement, error) {
 	if isBulkData(element) {
 		if bulkIter, ok := element.ValueField.(BulkDataIterator); ok {
@@ -239,19 +502,33 @@ func referenceBulkData(element *DataElement, isBulkData func(*DataElement) bool)
 	return element, nil
 }
 
-// UTF8TextOption returns an option that ensures all textual VRs are decoded into UTF-8.
+// UTF8TextOption returns an option that ensures all textual VRs are decoded into UTF-8. The
+// specific character set (0008,0005) of a sequence item applies to that item only; items without
+// one use the character set of the enclosing DataSet.
 func UTF8TextOption() ParseOption {
-	dataSetEncoding := defaultEncodingSystem()
-
-	return ParseOptionWithTransform(func(element *DataElement) (*DataElement, error) {
+	return ParseOptionWithContextTransform(func(ctx *ParseContext, element *DataElement) (*DataElement, error) {
 		if element.Tag == SpecificCharacterSetTag {
 			specificCodingSystem, err := newEncodingSystem(element)
 			if err != nil {
 				return nil, fmt.Errorf("setting specific character set: %v", err)
 			}
-			dataSetEncoding = specificCodingSystem
+			ctx.SetValue(encodingSystemKey{}, specificCodingSystem)
 		}
 
-		return dataSetEncoding.decode(element)
+		return contextEncodingSystem(ctx).decode(element)
 	})
 }
+
+// encodingSystemKey is the ParseContext key of the encodingSystem set by UTF8TextOption
+type encodingSystemKey struct{}
+
+// contextEncodingSystem returns the encodingSystem of the innermost DataSet in ctx with a specific
+// character set, or the default encodingSystem if there is none.
+func contextEncodingSystem(ctx *ParseContext) *encodingSystem {
+	for ; ctx != nil; ctx = ctx.Parent() {
+		if coding, ok := ctx.Value(encodingSystemKey{}).(*encodingSystem); ok {
+			return coding
+		}
+	}
+	return defaultEncodingSystem()
+}
@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -49,7 +50,7 @@ func TestSplitUncompressedPixelDataFrames(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			opt := SplitUncompressedPixelDataFrames()
-			got, err := opt.transform(tc.in)
+			got, err := opt.transform(newParseContext(explicitVRLittleEndian), tc.in)
 			if err != nil {
 				t.Fatalf("SplitUncompressedPixelDataFrames.transform(_) => %v", err)
 			}
@@ -58,6 +59,128 @@ func TestSplitUncompressedPixelDataFrames(t *testing.T) {
 	}
 }
 
+func TestSplitUncompressedPixelDataFrames_nestedImagePixelModule(t *testing.T) {
+	frames := [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}}
+	icon := NewDataSet(map[DataElementTag]interface{}{
+		RowsTag:            []uint16{1},
+		ColumnsTag:         []uint16{1},
+		SamplesPerPixelTag: []uint16{1},
+		BitsAllocatedTag:   []uint16{8},
+		PixelDataTag:       NewBulkDataBuffer([]byte{9}),
+	})
+	dataSet := NewDataSet(map[DataElementTag]interface{}{
+		TransferSyntaxUIDTag: []string{ExplicitVRLittleEndianUID},
+		RowsTag:              []uint16{2},
+		ColumnsTag:           []uint16{2},
+		SamplesPerPixelTag:   []uint16{1},
+		BitsAllocatedTag:     []uint16{8},
+		NumberOfFramesTag:    []string{"2"},
+		IconImageSequenceTag: &Sequence{Items: []*DataSet{icon}},
+		PixelDataTag:         NewBulkDataBuffer(bytes.Join(frames, nil)),
+	})
+	dataSet.Elements[PixelDataTag].VR = OBVR
+	file := &bytes.Buffer{}
+	if err := Construct(file, dataSet); err != nil {
+		t.Fatalf("Construct(_, _) => %v", err)
+	}
+
+	// The same option is used for both parses to ensure no state is kept across parses.
+	opt := SplitUncompressedPixelDataFrames()
+	for i := 0; i < 2; i++ {
+		got, err := Parse(bytes.NewReader(file.Bytes()), opt)
+		if err != nil {
+			t.Fatalf("Parse(_, _) => %v", err)
+		}
+
+		pixelData, ok := got.Elements[PixelDataTag].ValueField.(BulkDataBuffer)
+		if !ok {
+			t.Fatalf("got pixel data %v, want BulkDataBuffer", got.Elements[PixelDataTag])
+		}
+		if !reflect.DeepEqual(pixelData.Data(), frames) {
+			t.Fatalf("got frames %v, want %v", pixelData.Data(), frames)
+		}
+		iconPixelData := got.Elements[IconImageSequenceTag].ValueField.(*Sequence).Items[0].Elements[PixelDataTag]
+		if want := [][]byte{{9}}; !reflect.DeepEqual(iconPixelData.ValueField.(BulkDataBuffer).Data(), want) {
+			t.Fatalf("got icon frames %v, want %v", iconPixelData.ValueField, want)
+		}
+	}
+}
+
+func TestSplitUncompressedPixelDataFrames_subsampledChroma(t *testing.T) {
+	tests := []struct {
+		name   string
+		pi     string
+		frames [][]byte
+	}{
+		{"subsampled chrominance", "YBR_FULL_422", [][]byte{{1, 2, 3, 4}, {5, 6, 7, 8}}},
+		{"full resolution chrominance", "YBR_FULL", [][]byte{{1, 2, 3, 4, 5, 6}, {7, 8, 9, 10, 11, 12}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dataSet := NewDataSet(map[DataElementTag]interface{}{
+				TransferSyntaxUIDTag:         []string{ExplicitVRLittleEndianUID},
+				RowsTag:                      []uint16{1},
+				ColumnsTag:                   []uint16{2},
+				SamplesPerPixelTag:           []uint16{3},
+				BitsAllocatedTag:             []uint16{8},
+				PhotometricInterpretationTag: []string{tc.pi},
+				NumberOfFramesTag:            []string{"2"},
+				PixelDataTag:                 NewBulkDataBuffer(bytes.Join(tc.frames, nil)),
+			})
+			dataSet.Elements[PixelDataTag].VR = OBVR
+			file := &bytes.Buffer{}
+			if err := Construct(file, dataSet); err != nil {
+				t.Fatalf("Construct(_, _) => %v", err)
+			}
+
+			got, err := Parse(bytes.NewReader(file.Bytes()), SplitUncompressedPixelDataFrames())
+			if err != nil {
+				t.Fatalf("Parse(_, _) => %v", err)
+			}
+			pixelData, ok := got.Elements[PixelDataTag].ValueField.(BulkDataBuffer)
+			if !ok {
+				t.Fatalf("got pixel data %v, want BulkDataBuffer", got.Elements[PixelDataTag])
+			}
+			if !reflect.DeepEqual(pixelData.Data(), tc.frames) {
+				t.Fatalf("got frames %v, want %v", pixelData.Data(), tc.frames)
+			}
+		})
+	}
+}
+
+func TestSplitUncompressedPixelDataFrames_photometricInterpretationReplaced(t *testing.T) {
+	opt := SplitUncompressedPixelDataFrames()
+	ctx := newParseContext(explicitVRLittleEndian)
+	elements := []*DataElement{
+		{RowsTag, USVR, []uint16{1}, 2},
+		{ColumnsTag, USVR, []uint16{2}, 2},
+		{SamplesPerPixelTag, USVR, []uint16{3}, 2},
+		{BitsAllocatedTag, USVR, []uint16{8}, 2},
+		{PhotometricInterpretationTag, CSVR, []string{"YBR_FULL_422"}, 12},
+		{PhotometricInterpretationTag, CSVR, []string{"RGB"}, 4},
+	}
+	for _, element := range elements {
+		if _, err := opt.transform(ctx, element); err != nil {
+			t.Fatalf("transform(_, %v) => %v", element.Tag, err)
+		}
+	}
+
+	data := []byte{1, 2, 3, 4, 5, 6}
+	pixelData := &DataElement{PixelDataTag, OBVR, NewBulkDataIterator(bytes.NewReader(data), 0), uint32(len(data))}
+	got, err := opt.transform(ctx, pixelData)
+	if err != nil {
+		t.Fatalf("transform(_, %v) => %v", PixelDataTag, err)
+	}
+	buffered, err := got.ValueField.(BulkDataIterator).ToBuffer()
+	if err != nil {
+		t.Fatalf("ToBuffer() => %v", err)
+	}
+	if want := [][]byte{data}; !reflect.DeepEqual(buffered.Data(), want) {
+		t.Fatalf("got frames %v, want %v", buffered.Data(), want)
+	}
+}
+
 func TestUTF8Text(t *testing.T) {
 	order := binary.LittleEndian // TODO cleanup dependency on byte order for element comparison
 
@@ -107,9 +230,10 @@ func TestUTF8Text(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			utf8 := UTF8TextOption()
+			ctx := newParseContext(explicitVRLittleEndian)
 
-			utf8.transform(createCharacterSetElement(tc.codingTerm))
-			utf8.transform(tc.in)
+			utf8.transform(ctx, createCharacterSetElement(tc.codingTerm))
+			utf8.transform(ctx, tc.in)
 
 			compareDataElements(tc.in, tc.want, order, t)
 		})
@@ -132,7 +256,7 @@ func TestUTF8TextOption_NoCharacterSetSpecified(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := UTF8TextOption().transform(tc.in)
+			got, err := UTF8TextOption().transform(newParseContext(explicitVRLittleEndian), tc.in)
 			if err != nil {
 				t.Fatalf("utf8 option transform: %v", err)
 			}
@@ -142,6 +266,49 @@ func TestUTF8TextOption_NoCharacterSetSpecified(t *testing.T) {
 	}
 }
 
+func TestContextEncodingSystem(t *testing.T) {
+	parentCoding, itemCoding := defaultEncodingSystem(), defaultEncodingSystem()
+	parent := newParseContext(explicitVRLittleEndian)
+	parent.SetValue(encodingSystemKey{}, parentCoding)
+	withCharacterSet := parent.item(ReferencedStudySequenceTag, 0, explicitVRLittleEndian)
+	withCharacterSet.SetValue(encodingSystemKey{}, itemCoding)
+
+	tests := []struct {
+		name string
+		ctx  *ParseContext
+		want *encodingSystem
+	}{
+		{
+			"top level data set uses its specific character set",
+			parent,
+			parentCoding,
+		},
+		{
+			"item with a specific character set uses its own",
+			withCharacterSet,
+			itemCoding,
+		},
+		{
+			"item without a specific character set inherits the enclosing data set's",
+			parent.item(ReferencedStudySequenceTag, 1, explicitVRLittleEndian),
+			parentCoding,
+		},
+		{
+			"nested item without a specific character set inherits the innermost one",
+			withCharacterSet.item(ReferencedImageSequenceTag, 0, explicitVRLittleEndian),
+			itemCoding,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := contextEncodingSystem(tc.ctx); got != tc.want {
+				t.Fatalf("got %p, want %p", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestUTF8Text_encodings(t *testing.T) {
 	// Please refer to the section the DICOM standard linked below for useful explanation of the
 	// character sets
@@ -308,12 +475,13 @@ func TestUTF8Text_encodings(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.characterSetTerm, func(t *testing.T) {
 			opt := UTF8TextOption()
+			ctx := newParseContext(explicitVRLittleEndian)
 			characterSetElement := createCharacterSetElement(tc.characterSetTerm)
-			opt.transform(characterSetElement)
+			opt.transform(ctx, characterSetElement)
 
 			in := &DataElement{ViewNameTag, PNVR, []string{string(tc.encoded)}, uint32(len(tc.encoded))}
 			want := &DataElement{ViewNameTag, PNVR, []string{tc.utf8}, uint32(len(tc.encoded))}
-			got, err := opt.transform(in)
+			got, err := opt.transform(ctx, in)
 			if err != nil {
 				t.Fatalf("transform(_) => %v", err)
 			}
@@ -325,7 +493,7 @@ func TestUTF8Text_encodings(t *testing.T) {
 
 func TestReferenceBulkData(t *testing.T) {
 	length := uint32(len(sampleBytes))
-	refs := []BulkDataReference{{ByteRegion{0, int64(length)}}}
+	refs := []BulkDataReference{{Reference: ByteRegion{0, int64(length)}}}
 	tests := []struct {
 		name  string
 		in    *DataElement
@@ -348,7 +516,7 @@ func TestReferenceBulkData(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := ReferenceBulkData(DefaultBulkDataDefinition).transform(tc.in)
+			got, err := ReferenceBulkData(DefaultBulkDataDefinition).transform(newParseContext(explicitVRLittleEndian), tc.in)
 			if err != nil {
 				t.Fatalf("ReferenceBulkData.Apply(_) => (_, %v)", err)
 			}
@@ -377,7 +545,7 @@ func TestDropGroupLengths(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := DropGroupLengths.transform(tc.in)
+			got, err := DropGroupLengths.transform(newParseContext(explicitVRLittleEndian), tc.in)
 			if err != nil {
 				t.Fatalf("DropGroupLengths.transform(%v) => %v", tc.in, err)
 			}
@@ -405,7 +573,7 @@ func TestDropBasicOffsetTable(t *testing.T) {
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := DropBasicOffsetTable.transform(tc.in)
+			got, err := DropBasicOffsetTable.transform(newParseContext(explicitVRLittleEndian), tc.in)
 			if err != nil {
 				t.Fatalf("DropBasicOffsetTable.Transform(_) => %v", err)
 			}
@@ -462,6 +630,248 @@ func TestNewNativeMultiFrame_frameLengthZeroBanned(t *testing.T) {
 	}
 }
 
+func TestBitPackedMultiFrame_Next(t *testing.T) {
+	// 4 frames of 3 bits, the third of which spans both bytes
+	data := []byte{0xB5, 0x0E, 0, 0}
+	frames, err := newBitPackedMultiFrame(NewBulkDataIterator(bytes.NewReader(data), 10), 3, 4, false)
+	if err != nil {
+		t.Fatalf("newBitPackedMultiFrame: %v", err)
+	}
+
+	want := []struct {
+		bytes     []byte
+		offset    int64
+		bitOffset int64
+	}{
+		{[]byte{0xB5}, 10, 0},
+		{[]byte{0xB5}, 10, 3},
+		{[]byte{0xB5, 0x0E}, 10, 6},
+		{[]byte{0x0E}, 11, 1},
+	}
+	for i, w := range want {
+		frame, err := frames.Next()
+		if err != nil {
+			t.Fatalf("getting frame %d: %v", i, err)
+		}
+		got, err := ioutil.ReadAll(frame)
+		if err != nil {
+			t.Fatalf("reading frame %d: %v", i, err)
+		}
+		if !bytes.Equal(got, w.bytes) || frame.Offset != w.offset || frame.BitOffset != w.bitOffset || frame.BitLength != 3 {
+			t.Fatalf("got frame %d %v at offset %d bit offset %d of %d bits, want %v at offset %d bit offset %d of 3 bits",
+				i, got, frame.Offset, frame.BitOffset, frame.BitLength, w.bytes, w.offset, w.bitOffset)
+		}
+	}
+	if _, err := frames.Next(); err != io.EOF {
+		t.Fatalf("Next() => %v, want io.EOF", err)
+	}
+}
+
+func TestBitPackedMultiFrame_Next_shortFragment(t *testing.T) {
+	frames, err := newBitPackedMultiFrame(createBulkDataIterator([]byte{0xB5}), 16, 1, false)
+	if err != nil {
+		t.Fatalf("newBitPackedMultiFrame: %v", err)
+	}
+	if _, err := frames.Next(); err == nil {
+		t.Fatalf("Next() => nil, expected an error for a frame longer than the fragment")
+	}
+}
+
+func TestBitPackedMultiFrame_ToBuffer(t *testing.T) {
+	frames, err := newBitPackedMultiFrame(createBulkDataIterator([]byte{0xB5, 0x0E}), 3, 4, false)
+	if err != nil {
+		t.Fatalf("newBitPackedMultiFrame: %v", err)
+	}
+	got, err := frames.ToBuffer()
+	if err != nil {
+		t.Fatalf("ToBuffer() => %v", err)
+	}
+	want := NewBitPackedFrameBuffer(3, []byte{0x05}, []byte{0x06}, []byte{0x02}, []byte{0x07})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ToBuffer() => %v, want %v", got, want)
+	}
+}
+
+func TestBitPackedMultiFrame_write(t *testing.T) {
+	data := []byte{0xB5, 0x0E}
+	frames, err := newBitPackedMultiFrame(createBulkDataIterator(data), 3, 4, false)
+	if err != nil {
+		t.Fatalf("newBitPackedMultiFrame: %v", err)
+	}
+	got := &bytes.Buffer{}
+	if err := frames.write(got, explicitVRLittleEndian); err != nil {
+		t.Fatalf("write(_, _) => %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Fatalf("got %v, want %v", got.Bytes(), data)
+	}
+}
+
+func TestBitPackedMultiFrame_swapWords(t *testing.T) {
+	// the big endian OW word of TestBitPackedMultiFrame_ToBuffer
+	iter := newBulkDataIterator(bytes.NewReader([]byte{0x0E, 0xB5}), 0, 2, binary.BigEndian)
+	frames, err := newBitPackedMultiFrame(iter, 3, 4, true)
+	if err != nil {
+		t.Fatalf("newBitPackedMultiFrame: %v", err)
+	}
+	got, err := frames.ToBuffer()
+	if err != nil {
+		t.Fatalf("ToBuffer() => %v", err)
+	}
+	want := NewBitPackedFrameBuffer(3, []byte{0x05}, []byte{0x06}, []byte{0x02}, []byte{0x07})
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ToBuffer() => %v, want %v", got, want)
+	}
+}
+
+func TestSplitUncompressedPixelDataFrames_bitsAllocatedOne(t *testing.T) {
+	want := [][]byte{{0x05}, {0x06}, {0x02}, {0x07}}
+	syntaxes := []string{ExplicitVRLittleEndianUID, ExplicitVRBigEndianUID}
+
+	for _, syntax := range syntaxes {
+		t.Run(syntax, func(t *testing.T) {
+			dataSet := NewDataSet(map[DataElementTag]interface{}{
+				TransferSyntaxUIDTag: []string{syntax},
+				RowsTag:              []uint16{1},
+				ColumnsTag:           []uint16{3},
+				SamplesPerPixelTag:   []uint16{1},
+				BitsAllocatedTag:     []uint16{1},
+				NumberOfFramesTag:    []string{"4"},
+				PixelDataTag:         NewBulkDataBuffer([]byte{0xB5, 0x0E}),
+			})
+			dataSet.Elements[PixelDataTag].VR = OWVR
+			file := &bytes.Buffer{}
+			if err := Construct(file, dataSet); err != nil {
+				t.Fatalf("Construct(_, _) => %v", err)
+			}
+
+			buffered, err := Parse(bytes.NewReader(file.Bytes()), SplitUncompressedPixelDataFrames())
+			if err != nil {
+				t.Fatalf("Parse(_, _) => %v", err)
+			}
+			referenced, err := ParseReaderAt(bytes.NewReader(file.Bytes()), int64(file.Len()),
+				SplitUncompressedPixelDataFrames(), ReferenceBulkData(DefaultBulkDataDefinition))
+			if err != nil {
+				t.Fatalf("ParseReaderAt(_, _, _) => %v", err)
+			}
+
+			for _, ds := range []*DataSet{buffered, referenced} {
+				pixelData, err := NewPixelData(ds)
+				if err != nil {
+					t.Fatalf("NewPixelData(_) => %v", err)
+				}
+				for i, w := range want {
+					got, err := pixelData.NativeFrame(i)
+					if err != nil {
+						t.Fatalf("NativeFrame(%d) => %v", i, err)
+					}
+					if !bytes.Equal(got, w) {
+						t.Fatalf("NativeFrame(%d) => %v, want %v", i, got, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSplitUncompressedPixelDataFrames_hostileBitsAllocatedOne(t *testing.T) {
+	in := []byte{
+		0x28, 0x00, 0x02, 0x00, 'U', 'S', 0x02, 0x00, 0xFF, 0xFF, // SamplesPerPixel
+		0x28, 0x00, 0x10, 0x00, 'U', 'S', 0x02, 0x00, 0xFF, 0xFF, // Rows
+		0x28, 0x00, 0x11, 0x00, 'U', 'S', 0x02, 0x00, 0xFF, 0xFF, // Columns
+		0x28, 0x00, 0x00, 0x01, 'U', 'S', 0x02, 0x00, 0x01, 0x00, // BitsAllocated
+		0xE0, 0x7F, 0x10, 0x00, 'O', 'W', 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02, // PixelData
+	}
+
+	dataSet, err := ParseDataSet(bytes.NewReader(in), ExplicitVRLittleEndianUID, SplitUncompressedPixelDataFrames(),
+		WithLimits(Limits{MaxValueLength: 1 << 20, MaxBytes: 1 << 20}))
+	if err != nil {
+		t.Fatalf("ParseDataSet(_, _, _) => %v", err)
+	}
+	if _, ok := dataSet.Elements[PixelDataTag]; ok {
+		t.Fatalf("got pixel data with frames longer than the pixel data, want it dropped")
+	}
+	if len(dataSet.Diagnostics) != 1 || dataSet.Diagnostics[0].Category != DroppedElement {
+		t.Fatalf("got diagnostics %v, want a DroppedElement diagnostic", dataSet.Diagnostics)
+	}
+}
+
+func TestStopAtTag(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		syntax        transferSyntax
+		in            []byte
+		tag           DataElementTag
+		want          *DataSet
+		wantTruncated bool
+	}{
+		{
+			"elements at and after the tag are excluded",
+			"ExplicitVRLittleEndian.dcm",
+			explicitVRLittleEndian,
+			nil,
+			PixelDataTag,
+			withoutElements(createExpectedDataSet(bufferedPixelData, 198, ExplicitVRLittleEndianUID), PixelDataTag),
+			true,
+		},
+		{
+			"nested elements with greater tags are not affected",
+			"ExplicitVRBigEndian.dcm",
+			explicitVRBigEndian,
+			nil,
+			ReferencedImageSequenceTag,
+			withoutElements(createExpectedDataSet(bufferedPixelData, 198, ExplicitVRBigEndianUID), PixelDataTag),
+			true,
+		},
+		{
+			"deflated data sets are truncated",
+			"DeflatedExplicitVRLittleEndian.dcm",
+			deflatedExplicitVRLittleEndian,
+			nil,
+			PixelDataTag,
+			withoutElements(createExpectedDataSet(bufferedPixelData, 200, DeflatedExplicitVRLittleEndianUID), PixelDataTag),
+			true,
+		},
+		{
+			"the value field of the element at the tag is not read",
+			"ExplicitVRLittleEndian.dcm",
+			explicitVRLittleEndian,
+			mustReadFile("ExplicitVRLittleEndian.dcm", t)[:462],
+			PixelDataTag,
+			withoutElements(createExpectedDataSet(bufferedPixelData, 198, ExplicitVRLittleEndianUID), PixelDataTag),
+			true,
+		},
+		{
+			"when the tag is not reached, the data set is not truncated",
+			"ExplicitVRLittleEndian.dcm",
+			explicitVRLittleEndian,
+			nil,
+			DataElementTag(0xFFFEE000),
+			createExpectedDataSet(bufferedPixelData, 198, ExplicitVRLittleEndianUID),
+			false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in := tc.in
+			if in == nil {
+				in = mustReadFile(tc.file, t)
+			}
+			got, err := Parse(bytes.NewReader(in), StopAtTag(tc.tag))
+			if err != nil {
+				t.Fatalf("Parse(_, StopAtTag(%v)) => %v", tc.tag, err)
+			}
+
+			compareDataSets(got, tc.want, tc.syntax.byteOrder(), t)
+			if got.Truncated != tc.wantTruncated {
+				t.Fatalf("got Truncated %v, want %v", got.Truncated, tc.wantTruncated)
+			}
+		})
+	}
+}
+
 func ExampleParseOption() {
 	p := path.Join("../", "testdata/"+"ImplicitVRLittleEndian.dcm")
 	r, err := os.Open(p)
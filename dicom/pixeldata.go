@@ -0,0 +1,181 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FrameInfo describes the layout of a frame of pixel data in the native format, see the Image
+// Pixel Module in the DICOM standard part3 linked below. Multi-byte samples of native frames are
+// little endian.
+// http://dicom.nema.org/medical/dicom/current/output/html/part03.html#sect_C.7.6.3
+type FrameInfo struct {
+	Rows            int
+	Columns         int
+	SamplesPerPixel int
+	BitsAllocated   int
+
+	// BitsStored is the number of bits of each sample holding pixel data, which may be less than
+	// BitsAllocated. 0 is equivalent to BitsAllocated.
+	BitsStored int
+
+	// HighBit is the most significant bit of each sample holding pixel data. 0 is equivalent to
+	// BitsStored - 1.
+	HighBit int
+
+	// PixelRepresentation is 0 for unsigned samples and 1 for signed samples in two's complement
+	PixelRepresentation int
+
+	// PlanarConfiguration is 0 when the samples of each pixel are interleaved (color-by-pixel) and
+	// 1 when each sample is stored as a separate plane (color-by-plane)
+	PlanarConfiguration int
+}
+
+// NewFrameInfo returns the FrameInfo of the pixel data of the DataSet. Rows, Columns and
+// BitsAllocated are required while SamplesPerPixel, PixelRepresentation and PlanarConfiguration
+// default to 1, 0 and 0, BitsStored defaults to BitsAllocated and HighBit defaults to
+// BitsStored - 1.
+func NewFrameInfo(ds *DataSet) (FrameInfo, error) {
+	values := map[DataElementTag]int64{
+		RowsTag:                1,
+		ColumnsTag:             1,
+		BitsAllocatedTag:       1,
+		BitsStoredTag:          0,
+		HighBitTag:             0,
+		PixelRepresentationTag: 0,
+		SamplesPerPixelTag:     1,
+		PlanarConfigurationTag: 0,
+	}
+	for tag, defaultValue := range values {
+		element, ok := ds.Elements[tag]
+		if !ok {
+			if tag == RowsTag || tag == ColumnsTag || tag == BitsAllocatedTag {
+				return FrameInfo{}, fmt.Errorf("missing required element %v", tag)
+			}
+			continue
+		}
+		v, err := element.IntValue()
+		if err != nil {
+			return FrameInfo{}, fmt.Errorf("reading value of %v: %v", tag, err)
+		}
+		if v < defaultValue || v > 0xFFFF {
+			return FrameInfo{}, fmt.Errorf("invalid value %d of %v", v, tag)
+		}
+		values[tag] = v
+	}
+	if _, ok := ds.Elements[BitsStoredTag]; !ok {
+		values[BitsStoredTag] = values[BitsAllocatedTag]
+	}
+	if values[BitsStoredTag] > values[BitsAllocatedTag] {
+		return FrameInfo{}, fmt.Errorf("BitsStored %d exceeds BitsAllocated %d", values[BitsStoredTag], values[BitsAllocatedTag])
+	}
+	if _, ok := ds.Elements[HighBitTag]; !ok {
+		values[HighBitTag] = values[BitsStoredTag] - 1
+	}
+	if values[HighBitTag] >= values[BitsAllocatedTag] || values[HighBitTag] < values[BitsStoredTag]-1 {
+		return FrameInfo{}, fmt.Errorf("HighBit %d is inconsistent with BitsStored %d and BitsAllocated %d", values[HighBitTag], values[BitsStoredTag], values[BitsAllocatedTag])
+	}
+	if values[PixelRepresentationTag] > 1 {
+		return FrameInfo{}, fmt.Errorf("invalid value %d of %v", values[PixelRepresentationTag], PixelRepresentationTag)
+	}
+	if values[PlanarConfigurationTag] > 1 {
+		return FrameInfo{}, fmt.Errorf("invalid value %d of %v", values[PlanarConfigurationTag], PlanarConfigurationTag)
+	}
+
+	return FrameInfo{
+		Rows:                int(values[RowsTag]),
+		Columns:             int(values[ColumnsTag]),
+		SamplesPerPixel:     int(values[SamplesPerPixelTag]),
+		BitsAllocated:       int(values[BitsAllocatedTag]),
+		BitsStored:          int(values[BitsStoredTag]),
+		HighBit:             int(values[HighBitTag]),
+		PixelRepresentation: int(values[PixelRepresentationTag]),
+		PlanarConfiguration: int(values[PlanarConfigurationTag]),
+	}, nil
+}
+
+// Length returns the number of bytes of a native frame described by the FrameInfo
+func (f FrameInfo) Length() int {
+	return (f.Rows*f.Columns*f.SamplesPerPixel*f.BitsAllocated + 7) / 8
+}
+
+// sampleOffset returns the offset in a native frame of the given sample of the given pixel
+func (f FrameInfo) sampleOffset(pixel, sample int) int {
+	return f.sampleIndex(pixel, sample) * f.BitsAllocated / 8
+}
+
+// sampleIndex returns the index in a native frame of the given sample of the given pixel among all
+// samples of the frame
+func (f FrameInfo) sampleIndex(pixel, sample int) int {
+	if f.PlanarConfiguration == 1 {
+		return sample*f.Rows*f.Columns + pixel
+	}
+	return pixel*f.SamplesPerPixel + sample
+}
+
+// bitsStored returns the number of bits of each sample holding pixel data
+func (f FrameInfo) bitsStored() int {
+	if f.BitsStored == 0 {
+		return f.BitsAllocated
+	}
+	return f.BitsStored
+}
+
+// highBit returns the most significant bit of each sample holding pixel data
+func (f FrameInfo) highBit() int {
+	if f.HighBit == 0 {
+		return f.bitsStored() - 1
+	}
+	return f.HighBit
+}
+
+// sample returns the value of the given sample of the given pixel in a native frame of 1, 8, 16 or
+// 32 bit samples
+func (f FrameInfo) sample(frame []byte, pixel, sample int) int {
+	if f.BitsAllocated == 1 {
+		bit := f.sampleIndex(pixel, sample)
+		return int(frame[bit/8] >> uint(bit%8) & 1)
+	}
+	offset := f.sampleOffset(pixel, sample)
+	switch f.BitsAllocated {
+	case 8:
+		return int(frame[offset])
+	case 16:
+		return int(binary.LittleEndian.Uint16(frame[offset:]))
+	default:
+		return int(binary.LittleEndian.Uint32(frame[offset:]))
+	}
+}
+
+// setSample sets the value of the given sample of the given pixel in a native frame of 1, 8, 16 or
+// 32 bit samples
+func (f FrameInfo) setSample(frame []byte, pixel, sample, v int) {
+	if f.BitsAllocated == 1 {
+		bit := f.sampleIndex(pixel, sample)
+		frame[bit/8] = frame[bit/8]&^(1<<uint(bit%8)) | byte(v&1)<<uint(bit%8)
+		return
+	}
+	offset := f.sampleOffset(pixel, sample)
+	switch f.BitsAllocated {
+	case 8:
+		frame[offset] = byte(v)
+	case 16:
+		binary.LittleEndian.PutUint16(frame[offset:], uint16(v))
+	default:
+		binary.LittleEndian.PutUint32(frame[offset:], uint32(v))
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"testing"
+)
+
+func TestNewFrameInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements map[DataElementTag]interface{}
+		want     FrameInfo
+	}{
+		{
+			"defaults",
+			map[DataElementTag]interface{}{
+				RowsTag:          []uint16{2},
+				ColumnsTag:       []uint16{3},
+				BitsAllocatedTag: []uint16{16},
+			},
+			FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 16, HighBit: 15},
+		},
+		{
+			"color",
+			map[DataElementTag]interface{}{
+				RowsTag:                []uint16{2},
+				ColumnsTag:             []uint16{3},
+				BitsAllocatedTag:       []uint16{16},
+				BitsStoredTag:          []uint16{12},
+				SamplesPerPixelTag:     []uint16{3},
+				PlanarConfigurationTag: []uint16{1},
+			},
+			FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 3, BitsAllocated: 16, BitsStored: 12, HighBit: 11, PlanarConfiguration: 1},
+		},
+		{
+			"signed with high bit",
+			map[DataElementTag]interface{}{
+				RowsTag:                []uint16{2},
+				ColumnsTag:             []uint16{3},
+				BitsAllocatedTag:       []uint16{16},
+				BitsStoredTag:          []uint16{12},
+				HighBitTag:             []uint16{13},
+				PixelRepresentationTag: []uint16{1},
+			},
+			FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 16, BitsStored: 12, HighBit: 13, PixelRepresentation: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewFrameInfo(NewDataSet(tc.elements))
+			if err != nil {
+				t.Fatalf("NewFrameInfo(_) => %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("NewFrameInfo(_) => %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewFrameInfo_invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements map[DataElementTag]interface{}
+	}{
+		{
+			"missing rows",
+			map[DataElementTag]interface{}{
+				ColumnsTag:       []uint16{3},
+				BitsAllocatedTag: []uint16{16},
+			},
+		},
+		{
+			"zero columns",
+			map[DataElementTag]interface{}{
+				RowsTag:          []uint16{2},
+				ColumnsTag:       []uint16{0},
+				BitsAllocatedTag: []uint16{16},
+			},
+		},
+		{
+			"bits stored exceeds bits allocated",
+			map[DataElementTag]interface{}{
+				RowsTag:          []uint16{2},
+				ColumnsTag:       []uint16{3},
+				BitsAllocatedTag: []uint16{8},
+				BitsStoredTag:    []uint16{12},
+			},
+		},
+		{
+			"high bit below bits stored",
+			map[DataElementTag]interface{}{
+				RowsTag:          []uint16{2},
+				ColumnsTag:       []uint16{3},
+				BitsAllocatedTag: []uint16{16},
+				BitsStoredTag:    []uint16{12},
+				HighBitTag:       []uint16{10},
+			},
+		},
+		{
+			"high bit exceeds bits allocated",
+			map[DataElementTag]interface{}{
+				RowsTag:          []uint16{2},
+				ColumnsTag:       []uint16{3},
+				BitsAllocatedTag: []uint16{16},
+				BitsStoredTag:    []uint16{12},
+				HighBitTag:       []uint16{16},
+			},
+		},
+		{
+			"invalid pixel representation",
+			map[DataElementTag]interface{}{
+				RowsTag:                []uint16{2},
+				ColumnsTag:             []uint16{3},
+				BitsAllocatedTag:       []uint16{8},
+				PixelRepresentationTag: []uint16{2},
+			},
+		},
+		{
+			"invalid planar configuration",
+			map[DataElementTag]interface{}{
+				RowsTag:                []uint16{2},
+				ColumnsTag:             []uint16{3},
+				BitsAllocatedTag:       []uint16{8},
+				PlanarConfigurationTag: []uint16{2},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewFrameInfo(NewDataSet(tc.elements)); err == nil {
+				t.Fatalf("NewFrameInfo(_) => nil, expected an error")
+			}
+		})
+	}
+}
+
+func TestFrameInfo_Length(t *testing.T) {
+	tests := []struct {
+		info FrameInfo
+		want int
+	}{
+		{FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 16}, 12},
+		{FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 3, BitsAllocated: 8}, 18},
+		{FrameInfo{Rows: 3, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 1}, 2},
+	}
+
+	for _, tc := range tests {
+		if got := tc.info.Length(); got != tc.want {
+			t.Errorf("%+v.Length() => %d, want %d", tc.info, got, tc.want)
+		}
+	}
+}
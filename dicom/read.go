@@ -64,13 +64,29 @@ func readDataElement(dr *dcmReader, syntax transferSyntax) (*DataElement, error)
 }
 
 func readValue(tag DataElementTag, dr *dcmReader, vr *VR, length uint32, syntax transferSyntax) (interface{}, error) {
+	// PixelData of undefined length is in the encapsulated format whatever its VR is, so that the
+	// fragments of a mislabelled PixelData element are read as such.
+	if tag == PixelDataTag && length == UndefinedLength {
+		return readBulkData(dr, tag, length, syntax.byteOrder())
+	}
+
+	if vr.kind != bulkDataVR && vr.kind != sequenceVR {
+		// Values of these VRs are buffered into memory as they are read.
+		if err := dr.limiter.checkValueLength(tag, int64(length)); err != nil {
+			return nil, err
+		}
+	}
+
 	switch vr.kind {
 	case textVR:
 		return readText(dr, length, vr, unicode.IsSpace)
 	case numberBinaryVR:
 		return readNumberBinary(dr, length, vr, syntax.byteOrder())
 	case bulkDataVR:
-		return readBulkData(dr, tag, length)
+		if isUnknownSequence(tag, vr, length) {
+			return readSequence(dr, length, valueSyntax(vr, syntax))
+		}
+		return readBulkData(dr, tag, length, syntax.byteOrder())
 	case uniqueIdentifierVR:
 		return readText(dr, length, vr, func(r rune) bool {
 			return r == 0x00 || r == ' '
@@ -135,6 +151,10 @@ func readNumberBinary(dr *dcmReader, length uint32, vr *VR, order binary.ByteOrd
 		data = make([]float32, length/4)
 	case FDVR:
 		data = make([]float64, length/8)
+	case SVVR:
+		data = make([]int64, length/8)
+	case UVVR:
+		data = make([]uint64, length/8)
 	default:
 		return nil, fmt.Errorf("unknown vr: %v", vr)
 	}
@@ -146,7 +166,7 @@ func readNumberBinary(dr *dcmReader, length uint32, vr *VR, order binary.ByteOrd
 	return data, nil
 }
 
-func readBulkData(dr *dcmReader, tag DataElementTag, length uint32) (BulkDataIterator, error) {
+func readBulkData(dr *dcmReader, tag DataElementTag, length uint32, order binary.ByteOrder) (BulkDataIterator, error) {
 	if length == UndefinedLength {
 		if tag == PixelDataTag {
 			// Specified in http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_A.4
@@ -159,7 +179,16 @@ func readBulkData(dr *dcmReader, tag DataElementTag, length uint32) (BulkDataIte
 
 	// for native (uncompressed) formats, return regular bulk data stream
 	limitedReader := limitCountReader(dr.cr, int64(length))
-	return NewBulkDataIterator(limitedReader, dr.cr.bytesRead), nil
+	return newBulkDataIterator(limitedReader, dr.cr.bytesRead, -1, order), nil
+}
+
+// isUnknownSequence returns true if the element is of VR UN with undefined length. As specified in
+// CP-246, such elements hold a sequence that was re-encoded as UN (typically a private sequence
+// that was not in the data dictionary of the writer).
+// ftp://medical.nema.org/medical/dicom/final/cp246_ft.pdf
+func isUnknownSequence(tag DataElementTag, vr *VR, length uint32) bool {
+	// PixelData of undefined length is in the encapsulated format
+	return vr == UNVR && length == UndefinedLength && tag != PixelDataTag
 }
 
 func readSequence(dr *dcmReader, length uint32, syntax transferSyntax) (SequenceIterator, error) {
@@ -68,6 +68,62 @@ func TestReadDataElement(t *testing.T) {
 	}
 }
 
+func TestReadDataElement_unknownVR(t *testing.T) {
+	in := []byte{0x09, 0x00, 0x01, 0x10, 'Z', 'Z', 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02}
+
+	element, err := readDataElement(dcmReaderFromBytes(in), explicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("readDataElement(_, _) => %v", err)
+	}
+	if element.VR != UNVR || element.ValueLength != 2 {
+		t.Fatalf("got VR %v and length %v, want %v and %v", element.VR, element.ValueLength, UNVR, 2)
+	}
+	value, err := CollectFragments(element.ValueField.(BulkDataIterator))
+	if err != nil {
+		t.Fatalf("CollectFragments(_) => %v", err)
+	}
+	if want := [][]byte{{0x01, 0x02}}; !reflect.DeepEqual(value, want) {
+		t.Fatalf("got value %v, want %v", value, want)
+	}
+}
+
+func TestReadDataElement_encapsulatedPixelData(t *testing.T) {
+	tests := []struct {
+		name string
+		vr   string
+	}{
+		{"OB VR", "OB"},
+		{"mislabelled SQ VR", "SQ"},
+		{"mislabelled UT VR", "UT"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in := []byte{
+				0xE0, 0x7F, 0x10, 0x00, tc.vr[0], tc.vr[1], 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, // PixelData
+				0xFE, 0xFF, 0x00, 0xE0, 0x00, 0x00, 0x00, 0x00, // Basic Offset Table
+				0xFE, 0xFF, 0x00, 0xE0, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02, // Fragment
+				0xFE, 0xFF, 0xDD, 0xE0, 0x00, 0x00, 0x00, 0x00, // Sequence Delimitation
+			}
+			element, err := readDataElement(dcmReaderFromBytes(in), explicitVRLittleEndian)
+			if err != nil {
+				t.Fatalf("readDataElement(_, _) => %v", err)
+			}
+			iter, ok := element.ValueField.(*encapsulatedFormatIterator)
+			if !ok {
+				t.Fatalf("got ValueField of type %T, want *encapsulatedFormatIterator", element.ValueField)
+			}
+			fragments, err := CollectFragments(iter)
+			if err != nil {
+				t.Fatalf("CollectFragments(_) => %v", err)
+			}
+			if want := [][]byte{{}, {0x01, 0x02}}; !reflect.DeepEqual(fragments, want) {
+				t.Fatalf("got fragments %v, want %v", fragments, want)
+			}
+		})
+	}
+}
+
 func TestGetValueLength(t *testing.T) {
 	// testing format outlined in Table 7.1-1 and 7.1-2 is respected
 	// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_6.2
@@ -106,6 +162,13 @@ func TestGetValueLength(t *testing.T) {
 			explicitVRBigEndian,
 			0x1122,
 		},
+		{
+			"64-bit VRs have 32-bit lengths",
+			[]byte{0x00, 0x00, 0x11, 0x22, 0x33, 0x44},
+			SVVR,
+			explicitVRLittleEndian,
+			0x44332211,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -323,6 +386,22 @@ func TestReadNumberBinary_integers(t *testing.T) {
 			binary.BigEndian,
 			[]uint16{0xABCD, 0x1234},
 		},
+		{
+			"signed very long, little endian",
+			[]byte{0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+			8,
+			SVVR,
+			binary.LittleEndian,
+			[]int64{-2},
+		},
+		{
+			"unsigned very long, big endian",
+			[]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02},
+			8,
+			UVVR,
+			binary.BigEndian,
+			[]uint64{0x0102},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -400,7 +479,7 @@ func TestReadNumberBinary_float(t *testing.T) {
 func TestReadByteSequence(t *testing.T) {
 	expected := []byte{0x01, 0x02, 0x03, 0x00}
 	result, err := readBulkData(
-		dcmReaderFromBytes(expected), 0, 4)
+		dcmReaderFromBytes(expected), 0, 4, binary.LittleEndian)
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -420,3 +499,59 @@ func TestReadByteSequence(t *testing.T) {
 		t.Fatalf("got %v, want %v", data, expected)
 	}
 }
+
+func TestReadDataElement_undefinedLengthUN(t *testing.T) {
+	// The value field of UN elements with undefined length is always implicit VR little endian.
+	unknownSequenceValue := []byte{
+		0xFE, 0xFF, 0x00, 0xE0, // Item Tag
+		0xFF, 0xFF, 0xFF, 0xFF, // Undefined Item Length
+		0x08, 0x00, 0x60, 0x00, // Modality Tag
+		0x02, 0x00, 0x00, 0x00, // Length
+		'M', 'R', // Value
+		0xFE, 0xFF, 0x0D, 0xE0, // Item Delimitation Tag
+		0x00, 0x00, 0x00, 0x00, // Item Delimitation Length
+		0xFE, 0xFF, 0xDD, 0xE0, // Sequence Delimitation Tag
+		0x00, 0x00, 0x00, 0x00, // Sequence Delimitation Length
+	}
+	modality := &DataElement{ModalityTag, CSVR, []string{"MR"}, 2}
+	want := &DataElement{0x00091010, UNVR, &Sequence{[]*DataSet{{
+		Elements: map[DataElementTag]*DataElement{ModalityTag: modality},
+		Length:   UndefinedLength,
+	}}}, UndefinedLength}
+
+	tests := []struct {
+		name   string
+		header []byte
+		syntax transferSyntax
+	}{
+		{
+			"explicit VR little endian",
+			[]byte{0x09, 0x00, 0x10, 0x10, 'U', 'N', 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF},
+			explicitVRLittleEndian,
+		},
+		{
+			"explicit VR big endian",
+			[]byte{0x00, 0x09, 0x10, 0x10, 'U', 'N', 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF},
+			explicitVRBigEndian,
+		},
+		{
+			"implicit VR little endian",
+			[]byte{0x09, 0x00, 0x10, 0x10, 0xFF, 0xFF, 0xFF, 0xFF},
+			implicitVRLittleEndian,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			in := append(append([]byte{}, tc.header...), unknownSequenceValue...)
+			element, err := readDataElement(dcmReaderFromBytes(in), tc.syntax)
+			if err != nil {
+				t.Fatalf("readDataElement(_, _) => %v", err)
+			}
+			if _, ok := element.ValueField.(SequenceIterator); !ok {
+				t.Fatalf("unexpected type %T for ValueField (expected SequenceIterator)", element.ValueField)
+			}
+			compareDataElements(element, want, tc.syntax.byteOrder(), t)
+		})
+	}
+}
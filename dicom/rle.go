@@ -0,0 +1,231 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// rleHeaderLength is the length of the header of an RLE fragment: the number of segments
+	// followed by 15 segment offsets, all 32 bit little endian
+	rleHeaderLength = 64
+
+	// rleMaxSegments is the maximum number of segments of an RLE fragment
+	rleMaxSegments = 15
+
+	// rleMaxRun is the maximum number of bytes of a literal or replicate run
+	rleMaxRun = 128
+)
+
+// DecodeRLE decodes the pixel data in the RLE Lossless transfer syntax read from iter, an iterator
+// over the fragments of the encapsulated format such as those parsed from a DICOM file, into native
+// frames described by info. The first fragment of iter is the Basic Offset Table and is skipped.
+// Each of the remaining fragments holds exactly one frame.
+func DecodeRLE(iter BulkDataIterator, info FrameInfo) ([][]byte, error) {
+	if _, err := iter.Next(); err != nil {
+		return nil, fmt.Errorf("reading basic offset table: %v", err)
+	}
+
+	var frames [][]byte
+	for r, err := iter.Next(); err != io.EOF; r, err = iter.Next() {
+		if err != nil {
+			return nil, fmt.Errorf("reading fragment: %v", err)
+		}
+		fragment, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading fragment: %v", err)
+		}
+		frame, err := DecodeRLEFrame(fragment, info)
+		if err != nil {
+			return nil, fmt.Errorf("decoding frame %d: %v", len(frames), err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// EncodeRLE encodes native frames described by info into the encapsulated format of the RLE
+// Lossless transfer syntax, with one fragment per frame. See EncodeFrames.
+func EncodeRLE(frames [][]byte, info FrameInfo) (BulkDataBuffer, error) {
+	return EncodeFrames(frames, RLELosslessUID, info)
+}
+
+// rleCodec is the Codec of the RLE Lossless transfer syntax
+type rleCodec struct{}
+
+func (rleCodec) Decode(data []byte, info FrameInfo) ([]byte, error) {
+	return DecodeRLEFrame(data, info)
+}
+
+func (rleCodec) Encode(frame []byte, info FrameInfo) ([]byte, error) {
+	return EncodeRLEFrame(frame, info)
+}
+
+// DecodeRLEFrame decodes a fragment in the RLE Lossless transfer syntax into a native frame
+// described by info, as described in the DICOM standard part5 linked below. The fragment holds one
+// segment for each byte of each sample, each compressed with the PackBits algorithm.
+// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#chapter_G
+func DecodeRLEFrame(fragment []byte, info FrameInfo) ([]byte, error) {
+	if err := validateRLEFrameInfo(info); err != nil {
+		return nil, err
+	}
+	if len(fragment) < rleHeaderLength {
+		return nil, fmt.Errorf("fragment of length %d is shorter than the RLE header", len(fragment))
+	}
+
+	bytesPerSample := info.BitsAllocated / 8
+	numSegments := binary.LittleEndian.Uint32(fragment)
+	if want := uint32(info.SamplesPerPixel * bytesPerSample); numSegments != want {
+		return nil, fmt.Errorf("got %d segments, expected %d", numSegments, want)
+	}
+
+	offsets := make([]int, numSegments+1)
+	for i := range offsets[:numSegments] {
+		offsets[i] = int(binary.LittleEndian.Uint32(fragment[4+4*i:]))
+	}
+	offsets[numSegments] = len(fragment)
+	for i := 0; i < int(numSegments); i++ {
+		if offsets[i] < rleHeaderLength || offsets[i] > offsets[i+1] {
+			return nil, fmt.Errorf("invalid offset %d of segment %d", offsets[i], i)
+		}
+	}
+
+	pixels := info.Rows * info.Columns
+	frame := make([]byte, info.Length())
+	for i := 0; i < int(numSegments); i++ {
+		segment, err := decodePackBits(fragment[offsets[i]:offsets[i+1]], pixels)
+		if err != nil {
+			return nil, fmt.Errorf("decoding segment %d: %v", i, err)
+		}
+		// Segments are ordered by sample then from the most to the least significant byte
+		sample, significance := i/bytesPerSample, i%bytesPerSample
+		byteOffset := bytesPerSample - 1 - significance
+		for pixel, b := range segment {
+			frame[info.sampleOffset(pixel, sample)+byteOffset] = b
+		}
+	}
+	return frame, nil
+}
+
+// EncodeRLEFrame encodes a native frame described by info into a fragment in the RLE Lossless
+// transfer syntax. See DecodeRLEFrame.
+func EncodeRLEFrame(frame []byte, info FrameInfo) ([]byte, error) {
+	if err := validateRLEFrameInfo(info); err != nil {
+		return nil, err
+	}
+	if len(frame) != info.Length() {
+		return nil, fmt.Errorf("got frame of length %d, expected %d", len(frame), info.Length())
+	}
+
+	bytesPerSample := info.BitsAllocated / 8
+	numSegments := info.SamplesPerPixel * bytesPerSample
+	fragment := make([]byte, rleHeaderLength)
+	binary.LittleEndian.PutUint32(fragment, uint32(numSegments))
+
+	segment := make([]byte, info.Columns)
+	for i := 0; i < numSegments; i++ {
+		binary.LittleEndian.PutUint32(fragment[4+4*i:], uint32(len(fragment)))
+
+		sample, significance := i/bytesPerSample, i%bytesPerSample
+		byteOffset := bytesPerSample - 1 - significance
+		// Each row is encoded separately so that runs don't cross row boundaries
+		for row := 0; row < info.Rows; row++ {
+			for column := range segment {
+				segment[column] = frame[info.sampleOffset(row*info.Columns+column, sample)+byteOffset]
+			}
+			fragment = encodePackBits(fragment, segment)
+		}
+		// Segments are padded to even length
+		if len(fragment)%2 != 0 {
+			fragment = append(fragment, 0x00)
+		}
+	}
+	return fragment, nil
+}
+
+func validateRLEFrameInfo(info FrameInfo) error {
+	if info.Rows <= 0 || info.Columns <= 0 || info.SamplesPerPixel <= 0 {
+		return fmt.Errorf("invalid frame dimensions %dx%dx%d", info.Rows, info.Columns, info.SamplesPerPixel)
+	}
+	if info.BitsAllocated%8 != 0 || info.BitsAllocated <= 0 {
+		return fmt.Errorf("unsupported BitsAllocated %d", info.BitsAllocated)
+	}
+	if segments := info.SamplesPerPixel * info.BitsAllocated / 8; segments > rleMaxSegments {
+		return fmt.Errorf("%d segments exceed the maximum of %d", segments, rleMaxSegments)
+	}
+	return nil
+}
+
+// decodePackBits decodes the PackBits runs of src until n bytes are decoded. Bytes beyond n, such
+// as the padding of the segment, are ignored.
+func decodePackBits(src []byte, n int) ([]byte, error) {
+	dst := make([]byte, 0, n+rleMaxRun)
+	for i := 0; len(dst) < n; {
+		if i >= len(src) {
+			return nil, fmt.Errorf("segment ended after %d of %d bytes", len(dst), n)
+		}
+		header := int8(src[i])
+		i++
+		switch {
+		case header >= 0:
+			count := int(header) + 1
+			if i+count > len(src) {
+				return nil, fmt.Errorf("literal run of %d bytes exceeds the segment", count)
+			}
+			dst = append(dst, src[i:i+count]...)
+			i += count
+		case header != -128:
+			if i >= len(src) {
+				return nil, fmt.Errorf("replicate run exceeds the segment")
+			}
+			for count := 1 - int(header); count > 0; count-- {
+				dst = append(dst, src[i])
+			}
+			i++
+		}
+	}
+	return dst[:n], nil
+}
+
+// encodePackBits appends the PackBits runs of src to dst. Two or more equal bytes are encoded as
+// a replicate run and any other bytes as a literal run.
+func encodePackBits(dst, src []byte) []byte {
+	for i := 0; i < len(src); {
+		run := 1
+		for i+run < len(src) && src[i+run] == src[i] && run < rleMaxRun {
+			run++
+		}
+		if run > 1 {
+			dst = append(dst, byte(1-run), src[i])
+			i += run
+			continue
+		}
+
+		start := i
+		for i < len(src) && i-start < rleMaxRun {
+			if i+1 < len(src) && src[i] == src[i+1] {
+				break
+			}
+			i++
+		}
+		dst = append(dst, byte(i-start-1))
+		dst = append(dst, src[start:i]...)
+	}
+	return dst
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestDecodePackBits(t *testing.T) {
+	src := []byte{0xFE, 0xAA, 0x02, 0x80, 0x00, 0x2A, 0xFD, 0xAA, 0x80, 0x03, 0x80, 0x00, 0x2A, 0x22,
+		0xF7, 0xAA, 0x00}
+	want := []byte{0xAA, 0xAA, 0xAA, 0x80, 0x00, 0x2A, 0xAA, 0xAA, 0xAA, 0xAA, 0x80, 0x00, 0x2A, 0x22,
+		0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA}
+
+	got, err := decodePackBits(src, len(want))
+	if err != nil {
+		t.Fatalf("decodePackBits(_, %d) => %v", len(want), err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("decodePackBits(_, %d) => %x, want %x", len(want), got, want)
+	}
+}
+
+func TestEncodePackBits(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{
+			"literal run",
+			[]byte{0x01, 0x02, 0x03},
+			[]byte{0x02, 0x01, 0x02, 0x03},
+		},
+		{
+			"replicate run",
+			[]byte{0x07, 0x07, 0x07, 0x07},
+			[]byte{0xFD, 0x07},
+		},
+		{
+			"literal run followed by replicate run",
+			[]byte{0x01, 0x02, 0x03, 0x03},
+			[]byte{0x01, 0x01, 0x02, 0xFF, 0x03},
+		},
+		{
+			"runs longer than 128 bytes are split",
+			bytes.Repeat([]byte{0x05}, 130),
+			[]byte{0x81, 0x05, 0xFF, 0x05},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := encodePackBits(nil, tc.src)
+			if !bytes.Equal(got, tc.want) {
+				t.Fatalf("encodePackBits(nil, %x) => %x, want %x", tc.src, got, tc.want)
+			}
+			decoded, err := decodePackBits(got, len(tc.src))
+			if err != nil || !bytes.Equal(decoded, tc.src) {
+				t.Fatalf("decodePackBits(%x, %d) => %x, %v, want %x", got, len(tc.src), decoded, err, tc.src)
+			}
+		})
+	}
+}
+
+func TestDecodeRLEFrame(t *testing.T) {
+	// 2 pixels of 16 bit samples: the first segment holds the most significant bytes
+	header := make([]byte, rleHeaderLength)
+	binary.LittleEndian.PutUint32(header, 2)
+	binary.LittleEndian.PutUint32(header[4:], 64)
+	binary.LittleEndian.PutUint32(header[8:], 68)
+	fragment := append(header, 0x01, 0x01, 0x02, 0x00, 0xFF, 0x03)
+	info := FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16}
+
+	got, err := DecodeRLEFrame(fragment, info)
+	if err != nil {
+		t.Fatalf("DecodeRLEFrame(_, %v) => %v", info, err)
+	}
+	if want := []byte{0x03, 0x01, 0x03, 0x02}; !bytes.Equal(got, want) {
+		t.Fatalf("DecodeRLEFrame(_, %v) => %x, want %x", info, got, want)
+	}
+}
+
+func TestRLEFrame_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		info FrameInfo
+	}{
+		{"8 bit", FrameInfo{Rows: 3, Columns: 5, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"16 bit", FrameInfo{Rows: 4, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 16}},
+		{"32 bit", FrameInfo{Rows: 2, Columns: 3, SamplesPerPixel: 1, BitsAllocated: 32}},
+		{"8 bit color-by-pixel", FrameInfo{Rows: 3, Columns: 3, SamplesPerPixel: 3, BitsAllocated: 8}},
+		{"8 bit color-by-plane", FrameInfo{Rows: 3, Columns: 3, SamplesPerPixel: 3, BitsAllocated: 8, PlanarConfiguration: 1}},
+		{"16 bit color-by-pixel", FrameInfo{Rows: 2, Columns: 7, SamplesPerPixel: 3, BitsAllocated: 16}},
+		{"32 bit color-by-plane", FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 32, PlanarConfiguration: 1}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			frame := make([]byte, tc.info.Length())
+			for i := range frame {
+				// mix of runs and literals
+				frame[i] = byte(i / 3 * 7)
+			}
+
+			fragment, err := EncodeRLEFrame(frame, tc.info)
+			if err != nil {
+				t.Fatalf("EncodeRLEFrame(_, %v) => %v", tc.info, err)
+			}
+			if len(fragment)%2 != 0 {
+				t.Errorf("got fragment of odd length %d", len(fragment))
+			}
+			if got, want := binary.LittleEndian.Uint32(fragment), uint32(tc.info.SamplesPerPixel*tc.info.BitsAllocated/8); got != want {
+				t.Errorf("got %d segments, want %d", got, want)
+			}
+
+			got, err := DecodeRLEFrame(fragment, tc.info)
+			if err != nil {
+				t.Fatalf("DecodeRLEFrame(_, %v) => %v", tc.info, err)
+			}
+			if !bytes.Equal(got, frame) {
+				t.Fatalf("DecodeRLEFrame(EncodeRLEFrame(%x)) => %x", frame, got)
+			}
+		})
+	}
+}
+
+func TestRLEFrame_invalid(t *testing.T) {
+	info := FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8}
+	valid, err := EncodeRLEFrame([]byte{0x01, 0x02}, info)
+	if err != nil {
+		t.Fatalf("EncodeRLEFrame(_, %v) => %v", info, err)
+	}
+
+	badOffset := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(badOffset[4:], 1000)
+
+	tests := []struct {
+		name     string
+		fragment []byte
+		info     FrameInfo
+	}{
+		{"short header", valid[:10], info},
+		{"segment count mismatch", valid, FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 16}},
+		{"offset out of bounds", badOffset, info},
+		{"truncated segment", valid[:rleHeaderLength+1], info},
+		{"too many pixels", valid, FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 8}},
+		{"unsupported bits allocated", valid, FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 1, BitsAllocated: 12}},
+		{"too many segments", valid, FrameInfo{Rows: 1, Columns: 2, SamplesPerPixel: 4, BitsAllocated: 32}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := DecodeRLEFrame(tc.fragment, tc.info); err == nil {
+				t.Fatalf("DecodeRLEFrame(_, %v) => nil, expected an error", tc.info)
+			}
+		})
+	}
+
+	if _, err := EncodeRLEFrame([]byte{0x01}, info); err == nil {
+		t.Fatalf("EncodeRLEFrame(_, %v) with short frame => nil, expected an error", info)
+	}
+}
+
+func TestEncodeRLE_construct(t *testing.T) {
+	info := FrameInfo{Rows: 2, Columns: 2, SamplesPerPixel: 3, BitsAllocated: 16}
+	frames := [][]byte{
+		bytes.Repeat([]byte{0x01, 0x02}, 12),
+		bytes.Repeat([]byte{0x03, 0x04, 0x05}, 8),
+	}
+	pixelData, err := EncodeRLE(frames, info)
+	if err != nil {
+		t.Fatalf("EncodeRLE(_, %v) => %v", info, err)
+	}
+
+	dataSet := NewDataSet(map[DataElementTag]interface{}{
+		TransferSyntaxUIDTag: []string{RLELosslessUID},
+		PixelDataTag:         pixelData,
+	})
+	dataSet.Elements[PixelDataTag].VR = OBVR
+	file := &bytes.Buffer{}
+	if err := Construct(file, dataSet); err != nil {
+		t.Fatalf("Construct(_, _) => %v", err)
+	}
+
+	iter, err := NewDataElementIterator(file)
+	if err != nil {
+		t.Fatalf("NewDataElementIterator(_) => %v", err)
+	}
+	defer iter.Close()
+	for element, err := iter.Next(); err != io.EOF; element, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("iter.Next() => %v", err)
+		}
+		if element.Tag != PixelDataTag {
+			continue
+		}
+		got, err := DecodeRLE(element.ValueField.(BulkDataIterator), info)
+		if err != nil {
+			t.Fatalf("DecodeRLE(_, %v) => %v", info, err)
+		}
+		if !reflect.DeepEqual(got, frames) {
+			t.Fatalf("DecodeRLE(_, %v) => %x, want %x", info, got, frames)
+		}
+		return
+	}
+	t.Fatalf("pixel data not found")
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"fmt"
+	"io"
+)
+
+// Transcode reads the DICOM file from r and writes it to w in the transfer syntax identified by
+// targetSyntaxUID. Both the transfer syntax of the file and the target transfer syntax must store
+// pixel data in the native format. The options are applied to the written DataElements as in
+// Construct.
+//
+// Bulk data, such as the pixel data, is streamed from r to w without being buffered into memory.
+// Values of OW, OF, OL, OD and OV DataElements are byte swapped when the byte order changes, see
+// BulkDataIterator.ByteOrder, and the VRs of DataElements read from the implicit VR syntax are the
+// VRs of the data dictionary.
+// The TransferSyntaxUID and FileMetaInformationGroupLength elements of the meta header are
+// rewritten. Group length elements outside the meta header, which are retired, are dropped since
+// their values depend on the transfer syntax.
+func Transcode(r io.Reader, w io.Writer, targetSyntaxUID string, opts ...ConstructOption) error {
+	target, err := LookupTransferSyntax(targetSyntaxUID)
+	if err != nil {
+		return fmt.Errorf("looking up target transfer syntax: %v", err)
+	}
+	if target.Encapsulated {
+		return fmt.Errorf("transcoding to %v is not supported: pixel data is encapsulated", target)
+	}
+
+	iter, err := NewDataElementIterator(r)
+	if err != nil {
+		return fmt.Errorf("creating data element iterator: %v", err)
+	}
+	defer iter.Close()
+
+	header := &DataSet{Elements: map[DataElementTag]*DataElement{}}
+	element, err := iter.Next()
+	for ; err == nil && element.Tag.IsMetaElement(); element, err = iter.Next() {
+		buffered, err := processElement(element, newParseContext(explicitVRLittleEndian))
+		if err != nil {
+			return fmt.Errorf("reading meta element %v: %v", element.Tag, err)
+		}
+		header.Elements[element.Tag] = buffered
+	}
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("reading meta header: %v", err)
+	}
+
+	if source, err := header.TransferSyntax(); err == nil && source.Encapsulated {
+		return fmt.Errorf("transcoding from %v is not supported: pixel data is encapsulated", source)
+	}
+	header.Elements[TransferSyntaxUIDTag] = &DataElement{
+		Tag:         TransferSyntaxUIDTag,
+		VR:          UIVR,
+		ValueField:  []string{targetSyntaxUID},
+		ValueLength: uint32(len(targetSyntaxUID)),
+	}
+
+	writer, err := NewDataElementWriter(w, header, opts...)
+	if err != nil {
+		return fmt.Errorf("creating DataElementWriter: %v", err)
+	}
+
+	ctx := newParseContext(iter.syntax())
+	for ; err != io.EOF; element, err = iter.Next() {
+		if err != nil {
+			return fmt.Errorf("reading data element: %v", err)
+		}
+		transcoded, err := transcodeElement(element, ctx)
+		if err != nil {
+			return fmt.Errorf("transcoding data element %v: %v", element.Tag, err)
+		}
+		if transcoded == nil {
+			continue
+		}
+		if err := writer.WriteElement(transcoded); err != nil {
+			return fmt.Errorf("writing data element %v: %v", element.Tag, err)
+		}
+	}
+
+	if err := closeDataElementWriter(writer); err != nil {
+		return fmt.Errorf("closing DataElementWriter: %v", err)
+	}
+	return nil
+}
+
+// transcodeElement prepares an element of the top level data set for writing in another transfer
+// syntax. Bulk data is streamed, while sequences are buffered into memory. nil is returned for
+// elements that are dropped.
+func transcodeElement(element *DataElement, ctx *ParseContext) (*DataElement, error) {
+	if isGroupLength(element.Tag) {
+		return nil, nil
+	}
+
+	switch v := element.ValueField.(type) {
+	case SequenceIterator:
+		return processElement(element, ctx, dropGroupLengths)
+	case BulkDataIterator:
+		if element.ValueLength == UndefinedLength {
+			return nil, fmt.Errorf("encapsulated pixel data can't be transcoded")
+		}
+		r, err := v.Next()
+		if err != nil {
+			return nil, fmt.Errorf("reading bulk data: %v", err)
+		}
+		return &DataElement{
+			Tag:         element.Tag,
+			VR:          element.VR,
+			ValueField:  newBulkDataIterator(r, r.Offset, int64(element.ValueLength), v.ByteOrder()),
+			ValueLength: element.ValueLength,
+		}, nil
+	default:
+		return element, nil
+	}
+}
+
+// dropGroupLengths drops the group length elements of sequence items
+var dropGroupLengths = ParseOptionWithTransform(func(element *DataElement) (*DataElement, error) {
+	if isGroupLength(element.Tag) {
+		return nil, nil
+	}
+	return element, nil
+})
+
+func isGroupLength(tag DataElementTag) bool {
+	return tag.ElementNumber() == 0 && !tag.IsMetaElement()
+}
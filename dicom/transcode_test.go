@@ -0,0 +1,195 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dicom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTranscode(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		targetUID string
+		want      string
+	}{
+		{
+			"implicit VR little endian to explicit VR little endian",
+			"ImplicitVRLittleEndian.dcm",
+			ExplicitVRLittleEndianUID,
+			"ExplicitVRLittleEndian.dcm",
+		},
+		{
+			"explicit VR little endian to implicit VR little endian",
+			"ExplicitVRLittleEndian.dcm",
+			ImplicitVRLittleEndianUID,
+			"ImplicitVRLittleEndian.dcm",
+		},
+		{
+			"explicit VR big endian to explicit VR little endian",
+			"ExplicitVRBigEndian.dcm",
+			ExplicitVRLittleEndianUID,
+			"ExplicitVRLittleEndian.dcm",
+		},
+		{
+			"explicit VR little endian to explicit VR big endian",
+			"ExplicitVRLittleEndian.dcm",
+			ExplicitVRBigEndianUID,
+			"ExplicitVRBigEndian.dcm",
+		},
+		{
+			"explicit VR little endian to deflated explicit VR little endian",
+			"ExplicitVRLittleEndian.dcm",
+			DeflatedExplicitVRLittleEndianUID,
+			"DeflatedExplicitVRLittleEndian.dcm",
+		},
+		{
+			"deflated explicit VR little endian to implicit VR little endian",
+			"DeflatedExplicitVRLittleEndian.dcm",
+			ImplicitVRLittleEndianUID,
+			"ImplicitVRLittleEndian.dcm",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := openFile(tc.file)
+			if err != nil {
+				t.Fatalf("opening test file: %v", err)
+			}
+
+			w := &bytes.Buffer{}
+			if err := Transcode(f, w, tc.targetUID); err != nil {
+				t.Fatalf("Transcode(_, _, %q) => %v", tc.targetUID, err)
+			}
+
+			got, err := Parse(w)
+			if err != nil {
+				t.Fatalf("Parse(_) => %v", err)
+			}
+			target, err := LookupTransferSyntax(tc.targetUID)
+			if err != nil {
+				t.Fatalf("LookupTransferSyntax(%q) => %v", tc.targetUID, err)
+			}
+			compareDataSets(got, parse(tc.want, t), target.ByteOrder, t)
+		})
+	}
+}
+
+func TestTranscode_byteOrder(t *testing.T) {
+	dataSet := NewDataSet(map[DataElementTag]interface{}{
+		TransferSyntaxUIDTag:       []string{ExplicitVRLittleEndianUID},
+		DataElementTag(0x00280000): []uint32{10},
+		RowsTag:                    []uint16{0x0102},
+		FloatPixelDataTag:          NewBulkDataBuffer([]byte{0x01, 0x02, 0x03, 0x04}),
+		PixelDataTag:               NewBulkDataBuffer([]byte{0x01, 0x02, 0x03, 0x04}),
+		ReferencedImageSequenceTag: &Sequence{Items: []*DataSet{NewDataSet(map[DataElementTag]interface{}{
+			DataElementTag(0x00080000):        []uint32{10},
+			RedPaletteColorLookupTableDataTag: NewBulkDataBuffer([]byte{0x05, 0x06}),
+		})}},
+	})
+	for _, ds := range []*DataSet{dataSet, dataSet.Elements[ReferencedImageSequenceTag].ValueField.(*Sequence).Items[0]} {
+		for tag, element := range ds.Elements {
+			if isGroupLength(tag) {
+				element.VR = ULVR
+			}
+		}
+	}
+	dataSet.Elements[PixelDataTag].VR = OWVR
+	file := &bytes.Buffer{}
+	if err := Construct(file, dataSet); err != nil {
+		t.Fatalf("Construct(_, _) => %v", err)
+	}
+
+	// One byte reads check that words are swapped across reads
+	transcoded := &bytes.Buffer{}
+	if err := Transcode(iotest.OneByteReader(file), transcoded, ExplicitVRBigEndianUID); err != nil {
+		t.Fatalf("Transcode(_, _, _) => %v", err)
+	}
+	got, err := Parse(transcoded)
+	if err != nil {
+		t.Fatalf("Parse(_) => %v", err)
+	}
+
+	want := map[DataElementTag]interface{}{
+		RowsTag:           []uint16{0x0102},
+		FloatPixelDataTag: []float32{math.Float32frombits(0x04030201)},
+		PixelDataTag:      NewBulkDataBufferWithByteOrder(binary.BigEndian, []byte{0x02, 0x01, 0x04, 0x03}),
+	}
+	for tag, value := range want {
+		if got := got.Elements[tag].ValueField; !reflect.DeepEqual(got, value) {
+			t.Errorf("got %v = %v, want %v", tag, got, value)
+		}
+	}
+
+	if words, err := got.Elements[PixelDataTag].Uint16Values(); err != nil || !reflect.DeepEqual(words, []uint16{0x0201, 0x0403}) {
+		t.Errorf("got PixelData words %x (err %v), want %x", words, err, []uint16{0x0201, 0x0403})
+	}
+
+	item := got.Elements[ReferencedImageSequenceTag].ValueField.(*Sequence).Items[0]
+	if got, want := item.Elements[RedPaletteColorLookupTableDataTag].ValueField, NewBulkDataBufferWithByteOrder(binary.BigEndian, []byte{0x06, 0x05}); !reflect.DeepEqual(got, want) {
+		t.Errorf("got nested OW value %v, want %v", got, want)
+	}
+	for _, ds := range []*DataSet{got, item} {
+		for tag := range ds.Elements {
+			if isGroupLength(tag) {
+				t.Errorf("got group length element %v, want it dropped", tag)
+			}
+		}
+	}
+}
+
+func TestTranscode_invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		targetUID string
+	}{
+		{
+			"unknown target transfer syntax",
+			"ExplicitVRLittleEndian.dcm",
+			"1.2.3.4",
+		},
+		{
+			"encapsulated target transfer syntax",
+			"ExplicitVRLittleEndian.dcm",
+			JPEGBaselineUID,
+		},
+		{
+			"encapsulated source transfer syntax",
+			"MultiFrameCompressed.dcm",
+			ExplicitVRLittleEndianUID,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := openFile(tc.file)
+			if err != nil {
+				t.Fatalf("opening test file: %v", err)
+			}
+
+			if err := Transcode(f, ioutil.Discard, tc.targetUID); err == nil {
+				t.Fatalf("Transcode(_, _, %q) => nil, expected an error", tc.targetUID)
+			}
+		})
+	}
+}
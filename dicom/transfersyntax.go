@@ -18,35 +18,325 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"sync"
 )
 
 // list of transfer syntaxes obtained from
-// http://dicom.nema.org/medical/dicom/current/output/html/part06.html#chapter_A
+// http://dicom.nema.org/medical/dicom/current/output/html/part06.html#chapter_A. Retired transfer
+// syntaxes are included so that older files are recognized. The transfer syntaxes of the XML and
+// MIME encodings are not listed since such files are not in the binary format read by this package.
 const (
 	// ImplicitVRLittleEndianUID is the Implicit VR Little Endian UID
 	ImplicitVRLittleEndianUID = "1.2.840.10008.1.2"
 	// ExplicitVRLittleEndianUID is the Explicit VR Little Endian UID
 	ExplicitVRLittleEndianUID = "1.2.840.10008.1.2.1"
-	// ExplicitVRBigEndianUID is the Explicit VR Big Endian UID
-	ExplicitVRBigEndianUID = "1.2.840.10008.1.2.2"
+	// EncapsulatedUncompressedExplicitVRLittleEndianUID is the Encapsulated Uncompressed Explicit VR Little Endian transfer syntax UID
+	EncapsulatedUncompressedExplicitVRLittleEndianUID = "1.2.840.10008.1.2.1.98"
 	// DeflatedExplicitVRLittleEndianUID is the Deflated Explicit VR Little Endian UID
 	DeflatedExplicitVRLittleEndianUID = "1.2.840.10008.1.2.1.99"
+	// ExplicitVRBigEndianUID is the Explicit VR Big Endian UID
+	ExplicitVRBigEndianUID = "1.2.840.10008.1.2.2"
 	// JPEGBaselineUID is the JPEG Baseline (Process 1) transfer syntax UID
 	JPEGBaselineUID = "1.2.840.10008.1.2.4.50"
+	// JPEGExtended24UID is the JPEG Extended (Process 2 & 4) transfer syntax UID
+	JPEGExtended24UID = "1.2.840.10008.1.2.4.51"
+	// JPEGExtended35UID is the JPEG Extended (Process 3 & 5) transfer syntax UID
+	JPEGExtended35UID = "1.2.840.10008.1.2.4.52"
+	// JPEGSpectralSelectionNonHierarchical68UID is the JPEG Spectral Selection, Non-Hierarchical (Process 6 & 8) transfer syntax UID
+	JPEGSpectralSelectionNonHierarchical68UID = "1.2.840.10008.1.2.4.53"
+	// JPEGSpectralSelectionNonHierarchical79UID is the JPEG Spectral Selection, Non-Hierarchical (Process 7 & 9) transfer syntax UID
+	JPEGSpectralSelectionNonHierarchical79UID = "1.2.840.10008.1.2.4.54"
+	// JPEGFullProgressionNonHierarchical1012UID is the JPEG Full Progression, Non-Hierarchical (Process 10 & 12) transfer syntax UID
+	JPEGFullProgressionNonHierarchical1012UID = "1.2.840.10008.1.2.4.55"
+	// JPEGFullProgressionNonHierarchical1113UID is the JPEG Full Progression, Non-Hierarchical (Process 11 & 13) transfer syntax UID
+	JPEGFullProgressionNonHierarchical1113UID = "1.2.840.10008.1.2.4.56"
+	// JPEGLosslessUID is the JPEG Lossless, Non-Hierarchical (Process 14) transfer syntax UID
+	JPEGLosslessUID = "1.2.840.10008.1.2.4.57"
+	// JPEGLosslessNonHierarchical15UID is the JPEG Lossless, Non-Hierarchical (Process 15) transfer syntax UID
+	JPEGLosslessNonHierarchical15UID = "1.2.840.10008.1.2.4.58"
+	// JPEGExtendedHierarchical1618UID is the JPEG Extended, Hierarchical (Process 16 & 18) transfer syntax UID
+	JPEGExtendedHierarchical1618UID = "1.2.840.10008.1.2.4.59"
+	// JPEGExtendedHierarchical1719UID is the JPEG Extended, Hierarchical (Process 17 & 19) transfer syntax UID
+	JPEGExtendedHierarchical1719UID = "1.2.840.10008.1.2.4.60"
+	// JPEGSpectralSelectionHierarchical2022UID is the JPEG Spectral Selection, Hierarchical (Process 20 & 22) transfer syntax UID
+	JPEGSpectralSelectionHierarchical2022UID = "1.2.840.10008.1.2.4.61"
+	// JPEGSpectralSelectionHierarchical2123UID is the JPEG Spectral Selection, Hierarchical (Process 21 & 23) transfer syntax UID
+	JPEGSpectralSelectionHierarchical2123UID = "1.2.840.10008.1.2.4.62"
+	// JPEGFullProgressionHierarchical2426UID is the JPEG Full Progression, Hierarchical (Process 24 & 26) transfer syntax UID
+	JPEGFullProgressionHierarchical2426UID = "1.2.840.10008.1.2.4.63"
+	// JPEGFullProgressionHierarchical2527UID is the JPEG Full Progression, Hierarchical (Process 25 & 27) transfer syntax UID
+	JPEGFullProgressionHierarchical2527UID = "1.2.840.10008.1.2.4.64"
+	// JPEGLosslessHierarchical28UID is the JPEG Lossless, Hierarchical (Process 28) transfer syntax UID
+	JPEGLosslessHierarchical28UID = "1.2.840.10008.1.2.4.65"
+	// JPEGLosslessHierarchical29UID is the JPEG Lossless, Hierarchical (Process 29) transfer syntax UID
+	JPEGLosslessHierarchical29UID = "1.2.840.10008.1.2.4.66"
+	// JPEGLosslessSV1UID is the JPEG Lossless, Non-Hierarchical, First-Order Prediction (Process 14 [Selection Value 1]) transfer syntax UID
+	JPEGLosslessSV1UID = "1.2.840.10008.1.2.4.70"
+	// JPEGLSLosslessUID is the JPEG-LS Lossless Image Compression transfer syntax UID
+	JPEGLSLosslessUID = "1.2.840.10008.1.2.4.80"
+	// JPEGLSNearLosslessUID is the JPEG-LS Lossy (Near-Lossless) Image Compression transfer syntax UID
+	JPEGLSNearLosslessUID = "1.2.840.10008.1.2.4.81"
+	// JPEG2000LosslessUID is the JPEG 2000 Image Compression (Lossless Only) transfer syntax UID
+	JPEG2000LosslessUID = "1.2.840.10008.1.2.4.90"
+	// JPEG2000UID is the JPEG 2000 Image Compression transfer syntax UID
+	JPEG2000UID = "1.2.840.10008.1.2.4.91"
+	// JPEG2000MultiComponentLosslessUID is the JPEG 2000 Part 2 Multi-component Image Compression (Lossless Only) transfer syntax UID
+	JPEG2000MultiComponentLosslessUID = "1.2.840.10008.1.2.4.92"
+	// JPEG2000MultiComponentUID is the JPEG 2000 Part 2 Multi-component Image Compression transfer syntax UID
+	JPEG2000MultiComponentUID = "1.2.840.10008.1.2.4.93"
+	// JPIPReferencedUID is the JPIP Referenced transfer syntax UID
+	JPIPReferencedUID = "1.2.840.10008.1.2.4.94"
+	// JPIPReferencedDeflateUID is the JPIP Referenced Deflate transfer syntax UID
+	JPIPReferencedDeflateUID = "1.2.840.10008.1.2.4.95"
+	// MPEG2MainProfileMainLevelUID is the MPEG2 Main Profile / Main Level transfer syntax UID
+	MPEG2MainProfileMainLevelUID = "1.2.840.10008.1.2.4.100"
+	// FragmentableMPEG2MainProfileMainLevelUID is the Fragmentable MPEG2 Main Profile / Main Level transfer syntax UID
+	FragmentableMPEG2MainProfileMainLevelUID = "1.2.840.10008.1.2.4.100.1"
+	// MPEG2MainProfileHighLevelUID is the MPEG2 Main Profile / High Level transfer syntax UID
+	MPEG2MainProfileHighLevelUID = "1.2.840.10008.1.2.4.101"
+	// FragmentableMPEG2MainProfileHighLevelUID is the Fragmentable MPEG2 Main Profile / High Level transfer syntax UID
+	FragmentableMPEG2MainProfileHighLevelUID = "1.2.840.10008.1.2.4.101.1"
+	// MPEG4HighProfileLevel41UID is the MPEG-4 AVC/H.264 High Profile / Level 4.1 transfer syntax UID
+	MPEG4HighProfileLevel41UID = "1.2.840.10008.1.2.4.102"
+	// FragmentableMPEG4HighProfileLevel41UID is the Fragmentable MPEG-4 AVC/H.264 High Profile / Level 4.1 transfer syntax UID
+	FragmentableMPEG4HighProfileLevel41UID = "1.2.840.10008.1.2.4.102.1"
+	// MPEG4BDCompatibleHighProfileLevel41UID is the MPEG-4 AVC/H.264 BD-compatible High Profile / Level 4.1 transfer syntax UID
+	MPEG4BDCompatibleHighProfileLevel41UID = "1.2.840.10008.1.2.4.103"
+	// FragmentableMPEG4BDCompatibleHighProfileLevel41UID is the Fragmentable MPEG-4 AVC/H.264 BD-compatible High Profile / Level 4.1 transfer syntax UID
+	FragmentableMPEG4BDCompatibleHighProfileLevel41UID = "1.2.840.10008.1.2.4.103.1"
+	// MPEG4HighProfileLevel42For2DVideoUID is the MPEG-4 AVC/H.264 High Profile / Level 4.2 For 2D Video transfer syntax UID
+	MPEG4HighProfileLevel42For2DVideoUID = "1.2.840.10008.1.2.4.104"
+	// FragmentableMPEG4HighProfileLevel42For2DVideoUID is the Fragmentable MPEG-4 AVC/H.264 High Profile / Level 4.2 For 2D Video transfer syntax UID
+	FragmentableMPEG4HighProfileLevel42For2DVideoUID = "1.2.840.10008.1.2.4.104.1"
+	// MPEG4HighProfileLevel42For3DVideoUID is the MPEG-4 AVC/H.264 High Profile / Level 4.2 For 3D Video transfer syntax UID
+	MPEG4HighProfileLevel42For3DVideoUID = "1.2.840.10008.1.2.4.105"
+	// FragmentableMPEG4HighProfileLevel42For3DVideoUID is the Fragmentable MPEG-4 AVC/H.264 High Profile / Level 4.2 For 3D Video transfer syntax UID
+	FragmentableMPEG4HighProfileLevel42For3DVideoUID = "1.2.840.10008.1.2.4.105.1"
+	// MPEG4StereoHighProfileLevel42UID is the MPEG-4 AVC/H.264 Stereo High Profile / Level 4.2 transfer syntax UID
+	MPEG4StereoHighProfileLevel42UID = "1.2.840.10008.1.2.4.106"
+	// FragmentableMPEG4StereoHighProfileLevel42UID is the Fragmentable MPEG-4 AVC/H.264 Stereo High Profile / Level 4.2 transfer syntax UID
+	FragmentableMPEG4StereoHighProfileLevel42UID = "1.2.840.10008.1.2.4.106.1"
+	// HEVCMainProfileLevel51UID is the HEVC/H.265 Main Profile / Level 5.1 transfer syntax UID
+	HEVCMainProfileLevel51UID = "1.2.840.10008.1.2.4.107"
+	// HEVCMain10ProfileLevel51UID is the HEVC/H.265 Main 10 Profile / Level 5.1 transfer syntax UID
+	HEVCMain10ProfileLevel51UID = "1.2.840.10008.1.2.4.108"
+	// JPEGXLLosslessUID is the JPEG XL Lossless transfer syntax UID
+	JPEGXLLosslessUID = "1.2.840.10008.1.2.4.110"
+	// JPEGXLJPEGRecompressionUID is the JPEG XL JPEG Recompression transfer syntax UID
+	JPEGXLJPEGRecompressionUID = "1.2.840.10008.1.2.4.111"
+	// JPEGXLUID is the JPEG XL transfer syntax UID
+	JPEGXLUID = "1.2.840.10008.1.2.4.112"
+	// HTJ2KLosslessUID is the High-Throughput JPEG 2000 Image Compression (Lossless Only) transfer syntax UID
+	HTJ2KLosslessUID = "1.2.840.10008.1.2.4.201"
+	// HTJ2KLosslessRPCLUID is the High-Throughput JPEG 2000 with RPCL Options Image Compression (Lossless Only) transfer syntax UID
+	HTJ2KLosslessRPCLUID = "1.2.840.10008.1.2.4.202"
+	// HTJ2KUID is the High-Throughput JPEG 2000 Image Compression transfer syntax UID
+	HTJ2KUID = "1.2.840.10008.1.2.4.203"
+	// JPIPHTJ2KReferencedUID is the JPIP HTJ2K Referenced transfer syntax UID
+	JPIPHTJ2KReferencedUID = "1.2.840.10008.1.2.4.204"
+	// JPIPHTJ2KReferencedDeflateUID is the JPIP HTJ2K Referenced Deflate transfer syntax UID
+	JPIPHTJ2KReferencedDeflateUID = "1.2.840.10008.1.2.4.205"
+	// RLELosslessUID is the RLE Lossless transfer syntax UID
+	RLELosslessUID = "1.2.840.10008.1.2.5"
+	// SMPTEST211020UncompressedProgressiveActiveVideoUID is the SMPTE ST 2110-20 Uncompressed Progressive Active Video transfer syntax UID
+	SMPTEST211020UncompressedProgressiveActiveVideoUID = "1.2.840.10008.1.2.7.1"
+	// SMPTEST211020UncompressedInterlacedActiveVideoUID is the SMPTE ST 2110-20 Uncompressed Interlaced Active Video transfer syntax UID
+	SMPTEST211020UncompressedInterlacedActiveVideoUID = "1.2.840.10008.1.2.7.2"
+	// SMPTEST211030PCMDigitalAudioUID is the SMPTE ST 2110-30 PCM Digital Audio transfer syntax UID
+	SMPTEST211030PCMDigitalAudioUID = "1.2.840.10008.1.2.7.3"
+	// DeflatedImageFrameCompressionUID is the Deflated Image Frame Compression transfer syntax UID
+	DeflatedImageFrameCompressionUID = "1.2.840.10008.1.2.8.1"
+	// Papyrus3ImplicitVRLittleEndianUID is the Papyrus 3 Implicit VR Little Endian transfer syntax UID
+	Papyrus3ImplicitVRLittleEndianUID = "1.2.840.10008.1.20"
 )
 
-func lookupTransferSyntax(uid string) transferSyntax {
-	if uid == ExplicitVRLittleEndianUID {
-		return explicitVRLittleEndian
-	}
-	if uid == ImplicitVRLittleEndianUID {
+// TransferSyntax describes how a data set and its pixel data are encoded
+// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#chapter_10
+type TransferSyntax struct {
+	// UID is the transfer syntax UID stored in the TransferSyntaxUID (0002,0010) element
+	UID string
+
+	// Name is the human readable name of the transfer syntax
+	Name string
+
+	// ByteOrder is the byte order of binary values in the data set
+	ByteOrder binary.ByteOrder
+
+	// ExplicitVR is true if the VR of each DataElement is encoded in the data set and false if it
+	// must be looked up in the data dictionary
+	ExplicitVR bool
+
+	// Deflated is true if the data set is compressed with the deflate algorithm
+	Deflated bool
+
+	// Encapsulated is true if the pixel data is compressed and stored in fragments as specified in
+	// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_A.4. Pixel data is
+	// stored in the native format otherwise.
+	Encapsulated bool
+
+	// Lossy is true if the compression of the pixel data may lose information
+	Lossy bool
+}
+
+func (ts TransferSyntax) String() string {
+	return fmt.Sprintf("%v (%v)", ts.Name, ts.UID)
+}
+
+func (ts TransferSyntax) syntax() transferSyntax {
+	if !ts.ExplicitVR {
 		return implicitVRLittleEndian
 	}
-	if uid == ExplicitVRBigEndianUID {
-		return explicitVRBigEndian
+	return explicitSyntax{ts.ByteOrder, ts.Deflated}
+}
+
+var (
+	transferSyntaxesMu sync.RWMutex
+	transferSyntaxes   = map[string]TransferSyntax{}
+)
+
+// standardTransferSyntaxes are the transfer syntaxes defined in PS3.5 and PS3.6. All transfer
+// syntaxes other than the native ones are encoded in explicit VR little endian.
+// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_A.4
+var standardTransferSyntaxes = []TransferSyntax{
+	implicitTransferSyntax(ImplicitVRLittleEndianUID, "Implicit VR Little Endian"),
+	nativeTransferSyntax(ExplicitVRLittleEndianUID, "Explicit VR Little Endian", binary.LittleEndian),
+	encapsulatedTransferSyntax(EncapsulatedUncompressedExplicitVRLittleEndianUID, "Encapsulated Uncompressed Explicit VR Little Endian", false),
+	deflatedTransferSyntax(DeflatedExplicitVRLittleEndianUID, "Deflated Explicit VR Little Endian"),
+	nativeTransferSyntax(ExplicitVRBigEndianUID, "Explicit VR Big Endian", binary.BigEndian),
+	encapsulatedTransferSyntax(JPEGBaselineUID, "JPEG Baseline (Process 1)", true),
+	encapsulatedTransferSyntax(JPEGExtended24UID, "JPEG Extended (Process 2 & 4)", true),
+	encapsulatedTransferSyntax(JPEGExtended35UID, "JPEG Extended (Process 3 & 5)", true),
+	encapsulatedTransferSyntax(JPEGSpectralSelectionNonHierarchical68UID, "JPEG Spectral Selection, Non-Hierarchical (Process 6 & 8)", true),
+	encapsulatedTransferSyntax(JPEGSpectralSelectionNonHierarchical79UID, "JPEG Spectral Selection, Non-Hierarchical (Process 7 & 9)", true),
+	encapsulatedTransferSyntax(JPEGFullProgressionNonHierarchical1012UID, "JPEG Full Progression, Non-Hierarchical (Process 10 & 12)", true),
+	encapsulatedTransferSyntax(JPEGFullProgressionNonHierarchical1113UID, "JPEG Full Progression, Non-Hierarchical (Process 11 & 13)", true),
+	encapsulatedTransferSyntax(JPEGLosslessUID, "JPEG Lossless, Non-Hierarchical (Process 14)", false),
+	encapsulatedTransferSyntax(JPEGLosslessNonHierarchical15UID, "JPEG Lossless, Non-Hierarchical (Process 15)", false),
+	encapsulatedTransferSyntax(JPEGExtendedHierarchical1618UID, "JPEG Extended, Hierarchical (Process 16 & 18)", true),
+	encapsulatedTransferSyntax(JPEGExtendedHierarchical1719UID, "JPEG Extended, Hierarchical (Process 17 & 19)", true),
+	encapsulatedTransferSyntax(JPEGSpectralSelectionHierarchical2022UID, "JPEG Spectral Selection, Hierarchical (Process 20 & 22)", true),
+	encapsulatedTransferSyntax(JPEGSpectralSelectionHierarchical2123UID, "JPEG Spectral Selection, Hierarchical (Process 21 & 23)", true),
+	encapsulatedTransferSyntax(JPEGFullProgressionHierarchical2426UID, "JPEG Full Progression, Hierarchical (Process 24 & 26)", true),
+	encapsulatedTransferSyntax(JPEGFullProgressionHierarchical2527UID, "JPEG Full Progression, Hierarchical (Process 25 & 27)", true),
+	encapsulatedTransferSyntax(JPEGLosslessHierarchical28UID, "JPEG Lossless, Hierarchical (Process 28)", false),
+	encapsulatedTransferSyntax(JPEGLosslessHierarchical29UID, "JPEG Lossless, Hierarchical (Process 29)", false),
+	encapsulatedTransferSyntax(JPEGLosslessSV1UID, "JPEG Lossless, Non-Hierarchical, First-Order Prediction (Process 14 [Selection Value 1])", false),
+	encapsulatedTransferSyntax(JPEGLSLosslessUID, "JPEG-LS Lossless Image Compression", false),
+	encapsulatedTransferSyntax(JPEGLSNearLosslessUID, "JPEG-LS Lossy (Near-Lossless) Image Compression", true),
+	encapsulatedTransferSyntax(JPEG2000LosslessUID, "JPEG 2000 Image Compression (Lossless Only)", false),
+	encapsulatedTransferSyntax(JPEG2000UID, "JPEG 2000 Image Compression", true),
+	encapsulatedTransferSyntax(JPEG2000MultiComponentLosslessUID, "JPEG 2000 Part 2 Multi-component Image Compression (Lossless Only)", false),
+	encapsulatedTransferSyntax(JPEG2000MultiComponentUID, "JPEG 2000 Part 2 Multi-component Image Compression", true),
+	nativeTransferSyntax(JPIPReferencedUID, "JPIP Referenced", binary.LittleEndian),
+	deflatedTransferSyntax(JPIPReferencedDeflateUID, "JPIP Referenced Deflate"),
+	encapsulatedTransferSyntax(MPEG2MainProfileMainLevelUID, "MPEG2 Main Profile / Main Level", true),
+	encapsulatedTransferSyntax(FragmentableMPEG2MainProfileMainLevelUID, "Fragmentable MPEG2 Main Profile / Main Level", true),
+	encapsulatedTransferSyntax(MPEG2MainProfileHighLevelUID, "MPEG2 Main Profile / High Level", true),
+	encapsulatedTransferSyntax(FragmentableMPEG2MainProfileHighLevelUID, "Fragmentable MPEG2 Main Profile / High Level", true),
+	encapsulatedTransferSyntax(MPEG4HighProfileLevel41UID, "MPEG-4 AVC/H.264 High Profile / Level 4.1", true),
+	encapsulatedTransferSyntax(FragmentableMPEG4HighProfileLevel41UID, "Fragmentable MPEG-4 AVC/H.264 High Profile / Level 4.1", true),
+	encapsulatedTransferSyntax(MPEG4BDCompatibleHighProfileLevel41UID, "MPEG-4 AVC/H.264 BD-compatible High Profile / Level 4.1", true),
+	encapsulatedTransferSyntax(FragmentableMPEG4BDCompatibleHighProfileLevel41UID, "Fragmentable MPEG-4 AVC/H.264 BD-compatible High Profile / Level 4.1", true),
+	encapsulatedTransferSyntax(MPEG4HighProfileLevel42For2DVideoUID, "MPEG-4 AVC/H.264 High Profile / Level 4.2 For 2D Video", true),
+	encapsulatedTransferSyntax(FragmentableMPEG4HighProfileLevel42For2DVideoUID, "Fragmentable MPEG-4 AVC/H.264 High Profile / Level 4.2 For 2D Video", true),
+	encapsulatedTransferSyntax(MPEG4HighProfileLevel42For3DVideoUID, "MPEG-4 AVC/H.264 High Profile / Level 4.2 For 3D Video", true),
+	encapsulatedTransferSyntax(FragmentableMPEG4HighProfileLevel42For3DVideoUID, "Fragmentable MPEG-4 AVC/H.264 High Profile / Level 4.2 For 3D Video", true),
+	encapsulatedTransferSyntax(MPEG4StereoHighProfileLevel42UID, "MPEG-4 AVC/H.264 Stereo High Profile / Level 4.2", true),
+	encapsulatedTransferSyntax(FragmentableMPEG4StereoHighProfileLevel42UID, "Fragmentable MPEG-4 AVC/H.264 Stereo High Profile / Level 4.2", true),
+	encapsulatedTransferSyntax(HEVCMainProfileLevel51UID, "HEVC/H.265 Main Profile / Level 5.1", true),
+	encapsulatedTransferSyntax(HEVCMain10ProfileLevel51UID, "HEVC/H.265 Main 10 Profile / Level 5.1", true),
+	encapsulatedTransferSyntax(JPEGXLLosslessUID, "JPEG XL Lossless", false),
+	encapsulatedTransferSyntax(JPEGXLJPEGRecompressionUID, "JPEG XL JPEG Recompression", true),
+	encapsulatedTransferSyntax(JPEGXLUID, "JPEG XL", true),
+	encapsulatedTransferSyntax(HTJ2KLosslessUID, "High-Throughput JPEG 2000 Image Compression (Lossless Only)", false),
+	encapsulatedTransferSyntax(HTJ2KLosslessRPCLUID, "High-Throughput JPEG 2000 with RPCL Options Image Compression (Lossless Only)", false),
+	encapsulatedTransferSyntax(HTJ2KUID, "High-Throughput JPEG 2000 Image Compression", true),
+	nativeTransferSyntax(JPIPHTJ2KReferencedUID, "JPIP HTJ2K Referenced", binary.LittleEndian),
+	deflatedTransferSyntax(JPIPHTJ2KReferencedDeflateUID, "JPIP HTJ2K Referenced Deflate"),
+	encapsulatedTransferSyntax(RLELosslessUID, "RLE Lossless", false),
+	nativeTransferSyntax(SMPTEST211020UncompressedProgressiveActiveVideoUID, "SMPTE ST 2110-20 Uncompressed Progressive Active Video", binary.LittleEndian),
+	nativeTransferSyntax(SMPTEST211020UncompressedInterlacedActiveVideoUID, "SMPTE ST 2110-20 Uncompressed Interlaced Active Video", binary.LittleEndian),
+	nativeTransferSyntax(SMPTEST211030PCMDigitalAudioUID, "SMPTE ST 2110-30 PCM Digital Audio", binary.LittleEndian),
+	encapsulatedTransferSyntax(DeflatedImageFrameCompressionUID, "Deflated Image Frame Compression", false),
+	implicitTransferSyntax(Papyrus3ImplicitVRLittleEndianUID, "Papyrus 3 Implicit VR Little Endian"),
+}
+
+func implicitTransferSyntax(uid, name string) TransferSyntax {
+	return TransferSyntax{UID: uid, Name: name, ByteOrder: binary.LittleEndian}
+}
+
+func nativeTransferSyntax(uid, name string, order binary.ByteOrder) TransferSyntax {
+	return TransferSyntax{UID: uid, Name: name, ByteOrder: order, ExplicitVR: true}
+}
+
+func deflatedTransferSyntax(uid, name string) TransferSyntax {
+	return TransferSyntax{UID: uid, Name: name, ByteOrder: binary.LittleEndian, ExplicitVR: true, Deflated: true}
+}
+
+func encapsulatedTransferSyntax(uid, name string, lossy bool) TransferSyntax {
+	return TransferSyntax{
+		UID:          uid,
+		Name:         name,
+		ByteOrder:    binary.LittleEndian,
+		ExplicitVR:   true,
+		Encapsulated: true,
+		Lossy:        lossy,
+	}
+}
+
+func init() {
+	for _, ts := range standardTransferSyntaxes {
+		if err := RegisterTransferSyntax(ts); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// LookupTransferSyntax returns the TransferSyntax with the given UID. An error is returned if the
+// UID is not one of the transfer syntaxes known to the parser or registered with
+// RegisterTransferSyntax.
+func LookupTransferSyntax(uid string) (TransferSyntax, error) {
+	transferSyntaxesMu.RLock()
+	defer transferSyntaxesMu.RUnlock()
+
+	ts, ok := transferSyntaxes[uid]
+	if !ok {
+		return TransferSyntax{}, fmt.Errorf("unknown transfer syntax: %q", uid)
+	}
+	return ts, nil
+}
+
+// RegisterTransferSyntax makes a transfer syntax, such as a private one, known to
+// LookupTransferSyntax and to the parser. Data sets in registered transfer syntaxes are read with
+// the byte order and VR encoding of the TransferSyntax. An error is returned if the UID is already
+// registered or if the TransferSyntax can't be read by the parser.
+func RegisterTransferSyntax(ts TransferSyntax) error {
+	if ts.UID == "" {
+		return fmt.Errorf("transfer syntax UID must not be empty")
+	}
+	if ts.ByteOrder == nil {
+		return fmt.Errorf("transfer syntax %v has no byte order", ts.UID)
+	}
+	if !ts.ExplicitVR && ts.ByteOrder != binary.LittleEndian {
+		return fmt.Errorf("transfer syntax %v: implicit VR is only supported in little endian", ts.UID)
 	}
-	if uid == DeflatedExplicitVRLittleEndianUID {
-		return deflatedExplicitVRLittleEndian
+	if !ts.ExplicitVR && ts.Deflated {
+		return fmt.Errorf("transfer syntax %v: deflate is only supported with explicit VR", ts.UID)
+	}
+
+	transferSyntaxesMu.Lock()
+	defer transferSyntaxesMu.Unlock()
+
+	if _, ok := transferSyntaxes[ts.UID]; ok {
+		return fmt.Errorf("transfer syntax %v already registered", ts.UID)
+	}
+	transferSyntaxes[ts.UID] = ts
+	return nil
+}
+
+func lookupTransferSyntax(uid string) transferSyntax {
+	if ts, err := LookupTransferSyntax(uid); err == nil {
+		return ts.syntax()
 	}
 
 	// any other syntax should be explicit VR little endian according to PS3.5 A.4
@@ -54,6 +344,17 @@ func lookupTransferSyntax(uid string) transferSyntax {
 	return explicitVRLittleEndian
 }
 
+// valueSyntax returns the transfer syntax that the value field of an element with the given VR is
+// encoded in when the element itself is encoded in syntax. The value field of UN elements holding a
+// sequence is always encoded in the implicit VR little endian syntax as specified in
+// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_6.2.2
+func valueSyntax(vr *VR, syntax transferSyntax) transferSyntax {
+	if vr == UNVR {
+		return implicitVRLittleEndian
+	}
+	return syntax
+}
+
 const (
 	vrSize  = 2
 	tagSize = 4
@@ -133,7 +434,14 @@ func (s explicitSyntax) readVR(dr *dcmReader, tag DataElementTag) (*VR, error) {
 		return nil, fmt.Errorf("getting vr %v", vrString)
 	}
 
-	return lookupVRByName(vrString)
+	vr, err := lookupVRByName(vrString)
+	if err != nil {
+		// VRs unknown to the reader, such as those added in later editions of the standard, are read
+		// as UN. Such VRs always have a 32-bit length as specified in
+		// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_6.2
+		return UNVR, nil
+	}
+	return vr, nil
 }
 
 func (s explicitSyntax) readValueLength(dr *dcmReader, vr *VR) (uint32, error) {
@@ -185,7 +493,7 @@ func (s explicitSyntax) has32BitLength(vr *VR) bool {
 	// depending on the VR type. The 2 cases are defined at the link:
 	// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_7.1.2
 	switch vr {
-	case OBVR, ODVR, OFVR, OLVR, OWVR, SQVR, UCVR, URVR, UTVR, UNVR:
+	case OBVR, ODVR, OFVR, OLVR, OVVR, OWVR, SQVR, SVVR, UCVR, URVR, UTVR, UNVR, UVVR:
 		return true
 	default:
 		return false
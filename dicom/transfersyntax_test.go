@@ -15,6 +15,10 @@
 package dicom
 
 import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -60,6 +64,243 @@ func TestLookupTransferSyntax(t *testing.T) {
 	}
 }
 
+func TestLookupTransferSyntax_exported(t *testing.T) {
+	tests := []struct {
+		name             string
+		uid              string
+		wantExplicitVR   bool
+		wantByteOrder    binary.ByteOrder
+		wantDeflated     bool
+		wantEncapsulated bool
+	}{
+		{
+			"implicit vr little endian",
+			ImplicitVRLittleEndianUID,
+			false,
+			binary.LittleEndian,
+			false,
+			false,
+		},
+		{
+			"explicit vr big endian",
+			ExplicitVRBigEndianUID,
+			true,
+			binary.BigEndian,
+			false,
+			false,
+		},
+		{
+			"deflated explicit vr little endian",
+			DeflatedExplicitVRLittleEndianUID,
+			true,
+			binary.LittleEndian,
+			true,
+			false,
+		},
+		{
+			"jpeg baseline has encapsulated pixel data",
+			JPEGBaselineUID,
+			true,
+			binary.LittleEndian,
+			false,
+			true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := LookupTransferSyntax(tc.uid)
+			if err != nil {
+				t.Fatalf("LookupTransferSyntax(%q) => %v", tc.uid, err)
+			}
+			if got.UID != tc.uid {
+				t.Errorf("got UID %q, want %q", got.UID, tc.uid)
+			}
+			if got.ExplicitVR != tc.wantExplicitVR {
+				t.Errorf("got ExplicitVR %v, want %v", got.ExplicitVR, tc.wantExplicitVR)
+			}
+			if got.ByteOrder != tc.wantByteOrder {
+				t.Errorf("got ByteOrder %v, want %v", got.ByteOrder, tc.wantByteOrder)
+			}
+			if got.Deflated != tc.wantDeflated {
+				t.Errorf("got Deflated %v, want %v", got.Deflated, tc.wantDeflated)
+			}
+			if got.Encapsulated != tc.wantEncapsulated {
+				t.Errorf("got Encapsulated %v, want %v", got.Encapsulated, tc.wantEncapsulated)
+			}
+		})
+	}
+}
+
+func TestLookupTransferSyntax_unknown(t *testing.T) {
+	if got, err := LookupTransferSyntax("1.2.3.4"); err == nil {
+		t.Fatalf("LookupTransferSyntax(_) => %v, expected an error", got)
+	}
+}
+
+func TestRegisterTransferSyntax(t *testing.T) {
+	private := TransferSyntax{
+		UID:        "1.2.826.0.1.3680043.2.1143.1",
+		Name:       "Private Explicit VR Big Endian",
+		ByteOrder:  binary.BigEndian,
+		ExplicitVR: true,
+	}
+	if err := RegisterTransferSyntax(private); err != nil {
+		t.Fatalf("RegisterTransferSyntax(_) => %v", err)
+	}
+	if err := RegisterTransferSyntax(private); err == nil {
+		t.Fatalf("expected an error when registering %v twice", private.UID)
+	}
+
+	got, err := LookupTransferSyntax(private.UID)
+	if err != nil {
+		t.Fatalf("LookupTransferSyntax(_) => %v", err)
+	}
+	if got != private {
+		t.Fatalf("got %v, want %v", got, private)
+	}
+
+	modality := []byte{0x00, 0x08, 0x00, 0x60, 'C', 'S', 0x00, 0x02, 'M', 'R'}
+	dataSet, err := ParseDataSet(bytes.NewReader(modality), private.UID)
+	if err != nil {
+		t.Fatalf("ParseDataSet(_, _) => %v", err)
+	}
+	if got, err := dataSet.Elements[ModalityTag].StringValue(); err != nil || got != "MR" {
+		t.Fatalf("got modality %q (err %v), want %q", got, err, "MR")
+	}
+	if len(dataSet.Diagnostics) != 0 {
+		t.Fatalf("got diagnostics %v, want none", dataSet.Diagnostics)
+	}
+}
+
+func TestRegisterTransferSyntax_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   TransferSyntax
+	}{
+		{
+			"empty UID",
+			TransferSyntax{ByteOrder: binary.LittleEndian, ExplicitVR: true},
+		},
+		{
+			"missing byte order",
+			TransferSyntax{UID: "1.2.3.4.5", ExplicitVR: true},
+		},
+		{
+			"implicit vr big endian",
+			TransferSyntax{UID: "1.2.3.4.5", ByteOrder: binary.BigEndian},
+		},
+		{
+			"deflated implicit vr",
+			TransferSyntax{UID: "1.2.3.4.5", ByteOrder: binary.LittleEndian, Deflated: true},
+		},
+		{
+			"standard syntax is already registered",
+			TransferSyntax{UID: ExplicitVRLittleEndianUID, ByteOrder: binary.LittleEndian, ExplicitVR: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := RegisterTransferSyntax(tc.in); err == nil {
+				t.Fatalf("RegisterTransferSyntax(%v) => nil, expected an error", tc.in)
+			}
+		})
+	}
+}
+
+func TestStandardTransferSyntaxes(t *testing.T) {
+	for _, want := range standardTransferSyntaxes {
+		t.Run(want.Name, func(t *testing.T) {
+			got, err := LookupTransferSyntax(want.UID)
+			if err != nil {
+				t.Fatalf("LookupTransferSyntax(%q) => %v", want.UID, err)
+			}
+			if got != want {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+			if !strings.HasPrefix(got.UID, "1.2.840.10008.1.2") {
+				t.Fatalf("%v is not in the transfer syntax UID space", got.UID)
+			}
+			// PS3.5 A.4 requires encapsulated syntaxes to be explicit VR little endian
+			if got.Encapsulated && (!got.ExplicitVR || got.ByteOrder != binary.LittleEndian || got.Deflated) {
+				t.Fatalf("encapsulated syntax %v must be explicit VR little endian", got)
+			}
+			if got.Lossy && !got.Encapsulated {
+				t.Fatalf("lossy syntax %v must be encapsulated", got)
+			}
+		})
+	}
+}
+
+func TestTransferSyntax_classification(t *testing.T) {
+	tests := []struct {
+		uid              string
+		wantEncapsulated bool
+		wantLossy        bool
+	}{
+		{ExplicitVRLittleEndianUID, false, false},
+		{EncapsulatedUncompressedExplicitVRLittleEndianUID, true, false},
+		{JPEGBaselineUID, true, true},
+		{JPEGLosslessSV1UID, true, false},
+		{JPEGLSLosslessUID, true, false},
+		{JPEGLSNearLosslessUID, true, true},
+		{JPEG2000LosslessUID, true, false},
+		{JPEG2000UID, true, true},
+		{HTJ2KLosslessUID, true, false},
+		{HTJ2KUID, true, true},
+		{RLELosslessUID, true, false},
+		{MPEG2MainProfileMainLevelUID, true, true},
+		{HEVCMain10ProfileLevel51UID, true, true},
+		{DeflatedImageFrameCompressionUID, true, false},
+		{JPIPReferencedUID, false, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.uid, func(t *testing.T) {
+			got, err := LookupTransferSyntax(tc.uid)
+			if err != nil {
+				t.Fatalf("LookupTransferSyntax(%q) => %v", tc.uid, err)
+			}
+			if got.Encapsulated != tc.wantEncapsulated || got.Lossy != tc.wantLossy {
+				t.Fatalf("got encapsulated %v and lossy %v, want %v and %v",
+					got.Encapsulated, got.Lossy, tc.wantEncapsulated, tc.wantLossy)
+			}
+		})
+	}
+}
+
+func TestParseDataSet_encapsulatedSyntaxes(t *testing.T) {
+	pixelData := []byte{
+		0xE0, 0x7F, 0x10, 0x00, 'O', 'B', 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF, // PixelData
+		0xFE, 0xFF, 0x00, 0xE0, 0x00, 0x00, 0x00, 0x00, // Basic Offset Table
+		0xFE, 0xFF, 0x00, 0xE0, 0x02, 0x00, 0x00, 0x00, 0x01, 0x02, // Fragment
+		0xFE, 0xFF, 0xDD, 0xE0, 0x00, 0x00, 0x00, 0x00, // Sequence Delimitation
+	}
+
+	for _, ts := range standardTransferSyntaxes {
+		if !ts.Encapsulated {
+			continue
+		}
+		t.Run(ts.Name, func(t *testing.T) {
+			dataSet, err := ParseDataSet(bytes.NewReader(pixelData), ts.UID)
+			if err != nil {
+				t.Fatalf("ParseDataSet(_, %q) => %v", ts.UID, err)
+			}
+			if len(dataSet.Diagnostics) != 0 {
+				t.Fatalf("got diagnostics %v, want none", dataSet.Diagnostics)
+			}
+			got, ok := dataSet.Elements[PixelDataTag].ValueField.(encapsulatedFormatBuffer)
+			if !ok {
+				t.Fatalf("got PixelData of type %T, want encapsulated fragments", dataSet.Elements[PixelDataTag].ValueField)
+			}
+			if want := [][]byte{{}, {0x01, 0x02}}; !reflect.DeepEqual(got.Data(), want) {
+				t.Fatalf("got fragments %v, want %v", got.Data(), want)
+			}
+		})
+	}
+}
+
 func TestImplicitSyntax_ReadVR(t *testing.T) {
 	tests := []struct {
 		name string
@@ -96,10 +337,14 @@ func TestImplicitSyntax_ReadVR(t *testing.T) {
 	}
 }
 
-func TestExplicitSyntax_invalidVR(t *testing.T) {
+func TestExplicitSyntax_unknownVR(t *testing.T) {
 	dr := dcmReaderFromBytes([]byte("ZZ"))
-	if _, err := explicitVRLittleEndian.readVR(dr, DataElementTag(0)); err == nil {
-		t.Fatalf("expected error to be returned")
+	vr, err := explicitVRLittleEndian.readVR(dr, DataElementTag(0))
+	if err != nil {
+		t.Fatalf("readVR(_, _) => %v", err)
+	}
+	if vr != UNVR {
+		t.Fatalf("got %v, want %v", vr, UNVR)
 	}
 }
 
@@ -48,11 +48,12 @@ func compareDataElements(e1 *DataElement, e2 *DataElement, order binary.ByteOrde
 		t.Fatalf("expected tags to be equal: got %v, want %v", e1.Tag, e2.Tag)
 	}
 
-	e1, err := processElement(e1, order)
+	ctx := newParseContext(explicitSyntax{order: order})
+	e1, err := processElement(e1, ctx)
 	if err != nil {
 		t.Fatalf("unexpected error unstreaming data element: %v", err)
 	}
-	e2, err = processElement(e2, order)
+	e2, err = processElement(e2, ctx)
 	if err != nil {
 		t.Fatalf("unexpected error unstreaming data element: %v", err)
 	}
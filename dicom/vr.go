@@ -105,6 +105,8 @@ var (
 	ULVR = newVR("UL", numberBinaryVR)
 	FLVR = newVR("FL", numberBinaryVR)
 	FDVR = newVR("FD", numberBinaryVR)
+	SVVR = newVR("SV", numberBinaryVR)
+	UVVR = newVR("UV", numberBinaryVR)
 
 	// large binary sequences
 	OBVR = newVR("OB", bulkDataVR)
@@ -112,6 +114,7 @@ var (
 	OLVR = newVR("OL", bulkDataVR)
 	OWVR = newVR("OW", bulkDataVR)
 	OFVR = newVR("OF", bulkDataVR)
+	OVVR = newVR("OV", bulkDataVR)
 
 	// unlimited char
 	UCVR = newVR("UC", bulkDataVR)
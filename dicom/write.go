@@ -18,6 +18,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -48,7 +49,11 @@ func writeValue(dw *dcmWriter, syntax transferSyntax, vr *VR, valueField interfa
 	case numberBinaryVR:
 		return writeNumberBinary(dw, syntax, valueField)
 	case bulkDataVR:
-		return writeBulkData(dw, syntax, valueField)
+		if isSequenceValue(valueField) {
+			// UN elements holding a sequence as described in isUnknownSequence
+			return writeSequence(dw, valueSyntax(vr, syntax), valueField, valueLength)
+		}
+		return writeBulkData(dw, syntax, vr, valueField)
 	case uniqueIdentifierVR:
 		return writeText(dw, nullPadding, valueField)
 	case sequenceVR:
@@ -76,20 +81,33 @@ func writeText(dw *dcmWriter, paddingByte byte, v interface{}) error {
 
 func writeNumberBinary(dw *dcmWriter, syntax transferSyntax, v interface{}) error {
 	switch field := v.(type) {
-	case []int16, []uint16, []int32, []uint32, []float32, []float64:
+	case []int16, []uint16, []int32, []uint32, []int64, []uint64, []float32, []float64:
 		return binary.Write(dw, syntax.byteOrder(), v)
 	default:
 		return fmt.Errorf("unsupported binary number type: %T", field)
 	}
 }
 
-func writeBulkData(dw *dcmWriter, syntax transferSyntax, v interface{}) error {
+func writeBulkData(dw *dcmWriter, syntax transferSyntax, vr *VR, v interface{}) error {
+	size := wordSize(vr)
 	switch field := v.(type) {
 	case BulkDataIterator:
+		if size > 1 && field.Length() != UndefinedLength && field.ByteOrder() != syntax.byteOrder() {
+			return writeByteFragments(dw, func() (io.Reader, error) {
+				r, err := field.Next()
+				if err != nil {
+					return nil, err
+				}
+				return &byteSwapReader{r: r, size: size}, nil
+			})
+		}
 		return field.write(dw, syntax)
 	case BulkDataBuffer:
+		if size > 1 && field.Length() != UndefinedLength && field.ByteOrder() != syntax.byteOrder() {
+			return swappedBulkDataBuffer(field, size, syntax.byteOrder()).write(dw, syntax)
+		}
 		return field.write(dw, syntax)
-	case []int16, []uint16, []int32, []uint32, []float32, []float64:
+	case []int16, []uint16, []int32, []uint32, []int64, []uint64, []float32, []float64:
 		return binary.Write(dw, syntax.byteOrder(), field)
 	case []string:
 		return writeText(dw, ' ', v)
@@ -98,6 +116,17 @@ func writeBulkData(dw *dcmWriter, syntax transferSyntax, v interface{}) error {
 	}
 }
 
+// swappedBulkDataBuffer returns a copy of the buffer with the bytes of each word of size bytes
+// reversed
+func swappedBulkDataBuffer(buffer BulkDataBuffer, size int, order binary.ByteOrder) BulkDataBuffer {
+	fragments := make([][]byte, len(buffer.Data()))
+	for i, fragment := range buffer.Data() {
+		fragments[i] = append([]byte{}, fragment...)
+		swapBytes(fragments[i], size)
+	}
+	return NewBulkDataBufferWithByteOrder(order, fragments...)
+}
+
 func writeSequence(dw *dcmWriter, syntax transferSyntax, v interface{}, seqLength uint32) error {
 	switch seq := v.(type) {
 	case SequenceIterator:
@@ -133,6 +162,14 @@ func writeSequence(dw *dcmWriter, syntax transferSyntax, v interface{}, seqLengt
 	return nil
 }
 
+func isSequenceValue(valueField interface{}) bool {
+	switch valueField.(type) {
+	case *Sequence, SequenceIterator:
+		return true
+	}
+	return false
+}
+
 func writeTag(dr *dcmWriter, order binary.ByteOrder, valueField interface{}) error {
 	tag, ok := valueField.([]uint32)
 	if !ok {
@@ -16,6 +16,7 @@ package dicom
 
 import (
 	"bytes"
+	"encoding/binary"
 	"testing"
 )
 
@@ -306,6 +307,83 @@ func TestWriteDateElement(t *testing.T) {
 				0x01, 0x02, 0x03, 0x04, 0x05, 0x00,
 			},
 		},
+		{
+			"writing 64-bit signed integers",
+			&DataElement{Tag: 0x00091001, VR: SVVR, ValueField: []int64{-2}, ValueLength: 8},
+			explicitVRLittleEndian,
+			[]byte{
+				0x09, 0x00, 0x01, 0x10, 'S', 'V', 0x00, 0x00, 0x08, 0x00, 0x00, 0x00,
+				0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+			},
+		},
+		{
+			"writing 64-bit unsigned integers",
+			&DataElement{Tag: 0x00091001, VR: UVVR, ValueField: []uint64{1}, ValueLength: 8},
+			explicitVRBigEndian,
+			[]byte{
+				0x00, 0x09, 0x10, 0x01, 'U', 'V', 0x00, 0x00, 0x00, 0x00, 0x00, 0x08,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+			},
+		},
+		{
+			"writing other 64-bit very long",
+			&DataElement{Tag: 0x00091001, VR: OVVR, ValueField: []uint64{1}, ValueLength: 8},
+			explicitVRLittleEndian,
+			[]byte{
+				0x09, 0x00, 0x01, 0x10, 'O', 'V', 0x00, 0x00, 0x08, 0x00, 0x00, 0x00,
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+		},
+		{
+			"writing little endian OW buffer in big endian swaps bytes",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataBuffer([]byte{0x01, 0x02}, []byte{0x03, 0x04}), ValueLength: 4},
+			explicitVRBigEndian,
+			[]byte{
+				0x7F, 0xE0, 0x00, 0x10, 'O', 'W', 0x00, 0x00, 0x00, 0x00, 0x00, 0x04,
+				0x02, 0x01, 0x04, 0x03,
+			},
+		},
+		{
+			"writing big endian OW buffer in little endian swaps bytes",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataBufferWithByteOrder(binary.BigEndian, []byte{0x01, 0x02}), ValueLength: 2},
+			explicitVRLittleEndian,
+			[]byte{
+				0xE0, 0x7F, 0x10, 0x00, 'O', 'W', 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+				0x02, 0x01,
+			},
+		},
+		{
+			"writing big endian OW buffer in big endian keeps bytes",
+			&DataElement{Tag: PixelDataTag, VR: OWVR, ValueField: NewBulkDataBufferWithByteOrder(binary.BigEndian, []byte{0x01, 0x02}), ValueLength: 2},
+			explicitVRBigEndian,
+			[]byte{
+				0x7F, 0xE0, 0x00, 0x10, 'O', 'W', 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+				0x01, 0x02,
+			},
+		},
+		{
+			"writing OB buffer in big endian keeps bytes",
+			&DataElement{Tag: PixelDataTag, VR: OBVR, ValueField: NewBulkDataBuffer([]byte{0x01, 0x02}), ValueLength: 2},
+			explicitVRBigEndian,
+			[]byte{
+				0x7F, 0xE0, 0x00, 0x10, 'O', 'B', 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+				0x01, 0x02,
+			},
+		},
+		{
+			"writing little endian OF iterator in big endian swaps 32-bit words",
+			&DataElement{
+				Tag:         FloatPixelDataTag,
+				VR:          OFVR,
+				ValueField:  NewBulkDataIteratorWithLength(bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04}), 0, 4),
+				ValueLength: 4,
+			},
+			explicitVRBigEndian,
+			[]byte{
+				0x7F, 0xE0, 0x00, 0x08, 'O', 'F', 0x00, 0x00, 0x00, 0x00, 0x00, 0x04,
+				0x04, 0x03, 0x02, 0x01,
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -328,21 +406,28 @@ func TestNewDataElementWriter_invalidMetaHeaders(t *testing.T) {
 	tests := []struct {
 		name   string
 		header *DataSet
+		opts   []ConstructOption
 	}{
 		{
-			"deflated syntax is not supported",
+			"invalid deflate level",
 			&DataSet{Elements: map[DataElementTag]*DataElement{
 				TransferSyntaxUIDTag: &DataElement{
 					Tag:        TransferSyntaxUIDTag,
 					ValueField: []string{DeflatedExplicitVRLittleEndianUID},
 				},
 			}},
+			[]ConstructOption{DeflateLevel(42)},
+		},
+		{
+			"missing transfer syntax",
+			&DataSet{Elements: map[DataElementTag]*DataElement{}},
+			nil,
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			if _, err := NewDataElementWriter(bytes.NewBuffer([]byte{}), tc.header); err == nil {
+			if _, err := NewDataElementWriter(bytes.NewBuffer([]byte{}), tc.header, tc.opts...); err == nil {
 				t.Fatalf("expected an error to be returned")
 			}
 		})
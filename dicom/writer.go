@@ -15,6 +15,7 @@
 package dicom
 
 import (
+	"compress/flate"
 	"fmt"
 	"io"
 	"math"
@@ -31,7 +32,12 @@ var errExpectedMetaHeader = fmt.Errorf("expected header to only contain file met
 // NewDataElementWriter writes the DICOM preamble, signature, and meta header to w and returns a
 // DataElementWriter that writes DataElements in the transfer syntax specified by the header.
 // The options are applied in the order given to all DataElements including File Meta Elements
-// before being written to w.
+// before being written to w. In the deflated transfer syntax, the meta header is written
+// uncompressed and the DataElements that follow are compressed with the level set by DeflateLevel.
+//
+// The returned DataElementWriter implements io.Closer. Close flushes the DataElements written so
+// far to w and must be called after the last DataElement is written for the output to be complete,
+// e.g. to flush the compressor of the deflated transfer syntax. It does not close w.
 func NewDataElementWriter(w io.Writer, header *DataSet, opts ...ConstructOption) (DataElementWriter, error) {
 	if !header.isMetaHeader() {
 		return nil, errExpectedMetaHeader
@@ -41,8 +47,14 @@ func NewDataElementWriter(w io.Writer, header *DataSet, opts ...ConstructOption)
 	if err != nil {
 		return nil, fmt.Errorf("getting transfer syntax from header: %v", err)
 	}
-	if syntax == deflatedExplicitVRLittleEndian {
-		return nil, fmt.Errorf("writing in the deflated syntax is not supported yet")
+
+	settings := newConstructSettings(opts...)
+	var compressor *flate.Writer
+	if syntax.isDeflated() {
+		// Validate the level before anything is written to w
+		if compressor, err = flate.NewWriter(w, settings.deflateLevel); err != nil {
+			return nil, fmt.Errorf("creating compressor: %v", err)
+		}
 	}
 
 	dw := &dcmWriter{w}
@@ -75,13 +87,21 @@ func NewDataElementWriter(w io.Writer, header *DataSet, opts ...ConstructOption)
 		}
 	}
 
-	return &dataElementWriter{dw, syntax, opts}, nil
+	if compressor != nil {
+		// The data set of the deflated syntax follows the meta header as specified in PS3.5 A.5
+		// http://dicom.nema.org/medical/dicom/current/output/html/part05.html#sect_A.5
+		dw = &dcmWriter{compressor}
+	}
+	return &dataElementWriter{dw, syntax, opts, compressor}, nil
 }
 
 type dataElementWriter struct {
 	dw     *dcmWriter
 	syntax transferSyntax
 	opts   []ConstructOption
+
+	// compressor is the writer of the deflated data set. It is nil for other syntaxes.
+	compressor *flate.Writer
 }
 
 func (dew *dataElementWriter) WriteElement(element *DataElement) error {
@@ -92,6 +112,25 @@ func (dew *dataElementWriter) WriteElement(element *DataElement) error {
 	return writeDataElement(dew.dw, dew.syntax, element)
 }
 
+// closeDataElementWriter closes the DataElementWriter if it implements io.Closer, like those
+// returned by NewDataElementWriter
+func closeDataElementWriter(writer DataElementWriter) error {
+	if closer, ok := writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (dew *dataElementWriter) Close() error {
+	if dew.compressor == nil {
+		return nil
+	}
+	if err := dew.compressor.Close(); err != nil {
+		return fmt.Errorf("flushing compressor: %v", err)
+	}
+	return nil
+}
+
 func writeDicomSignature(dw *dcmWriter) error {
 	if err := dw.Bytes(make([]byte, 128)); err != nil {
 		return fmt.Errorf("writing DICOM preamble: %v", err)
@@ -132,7 +171,7 @@ func processElementForConstruct(element *DataElement, syntax transferSyntax, opt
 	}
 
 	if seq, ok := element.ValueField.(*Sequence); ok {
-		processedSeq, err := processSequenceForConstruct(seq, syntax, opts...)
+		processedSeq, err := processSequenceForConstruct(seq, valueSyntax(element.VR, syntax), opts...)
 		if err != nil {
 			return nil, fmt.Errorf("processing sequence: %v", err)
 		}
@@ -145,6 +184,9 @@ func processElementForConstruct(element *DataElement, syntax transferSyntax, opt
 func applyConstructOptions(element *DataElement, syntax transferSyntax, opts ...ConstructOption) (*DataElement, error) {
 	var err error
 	for i, opt := range opts {
+		if opt.transform == nil {
+			continue
+		}
 		element, err = opt.transform(element)
 		if err != nil {
 			return nil, fmt.Errorf("applying option %v: %v", i, err)
@@ -189,12 +231,20 @@ func calculateElementLength(element *DataElement, syntax transferSyntax) (uint32
 		numBytes = int64(len(v)) * 4
 	case []uint32:
 		numBytes = int64(len(v)) * 4
+	case []int64:
+		numBytes = int64(len(v)) * 8
+	case []uint64:
+		numBytes = int64(len(v)) * 8
 	case []float32:
 		numBytes = int64(len(v)) * 4
 	case []float64:
 		numBytes = int64(len(v)) * 8
 	case *Sequence:
-		seqLen, err := calculateSequenceLength(v, syntax)
+		vr := element.VR
+		if vr == nil {
+			vr = element.Tag.DictionaryVR()
+		}
+		seqLen, err := calculateSequenceLength(v, valueSyntax(vr, syntax))
 		if err != nil {
 			return 0, fmt.Errorf("calculating sequence length: %v", err)
 		}
@@ -16,8 +16,10 @@ package dicom
 
 import (
 	"bytes"
+	"compress/flate"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -73,6 +75,57 @@ func TestDataElementWriter_calculatesMetaHeaderAfterOptions(t *testing.T) {
 	}
 }
 
+func TestDataElementWriter_deflated(t *testing.T) {
+	modality := &DataElement{Tag: ModalityTag, VR: CSVR, ValueField: []string{"SR"}}
+	text := &DataElement{Tag: TextValueTag, VR: UTVR, ValueField: []string{strings.Repeat("finding ", 512)}}
+
+	tests := []struct {
+		name string
+		opts []ConstructOption
+	}{
+		{"default level", nil},
+		{"no compression", []ConstructOption{DeflateLevel(flate.NoCompression)}},
+		{"best compression", []ConstructOption{DeflateLevel(flate.BestCompression)}},
+	}
+
+	sizes := map[string]int{}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := &bytes.Buffer{}
+			writer := mustNewDataElementWriterWithSyntax(t, file, DeflatedExplicitVRLittleEndianUID, tc.opts...)
+			header := file.Len()
+			for _, element := range []*DataElement{modality, text} {
+				if err := writer.WriteElement(element); err != nil {
+					t.Fatalf("WriteElement(_) => %v", err)
+				}
+			}
+			if err := writer.(io.Closer).Close(); err != nil {
+				t.Fatalf("Close() => %v", err)
+			}
+			sizes[tc.name] = file.Len() - header
+
+			dataSet, err := Parse(file)
+			if err != nil {
+				t.Fatalf("Parse(_) => %v", err)
+			}
+			for _, element := range []*DataElement{modality, text} {
+				got, err := dataSet.Elements[element.Tag].StringValue()
+				if err != nil {
+					t.Fatalf("StringValue() => %v", err)
+				}
+				if want := strings.TrimRight(element.ValueField.([]string)[0], " "); got != want {
+					t.Fatalf("got %v = %q, want %q", element.Tag, got, want)
+				}
+			}
+		})
+	}
+
+	if sizes["best compression"] >= sizes["no compression"] {
+		t.Fatalf("got %d bytes with the best compression, want less than %d bytes without compression",
+			sizes["best compression"], sizes["no compression"])
+	}
+}
+
 func mustNewDataElementWriterWithSyntax(t *testing.T, w io.Writer, syntaxUID string, opts ...ConstructOption) DataElementWriter {
 	ret, err := NewDataElementWriter(w, &DataSet{
 		Elements: map[DataElementTag]*DataElement{
@@ -84,3 +137,20 @@ func mustNewDataElementWriterWithSyntax(t *testing.T, w io.Writer, syntaxUID str
 	}
 	return ret
 }
+
+// elementRecorder is a DataElementWriter implemented outside of the package API, without Close
+type elementRecorder struct {
+	elements []*DataElement
+}
+
+func (r *elementRecorder) WriteElement(element *DataElement) error {
+	r.elements = append(r.elements, element)
+	return nil
+}
+
+func TestCloseDataElementWriter_withoutClose(t *testing.T) {
+	var writer DataElementWriter = &elementRecorder{}
+	if err := closeDataElementWriter(writer); err != nil {
+		t.Fatalf("closeDataElementWriter(_) => %v", err)
+	}
+}